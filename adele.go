@@ -8,34 +8,49 @@
 package adele
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/CloudyKit/jet/v6"
 	"github.com/alexedwards/scs/v2"
 	"github.com/cidekar/adele-framework/auth"
-	"github.com/cidekar/adele-framework/cache"
 	"github.com/cidekar/adele-framework/cache/badgerdriver"
 	"github.com/cidekar/adele-framework/cache/redisdriver"
+	"github.com/cidekar/adele-framework/config"
 	"github.com/cidekar/adele-framework/database"
+	"github.com/cidekar/adele-framework/filesystem"
 	"github.com/cidekar/adele-framework/filesystem/miniofilesystem"
 	"github.com/cidekar/adele-framework/filesystem/s3filesystem"
 	"github.com/cidekar/adele-framework/filesystem/sftpfilesystem"
 	"github.com/cidekar/adele-framework/filesystem/webdavfilesystem"
+	"github.com/cidekar/adele-framework/health"
 	"github.com/cidekar/adele-framework/helpers"
 	"github.com/cidekar/adele-framework/logger"
 	"github.com/cidekar/adele-framework/mailer"
 	"github.com/cidekar/adele-framework/middleware"
 	"github.com/cidekar/adele-framework/mux"
+	"github.com/cidekar/adele-framework/oauth"
+	"github.com/cidekar/adele-framework/provider"
+	"github.com/cidekar/adele-framework/queue"
 	"github.com/cidekar/adele-framework/render"
+	"github.com/cidekar/adele-framework/schedule"
 	"github.com/cidekar/adele-framework/session"
+	"github.com/cidekar/adele-framework/urlsigner"
 	"github.com/cidekar/adele-framework/vite"
 	crs "github.com/go-chi/cors"
+	"github.com/gomodule/redigo/redis"
 	"github.com/joho/godotenv"
 	"github.com/robfig/cron/v3"
 	"gopkg.in/yaml.v2"
@@ -49,203 +64,347 @@ var Helpers = &helpers.Helpers{}
 
 // Create a new instance of the Adele type using a pointer to Adele with the
 // root path of the application as a argument. The new-up is called by project adele's consuming package
-// to bootstrap the framework.
-func (a *Adele) New(rootPath string) error {
+// to bootstrap the framework. Every subsystem is booted unless switched off with
+// an Option, and a failing bootstrap step is returned as a wrapped error rather
+// than terminating the process.
+func (a *Adele) New(rootPath string, opts ...Option) error {
+
+	boot := defaultBootOptions()
+	for _, opt := range opts {
+		opt(&boot)
+	}
 
 	directories := []string{"handlers", "logs", "jobs", "middleware", "migrations", "models", "public", "resources", "resources/views", "resources/mail", "storage"}
 
 	err := a.CreateDirectories(rootPath, directories)
 	if err != nil {
-		return err
+		return fmt.Errorf("create directories: %w", err)
 	}
 
 	err = a.CreateEnvironmentFile(rootPath)
 	if err != nil {
-		return err
+		return fmt.Errorf("create environment file: %w", err)
 	}
 
 	err = godotenv.Load(rootPath + "/.env")
 	if err != nil {
-		return err
+		return fmt.Errorf("load environment file: %w", err)
 	}
 
-	a.Log = logger.CreateLogger()
+	if boot.config != nil {
+		a.Config = boot.config
+	} else {
+		a.Config, err = config.New(rootPath)
+		if err != nil {
+			return fmt.Errorf("load configuration: %w", err)
+		}
+	}
+
+	a.Log = logger.NewLogger(a.Config.Log)
+	for _, warning := range a.Config.Warnings() {
+		a.Log.Warn(warning)
+	}
+
+	a.BootstrapScheduler()
+
+	if boot.database {
+		if err := a.BootstrapDatabase(); err != nil {
+			return fmt.Errorf("bootstrap database: %w", err)
+		}
+	}
 
 	sess, err := a.BootstrapSessionManager()
 	if err != nil {
-		return err
+		return fmt.Errorf("bootstrap session: %w", err)
 	}
 
 	a.Session = sess
 
 	a.BootstrapMiddleware()
 
-	a.Debug, _ = strconv.ParseBool(os.Getenv("APP_DEBUG"))
-	a.EncryptionKey = os.Getenv("APP_KEY")
+	a.Debug = a.Config.App.Debug
+	a.EncryptionKey = a.Config.App.Key
 	a.ErrorLog = log.New(os.Stderr, "ERRO ", log.Ldate|log.Ltime|log.Lshortfile)
 	a.RootPath = rootPath
 	a.Version = Version
-	a.ViewsTemplateDir = Helpers.Getenv("VIEWS_TEMPLATE_DIR", "resources/views")
-	a.config = config{
-		port:        Helpers.Getenv("HTTP_PORT", "4000"),
-		renderer:    Helpers.Getenv("RENDERER", "jet"),
-		sessionType: Helpers.Getenv("SESSION_TYPE"),
-	}
+	a.ViewsTemplateDir = a.Config.App.ViewsTemplateDir
 
-	if appURL := os.Getenv("APP_URL"); appURL != "" {
+	if appURL := a.Config.App.URL; appURL != "" {
 		a.Server.URL = appURL
 	} else {
-		a.Server.URL = "http://localhost:" + a.config.port
+		a.Server.URL = "http://localhost:" + a.Config.HTTP.Port
 	}
 
 	muxRouter, err := a.BootstrapMux(rootPath)
 	if err != nil {
-		return err
+		return fmt.Errorf("bootstrap router: %w", err)
 	}
 
 	a.Routes = muxRouter.(*mux.Mux)
 
-	a.BoostrapFilesystem()
-
-	a.Mail = a.BoootstrapMailer()
-
-	a.JetViews = a.BootstrapJetEngine()
+	if boot.filesystem {
+		if err := a.BootstrapFilesystem(); err != nil {
+			return fmt.Errorf("bootstrap filesystem: %w", err)
+		}
+	}
 
-	a.Render = a.BootstrapRender()
+	if boot.mail {
+		a.Mail, err = a.BootstrapMailer()
+		if err != nil {
+			return fmt.Errorf("bootstrap mailer: %w", err)
+		}
+	}
 
-	a.BootstrapDatabase()
+	if boot.render {
+		a.JetViews = a.BootstrapJetEngine()
+		a.Render = a.BootstrapRender()
+	}
 
 	a.Auth = &auth.Auth{
-		AppName:  a.AppName,
-		DB:       a.DB,
-		ErrorLog: a.ErrorLog,
-		Session:  a.Session,
+		AppName:       a.AppName,
+		DB:            a.DB,
+		ErrorLog:      a.ErrorLog,
+		Session:       a.Session,
+		TwoFactor:     a.Config.Auth.TwoFactor,
+		Log:           a.Log,
+		RememberTable: a.Config.Auth.RememberTable,
+		Hasher:        a.BootstrapHasher(),
+		Routes:        a.Routes,
+	}
+	a.Auth.Users = &auth.DatabaseUserProvider{
+		DB:          a.DB,
+		Table:       a.Config.Auth.UsersTable,
+		LoginColumn: a.Config.Auth.LoginColumn,
+		Hasher:      a.Auth.Hasher,
 	}
+	if a.DB != nil && a.DB.Pool != nil {
+		a.Auth.Permissions = a.Auth.DatabasePermissions
+	}
+	a.Auth.Verification = a.BootstrapEmailVerification()
+
+	a.OAuth = a.BootstrapOAuth()
 
 	a.Helpers = a.BootstrapHelpers()
+	a.Auth.Forbidden = a.Helpers.ErrorForbidden
 
-	a.BootstrapScheduler()
+	if boot.cache {
+		if err := a.BootstrapCache(rootPath); err != nil {
+			return fmt.Errorf("bootstrap cache: %w", err)
+		}
+	}
 
-	err = a.BootstrapCache(rootPath)
-	if err != nil {
-		return err
+	a.Auth.Throttle = a.BootstrapLoginThrottle()
+
+	if boot.queue {
+		if err := a.BootstrapQueue(); err != nil {
+			return fmt.Errorf("bootstrap queue: %w", err)
+		}
+	}
+
+	if err := a.BootstrapScheduleLocks(); err != nil {
+		return fmt.Errorf("bootstrap schedule: %w", err)
 	}
 
-	//a.BootstrapServiceProviders()
+	a.BootstrapHealth()
+
+	if boot.providers {
+		if err := a.BootstrapServiceProviders(rootPath); err != nil {
+			return fmt.Errorf("bootstrap providers: %w", err)
+		}
+	}
 
 	return nil
 }
 
-// TODO:
-// Describe that the method is going to do and provide when called...
-// func (a *Adele) BootstrapServiceProviders() {
-// 	//a.Provider = provider.New(a)
-// 	p := &provider.Provider{
-// 		//App:              a,
-// 		EnabledProviders: make(map[string]bool),
-// 		ProviderConfigs:  make(map[string]map[string]interface{}),
-// 	}
+// Load the service providers registered with provider.RegisterGlobalProvider into
+// the application. Per-provider settings are read from config/providers.yml, then
+// every enabled provider is registered and booted in priority and dependency
+// order. Deferred providers are loaded later through a.Provider.Resolve.
+func (a *Adele) BootstrapServiceProviders(rootPath string) error {
+	p := provider.New()
+	p.Log = a.Log
+
+	if err := p.LoadConfig(fmt.Sprintf("%s/config/providers.yml", rootPath)); err != nil {
+		return err
+	}
+
+	a.Provider = p
+
+	return a.Provider.LoadProviders(a)
+}
+
+// Create the health checker, register the built-in checks for every subsystem that
+// was booted and mount the liveness and readiness endpoints on the router. The
+// endpoints are exempt from maintenance mode so orchestrators can keep probing
+// an instance that is down for maintenance.
+func (a *Adele) BootstrapHealth() {
+	c := a.settings().Health
+	h := health.New(time.Duration(c.Timeout) * time.Second)
 
-// 	a.Provider = p
-// 	if err := a.Provider.LoadProviders(); err != nil {
-// 		a.Log.Error(err)
-// 		os.Exit(1)
-// 	}
+	if a.DB != nil && a.DB.Pool != nil {
+		h.Register("database", health.DatabaseCheck(a.DB.Pool))
+	}
 
-// }
+	if a.Cache != nil {
+		h.Register("cache", health.CacheCheck(a.Cache))
+	}
+
+	names := make([]string, 0, len(a.FileSystem))
+	for name := range a.FileSystem {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if fs := remoteFileSystem(a.FileSystem[name]); fs != nil {
+			h.Register("filesystem:"+strings.ToLower(name), health.FileSystemCheck(fs))
+		}
+	}
+
+	if a.Mail.API == "" && a.Mail.Host != "" {
+		h.Register("mail", health.DialCheck(net.JoinHostPort(a.Mail.Host, strconv.Itoa(a.Mail.Port))))
+	}
+
+	a.Health = h
+
+	if a.Routes != nil {
+		h.Mount(a.Routes, c.LivenessPath, c.ReadinessPath)
+	}
+}
+
+// Return the filesystem.FS implementation for a configured filesystem entry. The
+// filesystem map stores the backends by value while their methods use pointer
+// receivers.
+func remoteFileSystem(v interface{}) filesystem.FS {
+	switch fs := v.(type) {
+	case filesystem.FS:
+		return fs
+	case s3filesystem.S3:
+		return &fs
+	case miniofilesystem.Minio:
+		return &fs
+	case sftpfilesystem.SFTP:
+		return &fs
+	case webdavfilesystem.WebDAV:
+		return &fs
+	}
+	return nil
+}
 
 // Initializes and sets up a database connection for the application—establishes a database
-// connection during application startup and stores it in the Adele struct.
-func (a *Adele) BootstrapDatabase() {
-	db, err := database.OpenDB(os.Getenv("DATABASE_TYPE"), &database.DataSourceName{
-		Host:         Helpers.Getenv("DATABASE_HOST", "localhost"),
-		Port:         Helpers.Getenv("DATABASE_PORT", "5432"),
-		User:         Helpers.Getenv("DATABASE_USER"),
-		Password:     Helpers.Getenv("DATABASE_PASSWORD"),
-		DatabaseName: Helpers.Getenv("DATABASE_NAME"),
-		SslMode:      Helpers.Getenv("DATABASE_SSL_MODE"),
+// connection during application startup and stores it in the Adele struct. A connection
+// failure is returned to the caller.
+func (a *Adele) BootstrapDatabase() error {
+	c := a.settings().Database
+	db, err := database.OpenDB(c.Type, &database.DataSourceName{
+		Host:         c.Host,
+		Port:         c.Port,
+		User:         c.User,
+		Password:     c.Password,
+		DatabaseName: c.Name,
+		SslMode:      c.SSLMode,
 	})
 
 	if err != nil {
-		a.Log.Error(err)
-		os.Exit(1)
+		return err
 	}
 	a.DB = &database.Database{
-		DataType: os.Getenv("DATABASE_TYPE"),
+		DataType: c.Type,
 		Pool:     db,
 	}
+
+	return nil
 }
 
 // Initializes the file system auto-configuration method for the framework by detecting and
 // initializes available file storage systems based on environment variables during application startup.
-func (a *Adele) BoostrapFilesystem() {
+// A file system whose identifying key is set but whose remaining settings are incomplete is an error.
+func (a *Adele) BootstrapFilesystem() error {
+	c := a.settings().Filesystem
 	fileSystem := make(map[string]interface{})
 
-	if os.Getenv("S3_KEY") != "" {
-		s3 := s3filesystem.S3{
-			Key:    os.Getenv("S3_KEY"),
-			Secret: os.Getenv("S3_SECRET"),
-			Region: os.Getenv("S3_REGION"),
-			Bucket: os.Getenv("S3_BUCKET"),
+	required := []struct {
+		set      bool
+		system   string
+		settings map[string]string
+	}{
+		{c.S3Key != "", "S3_KEY", map[string]string{"S3_SECRET": c.S3Secret, "S3_REGION": c.S3Region, "S3_BUCKET": c.S3Bucket}},
+		{c.MinioSecret != "", "MINIO_SECRET", map[string]string{"MINIO_ENDPOINT": c.MinioEndpoint, "MINIO_KEY": c.MinioKey, "MINIO_BUCKET": c.MinioBucket}},
+		{c.SFTPHost != "", "SFTP_HOST", map[string]string{"SFTP_USER": c.SFTPUser, "SFTP_PORT": c.SFTPPort}},
+		{c.WebDAVHost != "", "WEBDAV_HOST", map[string]string{"WEBDAV_USER": c.WebDAVUser}},
+	}
+	var missing []string
+	for _, r := range required {
+		if !r.set {
+			continue
+		}
+		for name, value := range r.settings {
+			if value == "" {
+				missing = append(missing, fmt.Sprintf("%s is required when %s is set", name, r.system))
+			}
 		}
-		fileSystem["S3"] = s3
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return errors.New(strings.Join(missing, "; "))
 	}
 
-	if os.Getenv("MINIO_SECRET") != "" {
-		useSSL := false
-		if strings.ToLower(os.Getenv("MINIO_USESSL")) == "true" {
-			useSSL = true
+	if c.S3Key != "" {
+		s3 := s3filesystem.S3{
+			Key:    c.S3Key,
+			Secret: c.S3Secret,
+			Region: c.S3Region,
+			Bucket: c.S3Bucket,
 		}
+		fileSystem["S3"] = s3
+	}
 
+	if c.MinioSecret != "" {
 		minio := miniofilesystem.Minio{
-			Endpoint: os.Getenv("MINIO_ENDPOINT"),
-			Key:      os.Getenv("MINIO_KEY"),
-			Secret:   os.Getenv("MINIO_SECRET"),
-			UseSSL:   useSSL,
-			Region:   os.Getenv("MINIO_REGION"),
-			Bucket:   os.Getenv("MINIO_BUCKET"),
+			Endpoint: c.MinioEndpoint,
+			Key:      c.MinioKey,
+			Secret:   c.MinioSecret,
+			UseSSL:   c.MinioUseSSL,
+			Region:   c.MinioRegion,
+			Bucket:   c.MinioBucket,
 		}
 		fileSystem["MINIO"] = minio
 	}
 
-	if os.Getenv("SFTP_HOST") != "" {
+	if c.SFTPHost != "" {
 		sftp := sftpfilesystem.SFTP{
-			Host:     os.Getenv("SFTP_HOST"),
-			User:     os.Getenv("SFTP_USER"),
-			Password: os.Getenv("SFTP_PASSWORD"),
-			Port:     os.Getenv("SFTP_PORT"),
+			Host:     c.SFTPHost,
+			User:     c.SFTPUser,
+			Password: c.SFTPPassword,
+			Port:     c.SFTPPort,
 		}
 		fileSystem["SFTP"] = sftp
 	}
 
-	if os.Getenv("WEBDAV_HOST") != "" {
+	if c.WebDAVHost != "" {
 		webDAV := webdavfilesystem.WebDAV{
-			Host:     os.Getenv("WEBDAV_HOST"),
-			User:     os.Getenv("WEBDAV_USER"),
-			Password: os.Getenv("WEBDAV_PASSWORD"),
+			Host:     c.WebDAVHost,
+			User:     c.WebDAVUser,
+			Password: c.WebDAVPassword,
 		}
 		fileSystem["WEBDAV"] = webDAV
 	}
 
 	a.FileSystem = fileSystem
+	return nil
 }
 
 // Creates and returns a helper utilities object for the Adele framework— a collection of utility functions
 // that can be used throughout the application.
 func (a *Adele) BootstrapHelpers() *helpers.Helpers {
 
+	c := a.settings().Filesystem
+
 	// Define the file types allowd by the system and add any provided by the application developer.
 	mimeTypes := []string{"image/gif", "image/jpeg", "image/png", "application/pdf"}
-	exploded := strings.Split(Helpers.Getenv("FILE_TYPES_ALLOWED"), "")
-	mimeTypes = append(mimeTypes, exploded...)
+	mimeTypes = append(mimeTypes, c.AllowedFileTypes...)
 
-	// Max file upload size is set to 10 mb
-	var maxUploadSize int64
-	if max, err := strconv.Atoi(Helpers.Getenv("FILE_MAX_UPLOAD_SIZE")); err != nil {
-		maxUploadSize = 10 << 20
-	} else {
-		maxUploadSize = int64(max)
-	}
+	// Max file upload size defaults to 10 mb
+	maxUploadSize := c.MaxUploadSize
 
 	return &helpers.Helpers{
 		Redner: a.Render,
@@ -260,76 +419,147 @@ func (a *Adele) BootstrapHelpers() *helpers.Helpers {
 
 // Configure the mailer for the application by initializing mailer struct. The mailer
 // values are populated by the environemnt variables parsed from the .env file
-// at the root of the application.
-func (a *Adele) BoootstrapMailer() mailer.Mail {
-	port, _ := strconv.Atoi(Helpers.Getenv("SMTP_PORT", "1025"))
+// at the root of the application. A third-party API without both its key and URL
+// would silently fall back to SMTP, so it is an error.
+func (a *Adele) BootstrapMailer() (mailer.Mail, error) {
+	c := a.settings().Mail
+	if c.API != "" && c.API != "smtp" && (c.APIKey == "" || c.APIUrl == "") {
+		return mailer.Mail{}, fmt.Errorf("MAILER_API %q requires both MAILER_KEY and MAILER_URL", c.API)
+	}
+
 	m := mailer.Mail{
-		Domain:      os.Getenv("MAIL_DOMAIN"),
+		Domain:      c.Domain,
 		Templates:   a.RootPath + "/resources/mail",
-		Host:        os.Getenv("SMTP_HOST"),
-		Port:        port,
-		Username:    os.Getenv("SMTP_USERNAME"),
-		Password:    os.Getenv("SMTP_PASSWORD"),
-		Encryption:  os.Getenv("SMTP_ENCRYPTION"),
-		FromName:    os.Getenv("MAILER_FROM_NAME"),
-		FromAddress: os.Getenv("MAILER_FROM_ADDRESS"),
+		Host:        c.Host,
+		Port:        c.Port,
+		Username:    c.Username,
+		Password:    c.Password,
+		Encryption:  c.Encryption,
+		FromName:    c.FromName,
+		FromAddress: c.FromAddress,
 		Jobs:        make(chan mailer.Message, 20),
 		Results:     make(chan mailer.Result, 20),
-		API:         os.Getenv("MAILER_API"),
-		APIKey:      os.Getenv("MAILER_KEY"),
-		APIUrl:      os.Getenv("MAILER_URL"),
+		Done:        make(chan struct{}),
+		Stopped:     make(chan struct{}),
+		API:         c.API,
+		APIKey:      c.APIKey,
+		APIUrl:      c.APIUrl,
 	}
 
-	return m
+	return m, nil
 }
 
 // Configure the middleware for the application by initializing a middleware struct,
 // populating its values using the application configuration.
 func (a *Adele) BootstrapMiddleware() {
+	c := a.settings()
+
+	appName := a.AppName
+	if appName == "" {
+		appName = c.App.Name
+	}
+
 	myMiddleware := middleware.Middleware{
 		FrameworkVersion: a.Version,
-		AppName:          a.AppName,
+		AppName:          appName,
 		RootPath:         a.RootPath,
 		Log:              a.Log,
 		Session:          a.Session,
 		MaintenanceMode:  a.MaintenanceMode,
+		MaintenanceURLs:  append([]string{c.Health.LivenessPath, c.Health.ReadinessPath}, c.HTTP.MaintenanceURLs...),
+		Rate:             c.HTTP.RateLimit,
+		Duration:         time.Duration(c.HTTP.RateDuration) * time.Minute,
 	}
 
 	a.middleware = myMiddleware
 }
 
 // Initializes a cron job scheduler for the Adele framework. Sets up task scheduling capabilities
-// during application startup for framework-wide access.
+// during application startup for framework-wide access. Named tasks are added through
+// a.Schedule, which runs them on the same cron scheduler.
 func (a *Adele) BootstrapScheduler() {
 	a.Scheduler = cron.New()
+	a.Schedule = schedule.New(a.Scheduler)
+	a.Schedule.Log = a.Log
+}
+
+// Configure where scheduled task locks are held (memory, redis or database, per
+// SCHEDULE_LOCK) and record task runs in the cache when one is booted so every
+// process can report them.
+func (a *Adele) BootstrapScheduleLocks() error {
+	switch strings.ToLower(a.settings().Schedule.Lock) {
+	case "redis":
+		pool, err := a.BootstrapRedisPool()
+		if err != nil {
+			return fmt.Errorf("failed to create redis pool: %w", err)
+		}
+		a.Schedule.Locker = schedule.NewRedisLocker(pool, a.redisPrefix())
+	case "database":
+		if a.DB == nil || a.DB.Pool == nil {
+			return errors.New("database schedule locks require a database connection")
+		}
+		a.Schedule.Locker = schedule.NewSQLLocker(a.DB.Pool, a.DB.DataType)
+	}
+
+	a.Schedule.Cache = a.Cache
+
+	return nil
 }
 
 // Configure and create the session manager by initializing a session struct, populating
 // its cookie fields by retrieving values from environment variables.
 func (a *Adele) BootstrapSessionManager() (*scs.SessionManager, error) {
-
-	session := session.Session{
-		CookieDomain:   Helpers.Getenv("COOKIE_DOMAIN", "localhost"),
-		CookieLifetime: Helpers.Getenv("COOKIE_LIFETIME", "1"),
-		CookieName:     Helpers.Getenv("COOKIE_NAME", "adele"),
-		CookiePersist:  Helpers.Getenv("COOKIE_PERSIST", "true"),
-		CookieSecure:   Helpers.Getenv("COOKIE_SECURE", "false"),
+	c := a.settings()
+
+	sess := session.Session{
+		CookieDomain:   c.Session.CookieDomain,
+		CookieLifetime: strconv.Itoa(c.Session.CookieLifetime),
+		CookieName:     c.Session.CookieName,
+		CookiePersist:  strconv.FormatBool(c.Session.CookiePersist),
+		CookieSecure:   strconv.FormatBool(c.Session.CookieSecure),
+		SessionType:    strings.ToLower(c.Session.Type),
 	}
 
-	switch strings.ToLower(os.Getenv("SESSION_TYPE")) {
+	switch sess.SessionType {
 	case "redis":
-		//...
+		pool, err := a.BootstrapRedisPool()
+		if err != nil {
+			return nil, err
+		}
+		sess.RedisPool = pool
+		sess.RedisPrefix = a.redisPrefix()
 
 	case "mysql", "postgres", "mariadb", "postgresql":
-		//...
+		if a.DB == nil || a.DB.Pool == nil {
+			return nil, fmt.Errorf("session type %s requires a database connection", sess.SessionType)
+		}
+		sess.DBPool = a.DB.Pool
 	default:
 		a.Log.Warn("sessions using in-memory session store")
 	}
 
-	manager := session.InitSession()
+	manager := sess.InitSession()
+
+	if store, ok := manager.Store.(*session.SQLStore); ok {
+		a.BootstrapSessionCleanup(store)
+	}
+
 	return manager, nil
 }
 
+// Register the scheduled job that sweeps expired rows from the sessions table. The
+// schedule defaults to every five minutes and may be changed with the
+// SESSION_CLEANUP_SCHEDULE environment variable using any cron expression.
+func (a *Adele) BootstrapSessionCleanup(store *session.SQLStore) {
+	_, err := a.Schedule.Add("session-cleanup", a.settings().Session.CleanupSchedule, func(ctx context.Context) error {
+		_, err := store.DeleteExpired()
+		return err
+	}, schedule.WithoutOverlapping(0))
+	if err != nil {
+		a.Log.Errorf("failed to schedule session cleanup: %v", err)
+	}
+}
+
 // Setup This code is setting up the Jet template engine for your Adele framework with
 // different configurations based on whether the application is in debug/development mode
 // or production mode—enables features that help during development but would hurt performance
@@ -344,13 +574,14 @@ func (a *Adele) BootstrapJetEngine() *jet.Set {
 		views = jet.NewSet(loader)
 	}
 
+	c := a.settings().Vite
 	v := vite.New(
-		os.Getenv("VITE_HOST"),
-		os.Getenv("VITE_PORT"),
-		os.Getenv("VITE_ROOT_DIR"),
-		os.Getenv("VITE_DIST_DIR"),
-		os.Getenv("VITE_MANIFEST"),
-		os.Getenv("VITE_DEVELOPMENT_MODE"),
+		c.Host,
+		c.Port,
+		c.RootDir,
+		c.DistDir,
+		c.Manifest,
+		c.DevelopmentMode,
 		log.New(os.Stdout, "", log.Ldate|log.Ltime|log.Lshortfile),
 		log.New(os.Stderr, "", log.Ldate|log.Ltime|log.Lshortfile),
 	)
@@ -360,30 +591,80 @@ func (a *Adele) BootstrapJetEngine() *jet.Set {
 	views.AddGlobal("VITE_CLIENT", v.ClientPath)
 	views.AddGlobal("VITE_ASSET", v.GetViteAssetPath)
 	views.AddGlobal("VITE_MANIFEST", v.ParseViteBuildManifest)
+	views.AddGlobalFunc("can", a.jetGate("can", true))
+	views.AddGlobalFunc("cannot", a.jetGate("cannot", false))
+	views.AddGlobalFunc("route", a.jetRoute)
 
 	return views
 }
 
+// Create the can and cannot template functions, which check an ability of the
+// current user like Auth.Can, e.g. {{ if can("update", post) }}. The request is
+// read from the "request" variable set by the renderer.
+func (a *Adele) jetGate(name string, allowed bool) jet.Func {
+	return func(args jet.Arguments) reflect.Value {
+		args.RequireNumOfArguments(name, 1, 2)
+
+		var resource interface{}
+		if args.NumOfArguments() == 2 && args.Get(1).IsValid() {
+			resource = args.Get(1).Interface()
+		}
+
+		can := false
+		if request := args.Runtime().Resolve("request"); request.IsValid() && a.Auth != nil {
+			if r, ok := request.Interface().(*http.Request); ok {
+				can = a.Auth.Can(r, args.Get(0).String(), resource)
+			}
+		}
+
+		return reflect.ValueOf(can == allowed)
+	}
+}
+
+// The route template function builds the path of a named route like
+// Mux.URL, e.g. <a href="{{ route("users.show", user.ID) }}">. An unknown name
+// or params that do not fit the route fail the template.
+func (a *Adele) jetRoute(args jet.Arguments) reflect.Value {
+	args.RequireNumOfArguments("route", 1, -1)
+
+	params := make([]interface{}, 0, args.NumOfArguments()-1)
+	for i := 1; i < args.NumOfArguments(); i++ {
+		params = append(params, args.Get(i).Interface())
+	}
+
+	link, err := a.Routes.URL(args.Get(0).String(), params...)
+	if err != nil {
+		args.Panicf("route: %s", err)
+	}
+	return reflect.ValueOf(link)
+}
+
 // Setup up and configures an HTTP router using the adele mux package. This
 // function returns an http.Handler which represents a chain of middleware
 // and eventually, the handlers for specific routes.
 func (a *Adele) BootstrapMux(rootPath string) (http.Handler, error) {
 
 	// Load the applciation CORS (Cross-Origin Resource Sharing) configuration
-	// from a YAML file and returning it as a mux.Cors object.
+	// from a YAML file and returning it as a mux.Cors object. Without the file
+	// the CORS handler falls back to its defaults.
+	var corsConfig mux.Cors
 	configFile, err := os.ReadFile(fmt.Sprintf("%s/config/cors.yml", rootPath))
-	if err != nil {
-		return nil, fmt.Errorf("failed to read cors config file: %v", err)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		a.Log.Warn("config/cors.yml not found, using default CORS options")
+	case err != nil:
+		return nil, fmt.Errorf("failed to read cors config file: %w", err)
+	default:
+		err = yaml.Unmarshal(configFile, &corsConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cors config file: %w", err)
+		}
 	}
 
-	var corsConfig mux.Cors
-	err = yaml.Unmarshal(configFile, &corsConfig)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %v", err)
-	}
+	httpConfig := a.settings().HTTP
 
 	mux := mux.NewRouter()
-	mux.Use(middleware.TrustedProxy())
+	mux.Use(middleware.TrustedProxyWithConfig(httpConfig.TrustedProxies, httpConfig.TrustProxyHeaders))
 	mux.Use(middleware.RequestID())
 	mux.Use(middleware.RealIP())
 	mux.Use(a.middleware.RateLimiter())
@@ -400,7 +681,7 @@ func (a *Adele) BootstrapMux(rootPath string) (http.Handler, error) {
 	mux.Use(crs.Handler(corsOptions))
 
 	if a.Debug {
-		mux.Use(logger.HttpRequesLogger(logger.CreateLogger()))
+		mux.Use(logger.HttpRequesLogger(logger.NewLogger(a.settings().Log)))
 		mux.Use(a.middleware.RecovererWithDebug)
 	} else {
 		mux.Use(middleware.Recoverer())
@@ -417,11 +698,12 @@ func (a *Adele) BootstrapMux(rootPath string) (http.Handler, error) {
 // handles Rendering HTML templates for web pages, passing session data to templates,
 // and, managing template inheritance and layouts.
 func (a *Adele) BootstrapRender() *render.Render {
+	c := a.settings()
 	r := render.Render{
 		Directory: a.ViewsTemplateDir,
-		Renderer:  a.config.renderer,
+		Renderer:  c.App.Renderer,
 		RootPath:  a.RootPath,
-		Port:      a.config.port,
+		Port:      c.HTTP.Port,
 		JetViews:  a.JetViews,
 		Session:   a.Session,
 	}
@@ -432,51 +714,243 @@ func (a *Adele) BootstrapRender() *render.Render {
 // Cache initialization method that automatically detects and configures the appropriate
 // caching system during application startup based on environment variables.
 func (a *Adele) BootstrapCache(rootPath string) error {
-	if cache.UsesRedis() {
-		host := Helpers.Getenv("REDIS_HOST", "localhost") + ":" + Helpers.Getenv("REDIS_PORT", "6379")
-		maxIdle := Helpers.Getenv("REDIS_MAX_IDLE", "")
-		if maxIdle == "" {
-			maxIdle = Helpers.Getenv("REDIS_MAX_IDEL", "50")
-		}
-		pool, err := redisdriver.CreateRedisPool(
-			maxIdle,
-			Helpers.Getenv("REDIS_MAX_ACTIVE_CONNECTIONS", "10000"),
-			Helpers.Getenv("REDIS_TIMEOUT", "240"),
-			host,
-			Helpers.Getenv("REDIS_USERNAME", ""),
-			Helpers.Getenv("REDIS_PASSWORD", ""),
-		)
+	driver := strings.ToLower(a.settings().Cache.Driver)
+
+	if driver == "redis" {
+		pool, err := a.BootstrapRedisPool()
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to create redis pool: %w", err)
 		}
 
 		rc := redisdriver.RedisCache{
 			Conn:   pool,
-			Prefix: Helpers.Getenv("REDIS_PREFIX", Helpers.Getenv("APP_NAME")),
+			Prefix: a.redisPrefix(),
 		}
 
 		a.Cache = &rc
 
 	}
 
-	if cache.UsesBadger() {
+	if driver == "badger" {
 
 		bc := badgerdriver.BadgerCache{
-			Conn: badgerdriver.CreateBadgerPool(a.RootPath + "/resources/badger"),
+			Conn: badgerdriver.CreateBadgerPool(rootPath + "/resources/badger"),
+		}
+
+		if bc.Conn == nil {
+			return fmt.Errorf("failed to open badger cache at %s/resources/badger", rootPath)
 		}
 
 		a.Cache = &bc
 
-		a.Scheduler.AddFunc("@daily", func() {
-			if err := badgerdriver.BadgerCacheClean(&bc); err != nil {
-				a.Log.Errorf("Badger cache cleanup failed: %v", err)
-			}
-		})
+		a.Schedule.Add("badger-cache-clean", "@daily", func(ctx context.Context) error {
+			return badgerdriver.BadgerCacheClean(&bc)
+		}, schedule.WithoutOverlapping(0))
+	}
+
+	return nil
+}
+
+// Set up email verification. Links are signed with the application key and
+// mailed from the verify-email template in resources/mail, which receives the
+// user and the link as data.User and data.Link. Without a key no link can be
+// signed safely, so verification stays off.
+func (a *Adele) BootstrapEmailVerification() *auth.EmailVerification {
+	if a.EncryptionKey == "" {
+		return nil
+	}
+
+	c := a.settings().Auth
+	return &auth.EmailVerification{
+		Signer: &urlsigner.Signer{Secret: []byte(a.EncryptionKey)},
+		Send: func(user *auth.User, link string) error {
+			return a.Mail.Send(mailer.Message{
+				To:       user.Email,
+				Subject:  "Verify your email address",
+				Template: "verify-email",
+				Data: struct {
+					User *auth.User
+					Link string
+				}{user, link},
+			})
+		},
+		URL:            a.Server.URL,
+		Path:           c.VerifyPath,
+		NoticeURL:      c.VerifyNoticeURL,
+		Expiry:         time.Duration(c.VerifyExpiry) * time.Minute,
+		ResendInterval: time.Duration(c.VerifyResendInterval) * time.Second,
+	}
+}
+
+// Create the password hasher selected by AUTH_HASHER, with the cost or the
+// argon2id parameters from the other AUTH_* settings.
+func (a *Adele) BootstrapHasher() auth.Hasher {
+	c := a.settings().Auth
+	if strings.EqualFold(c.Hasher, "argon2id") {
+		h := auth.NewArgon2idHasher()
+		h.Memory = uint32(c.Argon2Memory)
+		h.Iterations = uint32(c.Argon2Iterations)
+		h.Parallelism = uint8(c.Argon2Parallelism)
+		return h
 	}
 
+	return &auth.BcryptHasher{Cost: c.BcryptCost}
+}
+
+// Create the failed login throttle from the AUTH_THROTTLE_* settings. Failures
+// are counted in the application cache, so logins are not throttled without one.
+func (a *Adele) BootstrapLoginThrottle() *auth.LoginThrottle {
+	c := a.settings().Auth
+	if a.Cache == nil || (c.ThrottleMaxAttempts == 0 && c.ThrottleMaxIPAttempts == 0) {
+		return nil
+	}
+
+	t := auth.NewLoginThrottle(a.Cache)
+	t.MaxAttempts = c.ThrottleMaxAttempts
+	t.MaxIPAttempts = c.ThrottleMaxIPAttempts
+	t.Decay = time.Duration(c.ThrottleDecay) * time.Second
+	t.Lockout = time.Duration(c.ThrottleLockout) * time.Second
+	t.MaxLockout = time.Duration(c.ThrottleMaxLockout) * time.Second
+
+	return t
+}
+
+// Create the background job queue with the driver selected by QUEUE_TYPE. The
+// redis driver shares the application's Redis pool and the database driver uses
+// the jobs and failed_jobs tables, so the database must be booted first.
+func (a *Adele) BootstrapQueue() error {
+	c := a.settings().Queue
+
+	var driver queue.Driver
+	switch strings.ToLower(c.Type) {
+	case "redis":
+		pool, err := a.BootstrapRedisPool()
+		if err != nil {
+			return fmt.Errorf("failed to create redis pool: %w", err)
+		}
+		driver = queue.NewRedisDriver(pool, a.redisPrefix())
+	case "database":
+		if a.DB == nil || a.DB.Pool == nil {
+			return errors.New("the database queue requires a database connection")
+		}
+		driver = queue.NewSQLDriver(a.DB.Pool, a.DB.DataType)
+	default:
+		driver = queue.NewMemoryDriver()
+	}
+
+	q := queue.New(driver)
+	q.DefaultQueue = c.Default
+	q.MaxAttempts = c.MaxAttempts
+	q.Timeout = time.Duration(c.Timeout) * time.Second
+
+	a.Queue = q
+
 	return nil
 }
 
+// Create a worker for the named queues, or the default queue, using the worker
+// settings from the queue configuration and the application logger.
+func (a *Adele) NewQueueWorker(queues ...string) *queue.Worker {
+	c := a.settings().Queue
+
+	w := a.Queue.NewWorker(queues...)
+	w.Concurrency = c.Concurrency
+	w.RetryAfter = time.Duration(c.RetryAfter) * time.Second
+	w.Backoff = time.Duration(c.Backoff) * time.Second
+	w.Sleep = time.Duration(c.Sleep) * time.Second
+	w.Log = a.Log
+
+	return w
+}
+
+// Create the OAuth authorization server. Clients and tokens are kept in the oauth
+// tables when a database is booted, and in memory otherwise. Users approve
+// clients while logged in to the application, on the consent page rendered from
+// OAUTH_CONSENT_VIEW with the request in a "consent" variable.
+func (a *Adele) BootstrapOAuth() *oauth.Server {
+	c := a.settings().OAuth
+
+	var store oauth.Store = oauth.NewMemoryStore()
+	if a.DB != nil && a.DB.Pool != nil {
+		store = oauth.NewSQLStore(a.DB.Pool, a.DB.DataType)
+	}
+
+	s := oauth.New(store)
+	s.AccessTokenTTL = time.Duration(c.AccessTokenTTL) * time.Second
+	s.RefreshTokenTTL = time.Duration(c.RefreshTokenTTL) * time.Second
+	s.AuthCodeTTL = time.Duration(c.AuthCodeTTL) * time.Second
+	s.LoginURL = c.LoginURL
+	s.ErrorLog = a.ErrorLog
+	s.Session = a.Session
+
+	s.User = func(r *http.Request) (int, bool) {
+		if a.Session == nil || !a.Session.Exists(r.Context(), "userID") {
+			return 0, false
+		}
+		return a.Session.GetInt(r.Context(), "userID"), true
+	}
+
+	s.Consent = func(w http.ResponseWriter, r *http.Request, consent *oauth.Consent) error {
+		if a.Render == nil {
+			return errors.New("the consent page needs the renderer to be booted")
+		}
+
+		vars := make(jet.VarMap)
+		vars.Set("consent", consent)
+		td := &render.TemplateData{Data: map[string]interface{}{"consent": consent}}
+
+		return a.Render.Page(w, r, c.ConsentView, vars, td)
+	}
+
+	return s
+}
+
+// Create the Redis connection pool shared by the cache and session store. The pool
+// is built once and reused on subsequent calls so every subsystem configured
+// with redis borrows connections from the same pool.
+func (a *Adele) BootstrapRedisPool() (*redis.Pool, error) {
+	if a.RedisPool != nil {
+		return a.RedisPool, nil
+	}
+
+	c := a.settings().Redis
+	pool, err := redisdriver.CreateRedisPool(
+		strconv.Itoa(c.MaxIdle),
+		strconv.Itoa(c.MaxActive),
+		strconv.Itoa(c.IdleTimeout),
+		c.Addr(),
+		c.Username,
+		c.Password,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	a.RedisPool = pool
+
+	return pool, nil
+}
+
+// The key prefix for redis backed sessions and cache entries, defaulting to the
+// application name.
+func (a *Adele) redisPrefix() string {
+	c := a.settings()
+	if c.Redis.Prefix != "" {
+		return c.Redis.Prefix
+	}
+	return c.App.Name
+}
+
+// Return the application configuration validated by New. An Adele value
+// created without New must be given a Config, e.g. one loaded with config.New,
+// before it is bootstrapped.
+func (a *Adele) settings() *config.Config {
+	if a.Config == nil {
+		panic("adele: the application has no configuration; create it with New or set Config")
+	}
+	return a.Config
+}
+
 // Ensure that a environment file at a specific path exists, creating it if it's missing, and returning
 // any errors that may arise.
 func (a *Adele) CreateEnvironmentFile(rootPath string) error {
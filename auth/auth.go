@@ -1,27 +1,30 @@
 // Package auth provides session-based user authentication for Adele applications.
 //
-// It handles login and logout, "remember me" cookie tokens, retrieval of the
-// currently authenticated user, and bcrypt password hashing, persisting users and
-// remember tokens through the framework's database layer.
+// It handles login and logout with optional TOTP two-factor authentication and
+// failed login throttling, rotating "remember me" cookie tokens, email
+// verification links, retrieval of the currently authenticated user, listing
+// and revoking the sessions a user is logged in with on each device, audited
+// impersonation of users by support staff, and bcrypt or argon2id password
+// hashing. Users are looked up through a pluggable UserProvider, by default the
+// users table of the framework's database layer. Clients that cannot hold a
+// cookie session authenticate with hashed personal access tokens checked
+// against the scopes annotated on mux routes. Abilities are authorized with
+// gates, per model policies and database-backed roles and permissions.
 package auth
 
 import (
-	"crypto/rand"
-	"crypto/sha256"
-	"encoding/base64"
-	"errors"
-	"fmt"
 	"net/http"
-	"os"
-	"reflect"
-	"time"
 
-	up "github.com/upper/db/v4"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// Check if a user is logged in.
+// Check if a user is logged in, either through the session or with a personal
+// access token accepted by TokenMiddleware.
 func (a *Auth) Check(r *http.Request) bool {
+	if a.Token(r) != nil {
+		return true
+	}
+
 	uid := a.Session.Get(r.Context(), "userID")
 	if uid != nil {
 		return true
@@ -30,171 +33,125 @@ func (a *Auth) Check(r *http.Request) bool {
 	return false
 }
 
-// Log the current user out.
+// Log the current user out and redirect to the route named login.
 func (a *Auth) Logout(w http.ResponseWriter, r *http.Request) (bool, error) {
 
 	// Delete remember token if exists
-	if a.Session.Exists(r.Context(), "remember_token") {
-		token := a.Session.GetString(r.Context(), "remember_token")
-		session := a.DB.NewSession()
-		collection := session.Collection("remember_tokens")
-		res := collection.Find(up.Cond{"remember_token": token})
-		err := res.Delete()
-		if err != nil {
-			return false, RememberTokenDeleteError
-		}
+	if err := a.forgetRemembered(r); err != nil {
+		return false, RememberTokenDeleteError
 	}
-
-	var appName string
-	value, ok := os.LookupEnv("APP_NAME")
-	if !ok {
-		appName = "adele"
-	} else {
-		appName = value
-	}
-
-	// Delete the cookie
-	cookie := http.Cookie{
-		Name:     fmt.Sprintf("%s_remember", appName),
-		Value:    "",
-		Path:     "/",
-		Expires:  time.Now().Add(-100 * time.Hour),
-		HttpOnly: true,
-		Domain:   a.Session.Cookie.Domain,
-		MaxAge:   -1,
-		Secure:   a.Session.Cookie.Secure,
-		SameSite: http.SameSiteStrictMode,
-	}
-
-	http.SetCookie(w, &cookie)
+	a.forgetRememberCookie(w)
 
 	// Log the user out of the system
 	a.Session.RenewToken(r.Context())
 	a.Session.Remove(r.Context(), "userID")
-	a.Session.Remove(r.Context(), "remember_token")
+	a.Session.Remove(r.Context(), rememberSessionKey)
 	a.Session.Destroy(r.Context())
 	a.Session.RenewToken(r.Context())
 
-	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	http.Redirect(w, r, a.loginURL(), http.StatusSeeOther)
 
 	return true, nil
 }
 
-// Log a user in.
-func (a *Auth) Login(w http.ResponseWriter, r *http.Request, email, password string) (bool, error) {
-
-	// look up the current user
-	var user User
-	session := a.DB.NewSession()
-	collection := session.Collection("users")
-	res := collection.Find(up.Cond{"email =": email})
-	err := res.One(&user)
+// The name of the route Logout redirects to.
+const loginRouteName = "login"
 
-	if err != nil {
-
-		if err == up.ErrNoMoreRows {
-			return false, nil
+// The path of the route named login, or /login when no route has the name.
+func (a *Auth) loginURL() string {
+	if a.Routes != nil {
+		if link, err := a.Routes.URL(loginRouteName); err == nil {
+			return link
 		}
-		return false, err
 	}
+	return "/login"
+}
 
-	if reflect.DeepEqual(user, User{}) {
-		return false, InvalidPasswordOrUserError
+// Log a user in with their login, the email address unless the UserProvider
+// identifies users otherwise, and password. While Throttle locks out the login
+// or client IP after failed attempts, a *LoginThrottledError with the time to
+// wait is returned. When TwoFactor is set and the user enabled two-factor
+// authentication, the user is not logged in yet: TwoFactorRequiredError is
+// returned and the login is finished by VerifyTwoFactor. The failed logins
+// Throttle counted against the login are forgotten once every factor passed.
+func (a *Auth) Login(w http.ResponseWriter, r *http.Request, login, password string) (bool, error) {
+
+	if err := a.checkLoginThrottle(r, login); err != nil {
+		return false, err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	// look up the current user
+	credentials := Credentials{Login: login, Password: password}
+	users := a.users()
+	user, err := users.FindByCredentials(r.Context(), credentials)
 	if err != nil {
-		switch {
-		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
-			return false, InvalidPasswordOrUserError
-		default:
-			return false, err
-		}
+		return false, err
 	}
-
-	// create and store a remember token
-	randomString := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0987654321_+"
-	s, rn := make([]rune, 12), []rune(randomString)
-	for i := range s {
-		p, _ := rand.Prime(rand.Reader, len(rn))
-		x, y := p.Uint64(), uint64(len(rn))
-		s[i] = rn[x%y]
+	if user == nil {
+		a.loginFailed(r, login)
+		return false, nil
 	}
 
-	hasher := sha256.New()
-	_, err = hasher.Write([]byte(string(s)))
+	ok, err := users.ValidateCredentials(r.Context(), user, credentials)
 	if err != nil {
-		return false, RememberTokenHashError
+		return false, err
+	}
+	if !ok {
+		a.loginFailed(r, login)
+		return false, InvalidPasswordOrUserError
 	}
 
-	sha := base64.URLEncoding.EncodeToString(hasher.Sum(nil))
+	a.rehashPassword(r.Context(), users, user, password)
 
-	collection = session.Collection("remember_tokens")
-	rememberToken := RememberToken{
-		UserID:        user.ID,
-		RememberToken: sha,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-	}
-	_, err = collection.Insert(rememberToken)
-	if err != nil {
-		return false, RememberTokenStoreError
+	if a.TwoFactor {
+		enabled, err := a.TwoFactorEnabled(r.Context(), user.ID)
+		if err != nil {
+			return false, err
+		}
+		if enabled {
+			if err := a.startTwoFactor(r, user.ID, login); err != nil {
+				return false, err
+			}
+			return false, TwoFactorRequiredError
+		}
 	}
 
-	var appName string
-	value, ok := os.LookupEnv("APP_NAME")
-	if !ok {
-		appName = "adele"
-	} else {
-		appName = value
+	if ok, err := a.completeLogin(w, r, user.ID); !ok {
+		return false, err
 	}
+	a.loginSucceeded(r, login)
+	return true, nil
+}
 
-	// Set the cookie using the session configuration
-	expiry := time.Now().Add(365 * 24 * 60 * 60 * time.Second)
-	cookie := http.Cookie{
-		Name:     fmt.Sprintf("%s_remember", appName),
-		Value:    fmt.Sprintf("%d|%s", user.ID, sha),
-		Path:     "/",
-		Expires:  expiry,
-		HttpOnly: true,
-		Domain:   a.Session.Cookie.Domain,
-		MaxAge:   315350000,
-		Secure:   a.Session.Cookie.Secure,
-		SameSite: http.SameSiteStrictMode,
+// Write the remember token and the user id into the session of a user whose
+// credentials have been verified.
+func (a *Auth) completeLogin(w http.ResponseWriter, r *http.Request, userID int) (bool, error) {
+	if err := a.remember(w, r, userID); err != nil {
+		return false, err
 	}
 
-	http.SetCookie(w, &cookie)
-
-	// add remember token to session
-	a.Session.Put(r.Context(), "remember_token", sha)
-
 	// add user id to session
-	a.Session.Put(r.Context(), "userID", user.ID)
+	a.Session.Put(r.Context(), "userID", userID)
 
 	return true, nil
 }
 
-// Get the current authenticated user
+// Get the current authenticated user from the UserProvider.
 func (a *Auth) User(r *http.Request) *User {
-
-	uid := a.Session.Get(r.Context(), "userID")
-	if uid != nil {
-		var theUser User
-		session := a.DB.NewSession()
-		collection := session.Collection("users")
-		res := collection.Find(up.Cond{"id =": uid})
-
-		err := res.One(&theUser)
-		if err != nil {
-			return nil
-		}
-		return &theUser
+	uid := a.userID(r)
+	if uid == 0 {
+		return nil
 	}
 
-	return nil
+	user, err := a.users().FindByID(r.Context(), uid)
+	if err != nil {
+		return nil
+	}
+	return user
 }
 
-// hash a plain text password for secure use in the application
+// hash a plain text password for secure use in the application with bcrypt
+// at cost 12. Auth.Hasher hashes with the configured algorithm instead.
 func HashPassword(password string) (*[]byte, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
 	if err != nil {
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cidekar/adele-framework/mux"
+)
+
+func TestLogout_RedirectsToLoginRoute(t *testing.T) {
+	a, _, _ := newRememberAuth(t)
+
+	logout := func() string {
+		w := httptest.NewRecorder()
+		a.Session.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			a.Session.Put(r.Context(), "userID", 7)
+			if ok, err := a.Logout(w, r); !ok || err != nil {
+				t.Fatalf("expected the user to be logged out, got %v", err)
+			}
+		})).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/logout", nil))
+		return w.Header().Get("Location")
+	}
+
+	if location := logout(); location != "/login" {
+		t.Errorf("expected /login without a router, got %s", location)
+	}
+
+	a.Routes = mux.NewRouter()
+	a.Routes.Get("/sign-in[name:login]", func(w http.ResponseWriter, r *http.Request) {})
+	if location := logout(); location != "/sign-in" {
+		t.Errorf("expected the route named login, got %s", location)
+	}
+}
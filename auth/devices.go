@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cidekar/adele-framework/database"
+	"github.com/cidekar/adele-framework/internal/bearer"
+	"github.com/cidekar/adele-framework/session"
+)
+
+// Session keys remembering which device was last recorded for the session,
+// and when.
+const (
+	sessionDeviceKey  = "sessionDevice"
+	sessionTouchedKey = "sessionTouchedAt"
+)
+
+// How often the last activity of a session is written to the store, so a busy
+// session does not cost a write per request.
+const sessionTouchInterval = time.Minute
+
+// The longest user agent recorded for a session.
+const maxUserAgentLength = 255
+
+// TrackSessions records the IP address, user agent and last activity of the
+// sessions of logged in users, which Sessions lists. It belongs after the
+// session is loaded and after RememberMiddleware, and does nothing unless the
+// session store is a session.DeviceStore, such as the database and Redis
+// stores.
+func (a *Auth) TrackSessions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.trackSession(r)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// List the sessions a user is logged in with, most recently active first.
+func (a *Auth) Sessions(ctx context.Context, userID int) ([]UserSession, error) {
+	store, ok := a.Session.Store.(session.DeviceStore)
+	if !ok {
+		return nil, SessionDevicesUnsupportedError
+	}
+
+	devices, err := store.UserDevices(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := a.sessionToken(ctx)
+	sessions := make([]UserSession, 0, len(devices))
+	for _, d := range devices {
+		sessions = append(sessions, UserSession{
+			ID:           bearer.Hash(d.Token),
+			IPAddress:    d.IPAddress,
+			UserAgent:    d.UserAgent,
+			LastActivity: d.LastActivity,
+			Current:      d.Token == current,
+		})
+	}
+
+	return sessions, nil
+}
+
+// End one of a user's sessions by the ID listed by Sessions, along with the
+// remember token issued to it. The user ID is part of the match so a user can
+// only end their own sessions.
+func (a *Auth) RevokeSession(ctx context.Context, userID int, id string) error {
+	store, ok := a.Session.Store.(session.DeviceStore)
+	if !ok {
+		return SessionDevicesUnsupportedError
+	}
+
+	devices, err := store.UserDevices(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range devices {
+		if subtle.ConstantTimeCompare([]byte(bearer.Hash(d.Token)), []byte(id)) == 1 {
+			return a.endSession(ctx, store, d.Token)
+		}
+	}
+
+	return SessionNotFoundError
+}
+
+// End every session of the current user except the one of the request, and
+// revoke the remember tokens of other browsers, e.g. after the user changed
+// their password.
+func (a *Auth) LogoutOtherDevices(r *http.Request) error {
+	userID := a.Session.GetInt(r.Context(), "userID")
+	if userID == 0 {
+		return nil
+	}
+
+	store, ok := a.Session.Store.(session.DeviceStore)
+	if !ok {
+		return SessionDevicesUnsupportedError
+	}
+
+	devices, err := store.UserDevices(userID)
+	if err != nil {
+		return err
+	}
+
+	current := a.Session.Token(r.Context())
+	for _, d := range devices {
+		if d.Token == current {
+			continue
+		}
+		if err := a.endSession(r.Context(), store, d.Token); err != nil {
+			return err
+		}
+	}
+
+	return a.forgetOtherRemembered(r, userID)
+}
+
+// Record the device of the session of a logged in user when it changed or
+// was last recorded more than sessionTouchInterval ago.
+func (a *Auth) trackSession(r *http.Request) {
+	store, ok := a.Session.Store.(session.DeviceStore)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	userID := a.Session.GetInt(ctx, "userID")
+	token := a.Session.Token(ctx)
+	if userID == 0 || token == "" {
+		return
+	}
+
+	// An impersonated user is not shown the session of the support staff.
+	if impersonatorID := a.Session.GetInt(ctx, impersonatorSessionKey); impersonatorID != 0 {
+		userID = impersonatorID
+	}
+
+	agent := r.UserAgent()
+	if len(agent) > maxUserAgentLength {
+		agent = agent[:maxUserAgentLength]
+	}
+
+	// The token is part of the device so a renewed session is recorded again.
+	device := fmt.Sprintf("%s|%s|%s", bearer.Hash(token), clientIP(r), agent)
+	touched := time.Unix(a.Session.GetInt64(ctx, sessionTouchedKey), 0)
+	if device == a.Session.GetString(ctx, sessionDeviceKey) && time.Since(touched) < sessionTouchInterval {
+		return
+	}
+
+	now := time.Now()
+	err := store.TouchDevice(session.Device{Token: token, UserID: userID, IPAddress: clientIP(r), UserAgent: agent, LastActivity: now})
+	if err != nil {
+		a.logError("auth: track session:", err)
+		return
+	}
+
+	a.Session.Put(ctx, sessionDeviceKey, device)
+	a.Session.Put(ctx, sessionTouchedKey, now.Unix())
+}
+
+// Delete a session from the store, and the remember token issued to it so the
+// browser is not logged back in by RememberMiddleware.
+func (a *Auth) endSession(ctx context.Context, store session.DeviceStore, token string) error {
+	b, found, err := store.Find(token)
+	if err != nil {
+		return err
+	}
+
+	if found {
+		if _, values, err := a.Session.Codec.Decode(b); err == nil {
+			if selector, _ := values[rememberSessionKey].(string); selector != "" && a.remembers() {
+				_, err := a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), fmt.Sprintf("DELETE FROM %s WHERE selector = ?", a.rememberTable())), selector)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return store.Delete(token)
+}
+
+// The token of the session in ctx, or "" when ctx carries no session, e.g. in
+// a background job.
+func (a *Auth) sessionToken(ctx context.Context) (token string) {
+	defer func() {
+		if recover() != nil {
+			token = ""
+		}
+	}()
+
+	return a.Session.Token(ctx)
+}
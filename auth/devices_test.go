@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alexedwards/scs/v2"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cidekar/adele-framework/session"
+	"github.com/gomodule/redigo/redis"
+)
+
+func newDeviceAuth(t *testing.T) (*Auth, sqlmock.Sqlmock) {
+	mr := miniredis.RunT(t)
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) { return redis.Dial("tcp", mr.Addr()) }}
+	t.Cleanup(func() { pool.Close() })
+
+	a, mock, _ := newRememberAuth(t)
+	a.Session.Store = session.NewRedisStore(pool, "")
+	return a, mock
+}
+
+// Log user 7 in on a device and serve a request from it through
+// TrackSessions, returning the session cookie.
+func loginDevice(t *testing.T, a *Auth, agent, selector string) *http.Cookie {
+	cookie := inSession(a, nil, func(w http.ResponseWriter, r *http.Request) {
+		a.Session.Put(r.Context(), "userID", 7)
+		a.Session.Put(r.Context(), rememberSessionKey, selector)
+	})
+
+	onDevice(a, cookie, agent, func(w http.ResponseWriter, r *http.Request) {})
+	return cookie
+}
+
+// Serve fn through TrackSessions on the session of cookie.
+func onDevice(a *Auth, cookie *http.Cookie, agent string, fn http.HandlerFunc) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:51234"
+	r.Header.Set("User-Agent", agent)
+	r.AddCookie(cookie)
+	a.Session.LoadAndSave(a.TrackSessions(fn)).ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestSessions_ListAndRevoke(t *testing.T) {
+	a, mock := newDeviceAuth(t)
+
+	laptop := loginDevice(t, a, "Laptop", "laptop-sel")
+	loginDevice(t, a, "Phone", "phone-sel")
+
+	var sessions []UserSession
+	onDevice(a, laptop, "Laptop", func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		if sessions, err = a.Sessions(r.Context(), 7); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if len(sessions) != 2 {
+		t.Fatalf("expected two sessions, got %+v", sessions)
+	}
+
+	var phone UserSession
+	for _, s := range sessions {
+		if s.UserAgent == "Phone" {
+			phone = s
+		}
+		if s.Current != (s.UserAgent == "Laptop") || s.IPAddress != "10.0.0.1" || s.LastActivity.IsZero() {
+			t.Errorf("unexpected session %+v", s)
+		}
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM remember_tokens WHERE selector = $1")).WithArgs("phone-sel").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := a.RevokeSession(t.Context(), 7, phone.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.RevokeSession(t.Context(), 8, sessions[0].ID); !errors.Is(err, SessionNotFoundError) {
+		t.Errorf("expected another user's session to be refused, got %v", err)
+	}
+
+	if sessions, _ := a.Sessions(t.Context(), 7); len(sessions) != 1 || sessions[0].UserAgent != "Laptop" {
+		t.Errorf("expected only the laptop session, got %+v", sessions)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLogoutOtherDevices(t *testing.T) {
+	a, mock := newDeviceAuth(t)
+
+	laptop := loginDevice(t, a, "Laptop", "laptop-sel")
+	loginDevice(t, a, "Phone", "phone-sel")
+	loginDevice(t, a, "Tablet", "tablet-sel")
+
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectExec("DELETE FROM remember_tokens WHERE selector").WithArgs("phone-sel").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM remember_tokens WHERE selector").WithArgs("tablet-sel").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM remember_tokens WHERE user_id = $1 AND selector <> $2")).WithArgs(7, "laptop-sel").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	onDevice(a, laptop, "Laptop", func(w http.ResponseWriter, r *http.Request) {
+		if err := a.LogoutOtherDevices(r); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if sessions, _ := a.Sessions(t.Context(), 7); len(sessions) != 1 || sessions[0].UserAgent != "Laptop" {
+		t.Errorf("expected only the laptop session to remain, got %+v", sessions)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSessions_Unsupported(t *testing.T) {
+	a := &Auth{Session: scs.New()}
+	if _, err := a.Sessions(t.Context(), 7); !errors.Is(err, SessionDevicesUnsupportedError) {
+		t.Errorf("expected the memory store to be unsupported, got %v", err)
+	}
+}
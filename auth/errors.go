@@ -1,6 +1,10 @@
 package auth
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var InvalidPasswordOrUserError = errors.New("the username or password is incorrect")
 
@@ -9,3 +13,72 @@ var RememberTokenHashError = errors.New("error hashing remember token")
 var RememberTokenStoreError = errors.New("error adding remember token to storage")
 
 var RememberTokenDeleteError = errors.New("error removing remember token form storage")
+
+var RememberTokenInvalidError = errors.New("the remember token is invalid")
+
+var RememberTokenReusedError = errors.New("the remember token was used after its rotation")
+
+// A request sent with the validator a concurrent request just rotated.
+var rememberTokenRotatedError = errors.New("the remember token was rotated")
+
+var UnsupportedHashError = errors.New("the password hash uses an unsupported algorithm")
+
+var SessionNotFoundError = errors.New("the session does not exist")
+
+var SessionDevicesUnsupportedError = errors.New("the session store does not track devices")
+
+var UserNotFoundError = errors.New("the user does not exist")
+
+var ImpersonationForbiddenError = errors.New("the user may not impersonate other users")
+
+var ImpersonationNestedError = errors.New("already impersonating a user")
+
+var ImpersonateSelfError = errors.New("users cannot impersonate themselves")
+
+var NotImpersonatingError = errors.New("no user is being impersonated")
+
+var TokenInvalidError = errors.New("the access token is invalid")
+
+var TokenExpiredError = errors.New("the access token has expired")
+
+var TokenNotFoundError = errors.New("the access token does not exist")
+
+var RoleNotFoundError = errors.New("the role does not exist")
+
+var TwoFactorRequiredError = errors.New("a two-factor code is required to finish logging in")
+
+var TwoFactorInvalidError = errors.New("the two-factor code is incorrect")
+
+var TwoFactorNotEnabledError = errors.New("two-factor authentication is not enabled")
+
+var TwoFactorEnabledError = errors.New("two-factor authentication is already enabled")
+
+var TwoFactorNoLoginError = errors.New("no login is awaiting a two-factor code")
+
+var EmailVerificationDisabledError = errors.New("email verification is not configured")
+
+var EmailAlreadyVerifiedError = errors.New("the email address is already verified")
+
+var VerificationLinkInvalidError = errors.New("the verification link is invalid")
+
+var VerificationLinkExpiredError = errors.New("the verification link has expired")
+
+// LoginThrottledError is returned by Login while an email address or client IP
+// is locked out after too many failed attempts. No credentials are checked.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("too many login attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// VerificationThrottledError is returned when a user asks for another
+// verification email too soon after the last one.
+type VerificationThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *VerificationThrottledError) Error() string {
+	return fmt.Sprintf("a verification email was sent recently, try again in %s", e.RetryAfter.Round(time.Second))
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/cidekar/adele-framework/internal/bearer"
+)
+
+// Define the gate deciding an ability that is not tied to a model type, e.g.
+//
+//	a.Auth.Define("view-reports", func(r *http.Request, user *auth.User, _ interface{}) bool {
+//		return user.Email == "admin@example.com"
+//	})
+//
+// Defining an ability again replaces its gate.
+func (a *Auth) Define(ability string, gate Gate) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.gates == nil {
+		a.gates = make(map[string]Gate)
+	}
+	a.gates[ability] = gate
+}
+
+// Register the policy deciding the abilities on a model type. The model is
+// any value of the type, a pointer or not, e.g.
+//
+//	a.Auth.RegisterPolicy(&Post{}, auth.Policy{
+//		"update": func(r *http.Request, user *auth.User, resource interface{}) bool {
+//			return resource.(*Post).UserID == user.ID
+//		},
+//	})
+//
+// The policy decides a.Auth.Can(r, "update", post) for both Post and *Post
+// resources, so a gate must accept whichever form the application passes.
+func (a *Auth) RegisterPolicy(model interface{}, policy Policy) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.policies == nil {
+		a.policies = make(map[reflect.Type]Policy)
+	}
+	a.policies[modelType(model)] = policy
+}
+
+// Check if the current user may perform an ability, optionally on a resource.
+// The ability is decided by the first of:
+//
+//   - the policy registered for the type of the resource, if it has the ability
+//   - the gate defined for the ability
+//   - the permissions the Permissions resolver grants the user, so an ability
+//     named after a permission, such as "posts:write", needs no gate
+//
+// Guests may do nothing.
+func (a *Auth) Can(r *http.Request, ability string, resource interface{}) bool {
+	return a.UserCan(r, a.User(r), ability, resource)
+}
+
+// Check if the current user may not perform an ability, see Can.
+func (a *Auth) Cannot(r *http.Request, ability string, resource interface{}) bool {
+	return !a.Can(r, ability, resource)
+}
+
+// Check if a user other than the current one may perform an ability, see Can.
+// A nil user is a guest.
+func (a *Auth) UserCan(r *http.Request, user *User, ability string, resource interface{}) bool {
+	if user == nil {
+		return false
+	}
+
+	if gate := a.gate(ability, resource); gate != nil {
+		return gate(r, user, resource)
+	}
+
+	if a.Permissions == nil {
+		return false
+	}
+
+	permissions, err := a.Permissions(r, user)
+	if err != nil {
+		a.logError("auth: resolve permissions:", err)
+		return false
+	}
+
+	return bearer.Grants(permissions, []string{ability})
+}
+
+// Find the policy gate for the resource or the gate defined for the ability.
+func (a *Auth) gate(ability string, resource interface{}) Gate {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if resource != nil {
+		if gate, ok := a.policies[modelType(resource)][ability]; ok {
+			return gate
+		}
+	}
+
+	return a.gates[ability]
+}
+
+// The type policies are registered under, with pointers dereferenced.
+func modelType(model interface{}) reflect.Type {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+type post struct {
+	UserID int
+}
+
+func TestUserCan_PoliciesGatesAndPermissions(t *testing.T) {
+	a := &Auth{
+		Permissions: func(r *http.Request, user *User) ([]string, error) {
+			return []string{"posts:write"}, nil
+		},
+	}
+	a.RegisterPolicy(post{}, Policy{
+		"update": func(r *http.Request, user *User, resource interface{}) bool {
+			return resource.(*post).UserID == user.ID
+		},
+	})
+	a.Define("update", func(r *http.Request, user *User, resource interface{}) bool {
+		return true
+	})
+	a.Define("view-reports", func(r *http.Request, user *User, resource interface{}) bool {
+		return user.ID == 1
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	user := &User{ID: 7}
+
+	tests := []struct {
+		ability  string
+		resource interface{}
+		want     bool
+	}{
+		{"update", &post{UserID: 7}, true},
+		{"update", &post{UserID: 8}, false},
+		{"update", nil, true},
+		{"view-reports", nil, false},
+		{"posts:write", &post{UserID: 8}, true},
+		{"posts:delete", nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := a.UserCan(r, user, tt.ability, tt.resource); got != tt.want {
+			t.Errorf("UserCan(%q, %+v) = %v, want %v", tt.ability, tt.resource, got, tt.want)
+		}
+	}
+
+	if a.UserCan(r, nil, "update", nil) {
+		t.Error("expected a guest to be refused")
+	}
+}
+
+func TestUserCan_ResolverError(t *testing.T) {
+	a := &Auth{
+		Permissions: func(r *http.Request, user *User) ([]string, error) {
+			return []string{"*"}, errors.New("database down")
+		},
+	}
+
+	if a.UserCan(httptest.NewRequest(http.MethodGet, "/", nil), &User{ID: 7}, "posts:write", nil) {
+		t.Error("expected a resolver error to refuse the ability")
+	}
+}
+
+func TestCan_Guest(t *testing.T) {
+	a := &Auth{Session: scs.New()}
+	a.Define("anything", func(r *http.Request, user *User, resource interface{}) bool {
+		return true
+	})
+
+	var can, cannot bool
+	h := a.Session.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		can, cannot = a.Can(r, "anything", nil), a.Cannot(r, "anything", nil)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if can || !cannot {
+		t.Error("expected a guest to be refused every ability")
+	}
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Create a bcrypt hasher with the default cost of 12.
+func NewBcryptHasher() *BcryptHasher {
+	return &BcryptHasher{Cost: 12}
+}
+
+// Create an argon2id hasher with the parameters recommended by RFC 9106 for
+// memory constrained servers: 64 MiB, 3 passes and 2 lanes.
+func NewArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// Hash a password with bcrypt, e.g. $2a$12$....
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	return string(hash), err
+}
+
+// Check a password against a hash made by any supported algorithm.
+func (h *BcryptHasher) Check(password, hash string) (bool, error) {
+	return CheckPassword(password, hash)
+}
+
+// A bcrypt hash with a lower cost needs a rehash. Argon2id hashes are
+// stronger and are kept.
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	if isArgon2id(hash) {
+		return false
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err == nil && cost < h.Cost
+}
+
+// Hash a password with argon2id in the PHC string format, e.g.
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>.
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Check a password against a hash made by any supported algorithm.
+func (h *Argon2idHasher) Check(password, hash string) (bool, error) {
+	return CheckPassword(password, hash)
+}
+
+// Bcrypt hashes, and argon2id hashes made with less memory, fewer passes or
+// lanes, or a shorter key, need a rehash.
+func (h *Argon2idHasher) NeedsRehash(hash string) bool {
+	p, err := parseArgon2id(hash)
+	if err != nil {
+		return isBcrypt(hash)
+	}
+
+	return p.memory < h.Memory || p.iterations < h.Iterations || p.parallelism < h.Parallelism || uint32(len(p.key)) < h.KeyLength
+}
+
+// Check a password against a bcrypt or argon2id hash, picking the algorithm
+// from the hash itself. A wrong password is not an error.
+func CheckPassword(password, hash string) (bool, error) {
+	switch {
+	case isArgon2id(hash):
+		p, err := parseArgon2id(hash)
+		if err != nil {
+			return false, err
+		}
+		key := argon2.IDKey([]byte(password), p.salt, p.iterations, p.memory, p.parallelism, uint32(len(p.key)))
+		return subtle.ConstantTimeCompare(key, p.key) == 1, nil
+	case isBcrypt(hash):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	default:
+		return false, UnsupportedHashError
+	}
+}
+
+// The parameters, salt and key of an argon2id hash.
+type argon2idHash struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func parseArgon2id(hash string) (*argon2idHash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, UnsupportedHashError
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, UnsupportedHashError
+	}
+
+	p := &argon2idHash{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.iterations, &p.parallelism); err != nil {
+		return nil, UnsupportedHashError
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, UnsupportedHashError
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(p.key) == 0 {
+		return nil, UnsupportedHashError
+	}
+
+	return p, nil
+}
+
+func isArgon2id(hash string) bool {
+	return strings.HasPrefix(hash, "$argon2id$")
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// The hasher new passwords are hashed with.
+func (a *Auth) hasher() Hasher {
+	if a.Hasher == nil {
+		return NewBcryptHasher()
+	}
+	return a.Hasher
+}
+
+// Replace the stored hash of a user who just logged in when it is weaker than
+// the configured Hasher would make. Failing to do so is logged but does not
+// fail the login.
+func (a *Auth) rehashPassword(ctx context.Context, users UserProvider, user *User, password string) {
+	h := a.hasher()
+	if !h.NeedsRehash(user.Password) {
+		return
+	}
+
+	updater, ok := users.(PasswordUpdater)
+	if !ok {
+		return
+	}
+
+	hash, err := h.Hash(password)
+	if err != nil {
+		a.logError("auth: rehash password:", err)
+		return
+	}
+	if err := updater.UpdatePassword(ctx, user, hash); err != nil {
+		a.logError("auth: rehash password:", err)
+		return
+	}
+	user.Password = hash
+}
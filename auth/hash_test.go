@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// A fast argon2id hasher for tests.
+func testArgon2idHasher() *Argon2idHasher {
+	h := NewArgon2idHasher()
+	h.Memory = 1024
+	h.Iterations = 1
+	return h
+}
+
+func TestCheckPassword_PicksAlgorithm(t *testing.T) {
+	for _, h := range []Hasher{&BcryptHasher{Cost: bcrypt.MinCost}, testArgon2idHasher()} {
+		hash, err := h.Hash("secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ok, err := CheckPassword("secret", hash); !ok || err != nil {
+			t.Errorf("%s: expected the password to match, got %v %v", hash, ok, err)
+		}
+		if ok, err := CheckPassword("wrong", hash); ok || err != nil {
+			t.Errorf("%s: expected a wrong password to be refused, got %v %v", hash, ok, err)
+		}
+	}
+
+	if !strings.HasPrefix(must(testArgon2idHasher().Hash("secret")), "$argon2id$v=19$m=1024,t=1,p=2$") {
+		t.Error("expected a self-describing argon2id hash")
+	}
+	if _, err := CheckPassword("secret", "5ebe2294ecd0e0f08eab7690d2a6ee69"); !errors.Is(err, UnsupportedHashError) {
+		t.Errorf("expected UnsupportedHashError, got %v", err)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	weakBcrypt := must((&BcryptHasher{Cost: bcrypt.MinCost}).Hash("secret"))
+	bcrypt5 := must((&BcryptHasher{Cost: bcrypt.MinCost + 1}).Hash("secret"))
+	weakArgon := must(testArgon2idHasher().Hash("secret"))
+
+	strongArgon := testArgon2idHasher()
+	strongArgon.Iterations = 2
+
+	tests := []struct {
+		hasher Hasher
+		hash   string
+		want   bool
+	}{
+		{&BcryptHasher{Cost: bcrypt.MinCost + 1}, weakBcrypt, true},
+		{&BcryptHasher{Cost: bcrypt.MinCost + 1}, bcrypt5, false},
+		{&BcryptHasher{Cost: bcrypt.MinCost}, bcrypt5, false},
+		{&BcryptHasher{Cost: bcrypt.MinCost + 1}, weakArgon, false},
+		{testArgon2idHasher(), weakBcrypt, true},
+		{testArgon2idHasher(), weakArgon, false},
+		{strongArgon, weakArgon, true},
+	}
+	for i, tt := range tests {
+		if got := tt.hasher.NeedsRehash(tt.hash); got != tt.want {
+			t.Errorf("%d: NeedsRehash(%s) = %v, want %v", i, tt.hash[:10], got, tt.want)
+		}
+	}
+}
+
+// A usernameProvider checking real hashes and recording rehashed passwords.
+type rehashProvider struct {
+	usernameProvider
+	updated []string
+}
+
+func (p *rehashProvider) ValidateCredentials(ctx context.Context, user *User, credentials Credentials) (bool, error) {
+	return CheckPassword(credentials.Password, user.Password)
+}
+
+func (p *rehashProvider) UpdatePassword(ctx context.Context, user *User, hash string) error {
+	p.updated = append(p.updated, hash)
+	user.Password = hash
+	return nil
+}
+
+func TestLogin_RehashesWeakerHash(t *testing.T) {
+	a, mock, _ := newRememberAuth(t)
+	a.Hasher = testArgon2idHasher()
+
+	users := &rehashProvider{usernameProvider: usernameProvider{"ada": {ID: 7, Password: must((&BcryptHasher{Cost: bcrypt.MinCost}).Hash("secret"))}}}
+	a.Users = users
+
+	for i := 0; i < 2; i++ {
+		mock.ExpectExec("INSERT INTO remember_tokens").WillReturnResult(sqlmock.NewResult(1, 1))
+		inSession(a, nil, func(w http.ResponseWriter, r *http.Request) {
+			if ok, err := a.Login(w, r, "ada", "secret"); !ok || err != nil {
+				t.Fatalf("login %d: expected success, got %v %v", i+1, ok, err)
+			}
+		})
+	}
+
+	if len(users.updated) != 1 || !isArgon2id(users.updated[0]) {
+		t.Errorf("expected the bcrypt hash to be replaced with argon2id once, got %q", users.updated)
+	}
+}
+
+func must(hash string, err error) string {
+	if err != nil {
+		panic(err)
+	}
+	return hash
+}
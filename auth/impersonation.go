@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Session key holding the id of the user who started impersonating the user
+// in "userID".
+const impersonatorSessionKey = "impersonatorID"
+
+// The ability a user needs to impersonate another, checked with Can against
+// the target user.
+const impersonateAbility = "impersonate"
+
+// Log the current user in as another user, e.g. for support staff to see the
+// application as a customer does. The current user needs the "impersonate"
+// ability for the target, granted by a gate or a permission of that name.
+// Their id is kept in the session for StopImpersonating, and impersonating
+// again before stopping is refused. Both ends of an impersonation are written
+// to the log.
+func (a *Auth) Impersonate(r *http.Request, targetID int) error {
+	ctx := r.Context()
+	if a.Session.Exists(ctx, impersonatorSessionKey) {
+		return ImpersonationNestedError
+	}
+
+	userID := a.Session.GetInt(ctx, "userID")
+	if userID == 0 {
+		return ImpersonationForbiddenError
+	}
+	if userID == targetID {
+		return ImpersonateSelfError
+	}
+
+	users := a.users()
+	impersonator, err := users.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	target, err := users.FindByID(ctx, targetID)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return UserNotFoundError
+	}
+
+	if !a.UserCan(r, impersonator, impersonateAbility, target) {
+		return ImpersonationForbiddenError
+	}
+
+	if err := a.Session.RenewToken(ctx); err != nil {
+		return err
+	}
+	a.Session.Put(ctx, impersonatorSessionKey, userID)
+	a.Session.Put(ctx, "userID", targetID)
+
+	a.log().WithFields(logrus.Fields{"impersonator_id": userID, "user_id": targetID, "ip": clientIP(r)}).Info("auth: impersonation started")
+	return nil
+}
+
+// Log the user who started impersonating back in as themselves.
+func (a *Auth) StopImpersonating(r *http.Request) error {
+	ctx := r.Context()
+	impersonatorID := a.Session.GetInt(ctx, impersonatorSessionKey)
+	if impersonatorID == 0 {
+		return NotImpersonatingError
+	}
+	targetID := a.Session.GetInt(ctx, "userID")
+
+	if err := a.Session.RenewToken(ctx); err != nil {
+		return err
+	}
+	a.Session.Put(ctx, "userID", impersonatorID)
+	a.Session.Remove(ctx, impersonatorSessionKey)
+
+	a.log().WithFields(logrus.Fields{"impersonator_id": impersonatorID, "user_id": targetID, "ip": clientIP(r)}).Info("auth: impersonation stopped")
+	return nil
+}
+
+// Check if the current user is being impersonated.
+func (a *Auth) Impersonating(r *http.Request) bool {
+	return a.Session.Exists(r.Context(), impersonatorSessionKey)
+}
+
+// Get the id of the user impersonating the current user, or 0 if there is
+// none.
+func (a *Auth) Impersonator(r *http.Request) int {
+	return a.Session.GetInt(r.Context(), impersonatorSessionKey)
+}
+
+// ForbidImpersonation refuses requests through Forbidden while the current
+// user is being impersonated, e.g. on routes changing passwords, email
+// addresses or two-factor settings.
+func (a *Auth) ForbidImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.Impersonating(r) {
+			a.forbidden(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
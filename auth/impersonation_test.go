@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newImpersonationAuth(t *testing.T) (*Auth, *http.Cookie, func() string) {
+	a, _, logs := newRememberAuth(t)
+	a.Users = usernameProvider{"support": {ID: 1}, "ada": {ID: 7}, "grace": {ID: 8}}
+	a.Define("impersonate", func(r *http.Request, user *User, resource interface{}) bool {
+		return user.ID == 1
+	})
+
+	cookie := inSession(a, nil, func(w http.ResponseWriter, r *http.Request) {
+		a.Session.Put(r.Context(), "userID", 1)
+	})
+	return a, cookie, logs.String
+}
+
+func TestImpersonate(t *testing.T) {
+	a, cookie, logs := newImpersonationAuth(t)
+
+	cookie = inSession(a, cookie, func(w http.ResponseWriter, r *http.Request) {
+		if err := a.Impersonate(r, 7); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	inSession(a, cookie, func(w http.ResponseWriter, r *http.Request) {
+		if user := a.User(r); user == nil || user.ID != 7 || !a.Impersonating(r) || a.Impersonator(r) != 1 {
+			t.Errorf("expected support to act as user 7, got %v", user)
+		}
+		if err := a.Impersonate(r, 8); !errors.Is(err, ImpersonationNestedError) {
+			t.Errorf("expected nested impersonation to be refused, got %v", err)
+		}
+	})
+
+	cookie = inSession(a, cookie, func(w http.ResponseWriter, r *http.Request) {
+		if err := a.StopImpersonating(r); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	inSession(a, cookie, func(w http.ResponseWriter, r *http.Request) {
+		if a.Session.GetInt(r.Context(), "userID") != 1 || a.Impersonating(r) {
+			t.Error("expected support to be logged in as themselves again")
+		}
+		if err := a.StopImpersonating(r); !errors.Is(err, NotImpersonatingError) {
+			t.Errorf("expected NotImpersonatingError, got %v", err)
+		}
+	})
+
+	if !strings.Contains(logs(), "auth: impersonation started") || !strings.Contains(logs(), "auth: impersonation stopped") ||
+		!strings.Contains(logs(), "impersonator_id=1") {
+		t.Errorf("expected both ends of the impersonation to be logged, got %s", logs())
+	}
+}
+
+func TestImpersonate_Refused(t *testing.T) {
+	a, cookie, _ := newImpersonationAuth(t)
+
+	tests := []struct {
+		userID, targetID int
+		want             error
+	}{
+		{0, 7, ImpersonationForbiddenError},
+		{1, 1, ImpersonateSelfError},
+		{1, 9, UserNotFoundError},
+		{7, 8, ImpersonationForbiddenError},
+	}
+
+	for _, tt := range tests {
+		inSession(a, cookie, func(w http.ResponseWriter, r *http.Request) {
+			a.Session.Put(r.Context(), "userID", tt.userID)
+			if err := a.Impersonate(r, tt.targetID); !errors.Is(err, tt.want) {
+				t.Errorf("user %d impersonating %d: expected %v, got %v", tt.userID, tt.targetID, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestForbidImpersonation(t *testing.T) {
+	a, cookie, _ := newImpersonationAuth(t)
+
+	h := a.Session.LoadAndSave(a.ForbidImpersonation(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	serve := func() int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/profile-password", nil)
+		r.AddCookie(cookie)
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := serve(); code != http.StatusOK {
+		t.Errorf("expected support to pass, got %d", code)
+	}
+
+	cookie = inSession(a, cookie, func(w http.ResponseWriter, r *http.Request) {
+		if err := a.Impersonate(r, 7); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if code := serve(); code != http.StatusForbidden {
+		t.Errorf("expected the route to be refused while impersonating, got %d", code)
+	}
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cidekar/adele-framework/database"
+	"github.com/cidekar/adele-framework/internal/bearer"
+	"github.com/sirupsen/logrus"
+)
+
+// Session key holding the selector of the remember token issued to the session.
+const rememberSessionKey = "remember_token"
+
+// How long a remember token keeps a user logged in without being used.
+const rememberDuration = 365 * 24 * time.Hour
+
+// How long after a rotation the previous validator is still expected from
+// requests the browser sent before it received the new cookie. Such a request
+// is treated as a guest instead of as a stolen token.
+const rememberRotationGrace = 10 * time.Second
+
+// RememberMiddleware logs a guest back in from the remember-me cookie set by
+// Login. The cookie holds a selector, naming the token, and a validator, of
+// which only the hash is stored. The validator is replaced every time the
+// cookie is used, so a validator presented again after its rotation means the
+// cookie was copied: every remember token of the user is revoked and both the
+// thief and the user have to log in again.
+func (a *Auth) RememberMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.remembers() || a.Session.Exists(r.Context(), "userID") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cookie, err := r.Cookie(a.rememberCookieName())
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		err = a.restoreRemembered(w, r, cookie.Value)
+		switch {
+		case err == nil, errors.Is(err, rememberTokenRotatedError):
+		case errors.Is(err, RememberTokenInvalidError), errors.Is(err, RememberTokenReusedError):
+			a.forgetRememberCookie(w)
+		default:
+			a.logError("auth: restore remember token:", err)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Revoke every remember token of a user, logging them out of each browser that
+// was kept logged in, e.g. after a password reset or by an administrator.
+func (a *Auth) RevokeRememberTokens(ctx context.Context, userID int) error {
+	if !a.remembers() {
+		return nil
+	}
+
+	_, err := a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), fmt.Sprintf("DELETE FROM %s WHERE user_id = ?", a.rememberTable())), userID)
+	return err
+}
+
+// Issue a remember token for a user who just logged in and set its cookie.
+func (a *Auth) remember(w http.ResponseWriter, r *http.Request, userID int) error {
+	if !a.remembers() {
+		return nil
+	}
+
+	selector, err := randomToken(12)
+	if err != nil {
+		return RememberTokenHashError
+	}
+	validator, err := randomToken(32)
+	if err != nil {
+		return RememberTokenHashError
+	}
+
+	now := time.Now().UTC()
+	_, err = a.DB.Pool.ExecContext(r.Context(), database.Rebind(a.dialect(), fmt.Sprintf("INSERT INTO %s (user_id, selector, remember_token, created_at, updated_at) VALUES (?, ?, ?, ?, ?)", a.rememberTable())),
+		userID, selector, bearer.Hash(validator), now, now)
+	if err != nil {
+		return RememberTokenStoreError
+	}
+
+	a.setRememberCookie(w, selector+":"+validator)
+	a.Session.Put(r.Context(), rememberSessionKey, selector)
+	return nil
+}
+
+// Log the user of a remember-me cookie in and rotate its validator.
+func (a *Auth) restoreRemembered(w http.ResponseWriter, r *http.Request, value string) error {
+	selector, validator, ok := strings.Cut(value, ":")
+	if !ok || selector == "" || validator == "" {
+		return RememberTokenInvalidError
+	}
+
+	var (
+		id        int64
+		userID    int
+		hash      string
+		updatedAt time.Time
+	)
+	err := a.DB.Pool.QueryRowContext(r.Context(), database.Rebind(a.dialect(), fmt.Sprintf("SELECT id, user_id, remember_token, updated_at FROM %s WHERE selector = ?", a.rememberTable())), selector).
+		Scan(&id, &userID, &hash, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RememberTokenInvalidError
+	}
+	if err != nil {
+		return err
+	}
+
+	if time.Since(updatedAt) > rememberDuration {
+		if _, err := a.DB.Pool.ExecContext(r.Context(), database.Rebind(a.dialect(), fmt.Sprintf("DELETE FROM %s WHERE id = ?", a.rememberTable())), id); err != nil {
+			return err
+		}
+		return RememberTokenInvalidError
+	}
+
+	if subtle.ConstantTimeCompare([]byte(bearer.Hash(validator)), []byte(hash)) != 1 {
+		if time.Since(updatedAt) < rememberRotationGrace {
+			return rememberTokenRotatedError
+		}
+
+		a.log().WithFields(logrus.Fields{"user_id": userID, "ip": r.RemoteAddr}).Warn("auth: remember token reused")
+		if err := a.RevokeRememberTokens(r.Context(), userID); err != nil {
+			return err
+		}
+		return RememberTokenReusedError
+	}
+
+	next, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+
+	// The old validator is part of the match, so of two requests racing with the
+	// same cookie only one rotates it.
+	res, err := a.DB.Pool.ExecContext(r.Context(), database.Rebind(a.dialect(), fmt.Sprintf("UPDATE %s SET remember_token = ?, updated_at = ? WHERE id = ? AND remember_token = ?", a.rememberTable())),
+		bearer.Hash(next), time.Now().UTC(), id, hash)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return rememberTokenRotatedError
+	}
+
+	if err := a.Session.RenewToken(r.Context()); err != nil {
+		return err
+	}
+	a.Session.Put(r.Context(), "userID", userID)
+	a.Session.Put(r.Context(), rememberSessionKey, selector)
+
+	// A remember token is issued once every factor was checked, and enabling
+	// two-factor authentication revokes the tokens issued before.
+	if a.TwoFactor {
+		enabled, err := a.TwoFactorEnabled(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+		if enabled {
+			a.Session.Put(r.Context(), twoFactorVerifiedKey, true)
+		}
+	}
+
+	a.setRememberCookie(w, selector+":"+next)
+	return nil
+}
+
+// Delete the remember token of the current session.
+func (a *Auth) forgetRemembered(r *http.Request) error {
+	selector := a.Session.GetString(r.Context(), rememberSessionKey)
+	if selector == "" || !a.remembers() {
+		return nil
+	}
+
+	_, err := a.DB.Pool.ExecContext(r.Context(), database.Rebind(a.dialect(), fmt.Sprintf("DELETE FROM %s WHERE selector = ?", a.rememberTable())), selector)
+	return err
+}
+
+// Revoke the remember tokens a user was issued in other sessions.
+func (a *Auth) forgetOtherRemembered(r *http.Request, userID int) error {
+	if !a.remembers() {
+		return nil
+	}
+
+	_, err := a.DB.Pool.ExecContext(r.Context(), database.Rebind(a.dialect(), fmt.Sprintf("DELETE FROM %s WHERE user_id = ? AND selector <> ?", a.rememberTable())),
+		userID, a.Session.GetString(r.Context(), rememberSessionKey))
+	return err
+}
+
+// Report whether remember tokens can be stored, which needs the database.
+func (a *Auth) remembers() bool {
+	return a.DB != nil && a.DB.Pool != nil
+}
+
+func (a *Auth) setRememberCookie(w http.ResponseWriter, value string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     a.rememberCookieName(),
+		Value:    value,
+		Path:     "/",
+		Expires:  time.Now().Add(rememberDuration),
+		MaxAge:   int(rememberDuration / time.Second),
+		HttpOnly: true,
+		Domain:   a.Session.Cookie.Domain,
+		Secure:   a.Session.Cookie.Secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func (a *Auth) forgetRememberCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     a.rememberCookieName(),
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Now().Add(-100 * time.Hour),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Domain:   a.Session.Cookie.Domain,
+		Secure:   a.Session.Cookie.Secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// The remember-me cookie is named after the application, e.g. myapp_remember,
+// falling back to APP_NAME from the environment.
+func (a *Auth) rememberCookieName() string {
+	appName := a.AppName
+	if appName == "" {
+		appName = os.Getenv("APP_NAME")
+	}
+	if appName == "" {
+		appName = "adele"
+	}
+	return fmt.Sprintf("%s_remember", appName)
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alexedwards/scs/v2"
+	"github.com/cidekar/adele-framework/internal/bearer"
+	"github.com/sirupsen/logrus"
+)
+
+// A sqlmock argument recording the value it was matched against.
+type captureArg struct {
+	value string
+}
+
+func (c *captureArg) Match(v driver.Value) bool {
+	c.value, _ = v.(string)
+	return true
+}
+
+func newRememberAuth(t *testing.T) (*Auth, sqlmock.Sqlmock, *bytes.Buffer) {
+	a, mock := newTokenAuth(t, "postgres")
+	a.AppName = "myapp"
+	a.Session = scs.New()
+
+	var logs bytes.Buffer
+	a.Log = logrus.New()
+	a.Log.SetOutput(&logs)
+	return a, mock, &logs
+}
+
+// Serve a request carrying a remember-me cookie through RememberMiddleware and
+// return the response with the user id the handler saw.
+func serveRemembered(a *Auth, value string) (*httptest.ResponseRecorder, int) {
+	var userID int
+	h := a.Session.LoadAndSave(a.RememberMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID = a.Session.GetInt(r.Context(), "userID")
+	})))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	r.AddCookie(&http.Cookie{Name: "myapp_remember", Value: value})
+	h.ServeHTTP(w, r)
+	return w, userID
+}
+
+func rememberCookie(w *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "myapp_remember" {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestCompleteLogin_StoresValidatorHash(t *testing.T) {
+	a, mock, _ := newRememberAuth(t)
+
+	selector, hash := &captureArg{}, &captureArg{}
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO remember_tokens (user_id, selector, remember_token, created_at, updated_at) VALUES ($1, $2, $3, $4, $5)")).
+		WithArgs(7, selector, hash, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	w := httptest.NewRecorder()
+	a.Session.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, err := a.completeLogin(w, r, 7); !ok || err != nil {
+			t.Fatalf("expected the login to complete, got %v", err)
+		}
+	})).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+
+	cookie := rememberCookie(w)
+	if cookie == nil {
+		t.Fatal("expected a myapp_remember cookie")
+	}
+	sel, validator, _ := strings.Cut(cookie.Value, ":")
+	if sel != selector.value || bearer.Hash(validator) != hash.value || validator == hash.value {
+		t.Errorf("expected the cookie to carry the selector and a validator whose hash is stored, got %q", cookie.Value)
+	}
+}
+
+func TestRememberMiddleware_RotatesValidator(t *testing.T) {
+	a, mock, _ := newRememberAuth(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, user_id, remember_token, updated_at FROM remember_tokens WHERE selector = $1")).WithArgs("sel").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "remember_token", "updated_at"}).AddRow(3, 7, bearer.Hash("old"), time.Now().Add(-time.Hour)))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE remember_tokens SET remember_token = $1, updated_at = $2 WHERE id = $3 AND remember_token = $4")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 3, bearer.Hash("old")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	w, userID := serveRemembered(a, "sel:old")
+	if userID != 7 {
+		t.Errorf("expected user 7 to be logged in, got %d", userID)
+	}
+
+	cookie := rememberCookie(w)
+	if cookie == nil || !strings.HasPrefix(cookie.Value, "sel:") || cookie.Value == "sel:old" {
+		t.Errorf("expected a rotated validator for the same selector, got %v", cookie)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRememberMiddleware_ReusedValidatorRevokesAll(t *testing.T) {
+	a, mock, logs := newRememberAuth(t)
+
+	mock.ExpectQuery("SELECT id, user_id, remember_token, updated_at FROM remember_tokens").WithArgs("sel").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "remember_token", "updated_at"}).AddRow(3, 7, bearer.Hash("new"), time.Now().Add(-time.Hour)))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM remember_tokens WHERE user_id = $1")).WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	w, userID := serveRemembered(a, "sel:old")
+	if userID != 0 {
+		t.Error("expected a reused validator to be refused")
+	}
+	if cookie := rememberCookie(w); cookie == nil || cookie.MaxAge >= 0 {
+		t.Errorf("expected the cookie to be deleted, got %v", cookie)
+	}
+	if !strings.Contains(logs.String(), "auth: remember token reused") {
+		t.Errorf("expected the reuse to be logged, got %s", logs)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRememberMiddleware_ConcurrentRotation(t *testing.T) {
+	a, mock, _ := newRememberAuth(t)
+
+	// Another request rotated the validator a moment ago.
+	mock.ExpectQuery("SELECT id, user_id, remember_token, updated_at FROM remember_tokens").WithArgs("sel").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "remember_token", "updated_at"}).AddRow(3, 7, bearer.Hash("new"), time.Now()))
+
+	w, userID := serveRemembered(a, "sel:old")
+	if userID != 0 {
+		t.Error("expected the request to be served as a guest")
+	}
+	if cookie := rememberCookie(w); cookie != nil {
+		t.Errorf("expected the rotated cookie to be left alone, got %v", cookie)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRememberMiddleware_UnknownToken(t *testing.T) {
+	a, mock, _ := newRememberAuth(t)
+
+	mock.ExpectQuery("SELECT id, user_id, remember_token, updated_at FROM remember_tokens").WithArgs("sel").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "remember_token", "updated_at"}))
+
+	for _, value := range []string{"sel:old", "7|legacy-token"} {
+		w, userID := serveRemembered(a, value)
+		if cookie := rememberCookie(w); userID != 0 || cookie == nil || cookie.MaxAge >= 0 {
+			t.Errorf("%s: expected the cookie to be deleted, got %v", value, cookie)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cidekar/adele-framework/database"
+)
+
+// Create a role. Permissions are granted to it with GrantPermission.
+func (a *Auth) CreateRole(ctx context.Context, name string) (*Role, error) {
+	role := &Role{Name: name, CreatedAt: time.Now().UTC()}
+
+	id, err := a.insert(ctx, "INSERT INTO roles (name, created_at) VALUES (?, ?)", role.Name, role.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	role.ID = id
+
+	return role, nil
+}
+
+// Delete a role, taking it away from every user it was assigned to.
+func (a *Auth) DeleteRole(ctx context.Context, name string) error {
+	res, err := a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), "DELETE FROM roles WHERE name = ?"), name)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return RoleNotFoundError
+	}
+
+	return nil
+}
+
+// Grant a permission, such as "posts:write", to a role. The permission is
+// created when it does not exist yet, and granting it twice is not an error.
+func (a *Auth) GrantPermission(ctx context.Context, role, permission string) error {
+	roleID, err := a.roleID(ctx, role)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), a.insertIgnore("INSERT INTO permissions (name, created_at) VALUES (?, ?)")), permission, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	_, err = a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), a.insertIgnore("INSERT INTO role_permissions (role_id, permission_id) SELECT ?, id FROM permissions WHERE name = ?")), roleID, permission)
+	return err
+}
+
+// Take a permission away from a role.
+func (a *Auth) RevokePermission(ctx context.Context, role, permission string) error {
+	roleID, err := a.roleID(ctx, role)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), "DELETE FROM role_permissions WHERE role_id = ? AND permission_id IN (SELECT id FROM permissions WHERE name = ?)"), roleID, permission)
+	return err
+}
+
+// Assign a role to a user. Assigning a role the user already has is not an
+// error.
+func (a *Auth) AssignRole(ctx context.Context, userID int, role string) error {
+	roleID, err := a.roleID(ctx, role)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), a.insertIgnore("INSERT INTO user_roles (user_id, role_id) VALUES (?, ?)")), userID, roleID)
+	return err
+}
+
+// Take a role away from a user.
+func (a *Auth) RemoveRole(ctx context.Context, userID int, role string) error {
+	roleID, err := a.roleID(ctx, role)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), "DELETE FROM user_roles WHERE user_id = ? AND role_id = ?"), userID, roleID)
+	return err
+}
+
+// Get the roles assigned to a user, ordered by name.
+func (a *Auth) Roles(ctx context.Context, userID int) ([]Role, error) {
+	rows, err := a.DB.Pool.QueryContext(ctx, database.Rebind(a.dialect(), `SELECT r.id, r.name, r.created_at FROM roles r
+		JOIN user_roles ur ON ur.role_id = r.id WHERE ur.user_id = ? ORDER BY r.name`), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []Role
+	for rows.Next() {
+		var role Role
+		if err := rows.Scan(&role.ID, &role.Name, &role.CreatedAt); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// Check if the current user has been assigned a role.
+func (a *Auth) HasRole(r *http.Request, role string) bool {
+	user := a.User(r)
+	if user == nil {
+		return false
+	}
+
+	roles, err := a.Roles(r.Context(), user.ID)
+	if err != nil {
+		a.logError("auth: load roles:", err)
+		return false
+	}
+
+	for _, have := range roles {
+		if have.Name == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get the permissions a user is granted through their roles, ordered by name.
+func (a *Auth) RolePermissions(ctx context.Context, userID int) ([]string, error) {
+	rows, err := a.DB.Pool.QueryContext(ctx, database.Rebind(a.dialect(), `SELECT DISTINCT p.name FROM permissions p
+		JOIN role_permissions rp ON rp.permission_id = p.id
+		JOIN user_roles ur ON ur.role_id = rp.role_id
+		WHERE ur.user_id = ? ORDER BY p.name`), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, name)
+	}
+
+	return permissions, rows.Err()
+}
+
+// DatabasePermissions is a PermissionResolver granting a user the permissions
+// of their roles. Applications with a database use it as the default resolver.
+func (a *Auth) DatabasePermissions(r *http.Request, user *User) ([]string, error) {
+	return a.RolePermissions(r.Context(), user.ID)
+}
+
+func (a *Auth) roleID(ctx context.Context, name string) (int64, error) {
+	var id int64
+	err := a.DB.Pool.QueryRowContext(ctx, database.Rebind(a.dialect(), "SELECT id FROM roles WHERE name = ?"), name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, RoleNotFoundError
+	}
+
+	return id, err
+}
+
+// Turn an INSERT into one that skips rows violating a unique key.
+func (a *Auth) insertIgnore(query string) string {
+	if a.dialect() == "mysql" {
+		return strings.Replace(query, "INSERT INTO", "INSERT IGNORE INTO", 1)
+	}
+
+	return query + " ON CONFLICT DO NOTHING"
+}
+
+func (a *Auth) logError(v ...interface{}) {
+	if a.ErrorLog != nil {
+		a.ErrorLog.Println(v...)
+	}
+}
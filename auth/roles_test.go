@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGrantPermission_CreatesPermission(t *testing.T) {
+	tests := map[string][]string{
+		"postgres": {
+			"INSERT INTO permissions (name, created_at) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			"INSERT INTO role_permissions (role_id, permission_id) SELECT $1, id FROM permissions WHERE name = $2 ON CONFLICT DO NOTHING",
+		},
+		"mysql": {
+			"INSERT IGNORE INTO permissions (name, created_at) VALUES (?, ?)",
+			"INSERT IGNORE INTO role_permissions (role_id, permission_id) SELECT ?, id FROM permissions WHERE name = ?",
+		},
+	}
+
+	for dialect, queries := range tests {
+		a, mock := newTokenAuth(t, dialect)
+
+		mock.ExpectQuery("SELECT id FROM roles").WithArgs("editor").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectExec(regexp.QuoteMeta(queries[0])).WithArgs("posts:write", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(queries[1])).WithArgs(3, "posts:write").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		if err := a.GrantPermission(context.Background(), "editor", "posts:write"); err != nil {
+			t.Fatalf("%s: %v", dialect, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("%s: %v", dialect, err)
+		}
+	}
+}
+
+func TestAssignRole_UnknownRole(t *testing.T) {
+	a, mock := newTokenAuth(t, "postgres")
+
+	mock.ExpectQuery("SELECT id FROM roles").WithArgs("ghost").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if err := a.AssignRole(context.Background(), 7, "ghost"); !errors.Is(err, RoleNotFoundError) {
+		t.Errorf("expected RoleNotFoundError, got %v", err)
+	}
+}
+
+func TestCreateAndDeleteRole(t *testing.T) {
+	a, mock := newTokenAuth(t, "mysql")
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO roles (name, created_at) VALUES (?, ?)")).
+		WithArgs("editor", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM roles WHERE name = ?")).
+		WithArgs("editor").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM roles WHERE name = ?")).
+		WithArgs("editor").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	role, err := a.CreateRole(context.Background(), "editor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role.ID != 5 || role.Name != "editor" {
+		t.Errorf("unexpected role %+v", role)
+	}
+
+	if err := a.DeleteRole(context.Background(), "editor"); err != nil {
+		t.Error(err)
+	}
+	if err := a.DeleteRole(context.Background(), "editor"); !errors.Is(err, RoleNotFoundError) {
+		t.Errorf("expected RoleNotFoundError, got %v", err)
+	}
+}
+
+func TestDatabasePermissions(t *testing.T) {
+	a, mock := newTokenAuth(t, "postgres")
+
+	mock.ExpectQuery("SELECT DISTINCT p.name FROM permissions p").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("posts:delete").AddRow("posts:write"))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	permissions, err := a.DatabasePermissions(r, &User{ID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(permissions, []string{"posts:delete", "posts:write"}) {
+		t.Errorf("unexpected permissions %v", permissions)
+	}
+
+	a.Permissions = a.DatabasePermissions
+	mock.ExpectQuery("SELECT DISTINCT p.name FROM permissions p").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("posts:write"))
+	if !a.UserCan(r, &User{ID: 7}, "posts:write", nil) {
+		t.Error("expected the role permission to allow the ability")
+	}
+}
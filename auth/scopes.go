@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/cidekar/adele-framework/internal/bearer"
+	"github.com/cidekar/adele-framework/mux"
+)
+
+// ScopeMiddleware authorizes requests from logged in users against the scopes
+// annotated on routes, e.g. m.Post("/posts[scopes:posts:write]", handler). The
+// request is matched to the route chi serves it with, whichever router m the
+// middleware is used on, and is refused through Forbidden unless the
+// permissions the Permissions resolver grants the current user cover every
+// scope of the route. Routes without scopes are not checked, while a route the
+// mux tree has no record of is refused.
+func (a *Auth) ScopeMiddleware(m *mux.Mux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, err := m.RouteScopes(r)
+			if err != nil {
+				a.logError("auth: resolve route scopes:", err)
+				a.forbidden(w, r)
+				return
+			}
+
+			scopes := route.Scope
+			if len(scopes) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !a.Check(r) {
+				a.forbidden(w, r)
+				return
+			}
+
+			permissions, err := a.UserPermissions(r)
+			if err != nil {
+				a.logError("auth: resolve permissions:", err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+
+			if !bearer.Grants(permissions, scopes) {
+				a.forbidden(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Get the permissions of the current user from the Permissions resolver. A
+// guest, or an application without a resolver, has none.
+func (a *Auth) UserPermissions(r *http.Request) ([]string, error) {
+	if a.Permissions == nil {
+		return nil, nil
+	}
+
+	user := a.User(r)
+	if user == nil {
+		return nil, nil
+	}
+
+	return a.Permissions(r, user)
+}
+
+func (a *Auth) forbidden(w http.ResponseWriter, r *http.Request) {
+	if a.Forbidden != nil {
+		a.Forbidden(w, r)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+}
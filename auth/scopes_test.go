@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/cidekar/adele-framework/mux"
+)
+
+func TestScopeMiddleware_Guests(t *testing.T) {
+	forbidden := 0
+	a := &Auth{
+		Session: scs.New(),
+		Forbidden: func(w http.ResponseWriter, r *http.Request) {
+			forbidden++
+			w.WriteHeader(http.StatusForbidden)
+		},
+	}
+
+	m := mux.NewRouter()
+	m.Use(a.Session.LoadAndSave, a.ScopeMiddleware(m))
+	m.Get("/scope-posts/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	m.Post("/scope-posts/{id}[scopes:posts:write]", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected a guest to be refused")
+	})
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/scope-posts/4", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected a route without scopes to pass, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/scope-posts/4", nil))
+	if w.Code != http.StatusForbidden || forbidden != 1 {
+		t.Errorf("expected the Forbidden handler to refuse a guest, got %d", w.Code)
+	}
+}
+
+func TestScopeMiddleware_NestedRouters(t *testing.T) {
+	a := &Auth{
+		Session: scs.New(),
+		Users:   usernameProvider{"ada": {ID: 7}},
+		Permissions: func(r *http.Request, user *User) ([]string, error) {
+			return []string{"posts:read"}, nil
+		},
+	}
+	h := func(w http.ResponseWriter, r *http.Request) {}
+
+	root := mux.NewRouter()
+	root.Use(a.Session.LoadAndSave, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			a.Session.Put(r.Context(), "userID", 7)
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	// The middleware on a subrouter, whose routes and nested subrouter are
+	// added both before and after they are mounted.
+	api := mux.NewRouter()
+	api.Use(a.ScopeMiddleware(api))
+	api.Get("/posts[scopes:posts:read]", h)
+	root.Mount("/scope-api", api)
+	api.Post("/posts[scopes:posts:write]", h)
+
+	admin := mux.NewRouter()
+	api.Mount("/admin", admin)
+	admin.Get("/posts", h)
+	admin.Delete("/posts/{id}[scope:posts:delete]", h)
+	admin.Mux.Put("/posts/{id}", h)
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/scope-api/posts", http.StatusOK},
+		{http.MethodPost, "/scope-api/posts", http.StatusForbidden},
+		{http.MethodGet, "/scope-api/admin/posts", http.StatusOK},
+		{http.MethodDelete, "/scope-api/admin/posts/4", http.StatusForbidden},
+		{http.MethodPut, "/scope-api/admin/posts/4", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		root.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.want, w.Code)
+		}
+	}
+}
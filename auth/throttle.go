@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+	"github.com/sirupsen/logrus"
+)
+
+// Create a login throttle with the default limits: 5 failures per email and
+// 20 per IP within 15 minutes, then a 1 minute lockout growing to 1 hour.
+func NewLoginThrottle(c cache.Cache) *LoginThrottle {
+	return &LoginThrottle{
+		Cache:         c,
+		MaxAttempts:   5,
+		MaxIPAttempts: 20,
+		Decay:         15 * time.Minute,
+		Lockout:       time.Minute,
+		MaxLockout:    time.Hour,
+	}
+}
+
+// A throttled key with the failures that lock it out. The failures are counted
+// under name and the lockout is stored under name + ":lock".
+type throttleKey struct {
+	name  string
+	kind  string
+	value string
+	max   int
+}
+
+// Unlock an email address locked out after failed logins.
+func (a *Auth) UnlockLogin(email string) error {
+	if a.Throttle == nil {
+		return nil
+	}
+
+	key := a.Throttle.emailKey(email)
+	for _, name := range []string{key.name, key.lockName()} {
+		if err := a.Throttle.Cache.Forget(name); err != nil {
+			return err
+		}
+	}
+
+	a.log().WithFields(logrus.Fields{"email": key.value}).Info("auth: login unlocked")
+	return nil
+}
+
+// Refuse a login while its email address or client IP is locked out.
+func (a *Auth) checkLoginThrottle(r *http.Request, email string) error {
+	if a.Throttle == nil {
+		return nil
+	}
+
+	var retryAfter time.Duration
+	for _, key := range a.Throttle.keys(r, email) {
+		if wait := time.Until(a.lockedUntil(key)); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return &LoginThrottledError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// Count a failed login, locking out its email address or client IP once their
+// limit is reached.
+func (a *Auth) loginFailed(r *http.Request, email string) {
+	if a.Throttle == nil {
+		return
+	}
+
+	t := a.Throttle
+	for _, key := range t.keys(r, email) {
+		failures, err := t.countFailure(key.name)
+		if err != nil {
+			a.logError("auth: store failed login:", err)
+			continue
+		}
+		if failures < key.max {
+			continue
+		}
+
+		lockout := t.Lockout << uint(failures-key.max)
+		if lockout > t.MaxLockout || lockout <= 0 {
+			lockout = t.MaxLockout
+		}
+		lockedUntil := time.Now().Add(lockout)
+
+		a.log().WithFields(logrus.Fields{
+			key.kind:   key.value,
+			"failures": failures,
+			"until":    lockedUntil.UTC().Format(time.RFC3339),
+		}).Warn("auth: login locked")
+
+		value := map[string]interface{}{"locked_until": lockedUntil.Unix()}
+		if err := t.Cache.Set(key.lockName(), value, seconds(lockout)); err != nil {
+			a.logError("auth: store login lockout:", err)
+		}
+	}
+}
+
+// Forget the failed logins of an email address once its user logged in. The
+// failures of the IP are kept, so one valid account cannot be used to reset
+// the counter of an IP guessing others.
+func (a *Auth) loginSucceeded(r *http.Request, email string) {
+	if a.Throttle == nil {
+		return
+	}
+
+	key := a.Throttle.emailKey(email)
+	locked := !a.lockedUntil(key).IsZero()
+
+	for _, name := range []string{key.name, key.lockName()} {
+		if err := a.Throttle.Cache.Forget(name); err != nil {
+			a.logError("auth: clear failed logins:", err)
+			return
+		}
+	}
+	if locked {
+		a.log().WithFields(logrus.Fields{"email": key.value}).Info("auth: login unlocked")
+	}
+}
+
+// Count one more failure under name and return the failures counted within
+// Decay of each other. A cache.Counter counts atomically, so concurrent
+// failures are all counted; other caches are read and written back, and
+// failures counted at the same time may be lost.
+func (t *LoginThrottle) countFailure(name string) (int, error) {
+	if counter, ok := t.Cache.(cache.Counter); ok {
+		return counter.Increment(name, seconds(t.Decay))
+	}
+
+	failures := 0
+	if ok, err := t.Cache.Has(name); err != nil {
+		return 0, err
+	} else if ok {
+		value, err := t.Cache.Get(name)
+		if err != nil {
+			return 0, err
+		}
+		// Cached values are stored as JSON, so the number comes back as float64.
+		f, _ := value.(float64)
+		failures = int(f)
+	}
+
+	failures++
+	return failures, t.Cache.Set(name, failures, seconds(t.Decay))
+}
+
+// Load when the lockout of a key ends, or the zero time when it is not locked
+// out. A cache error is logged and counts as no lockout, so an unavailable
+// cache does not lock everyone out.
+func (a *Auth) lockedUntil(key throttleKey) time.Time {
+	c := a.Throttle.Cache
+	if ok, err := c.Has(key.lockName()); err != nil || !ok {
+		if err != nil {
+			a.logError("auth: load login lockout:", err)
+		}
+		return time.Time{}
+	}
+
+	value, err := c.Get(key.lockName())
+	if err != nil {
+		a.logError("auth: load login lockout:", err)
+		return time.Time{}
+	}
+
+	// Cached values are stored as JSON, so the numbers come back as float64.
+	m, _ := value.(map[string]interface{})
+	lockedUntil, _ := m["locked_until"].(float64)
+	if lockedUntil <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(lockedUntil), 0)
+}
+
+func (a *Auth) log() *logrus.Logger {
+	if a.Log == nil {
+		return logrus.StandardLogger()
+	}
+	return a.Log
+}
+
+// The keys a login is throttled by: its email address and its client IP. A
+// limit of zero turns its key off.
+func (t *LoginThrottle) keys(r *http.Request, email string) []throttleKey {
+	ip := clientIP(r)
+
+	var keys []throttleKey
+	if t.MaxAttempts > 0 {
+		keys = append(keys, t.emailKey(email))
+	}
+	if t.MaxIPAttempts > 0 {
+		keys = append(keys, throttleKey{name: "auth:throttle:ip:" + ip, kind: "ip", value: ip, max: t.MaxIPAttempts})
+	}
+	return keys
+}
+
+// The IP address of the client, without the port.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+func (k throttleKey) lockName() string {
+	return k.name + ":lock"
+}
+
+// A duration in whole seconds, at least one, for a cache expiry.
+func seconds(d time.Duration) int {
+	if d < time.Second {
+		return 1
+	}
+	return int(d / time.Second)
+}
+
+func (t *LoginThrottle) emailKey(email string) throttleKey {
+	email = strings.ToLower(strings.TrimSpace(email))
+	sum := sha256.Sum256([]byte(email))
+
+	return throttleKey{name: "auth:throttle:email:" + hex.EncodeToString(sum[:]), kind: "email", value: email, max: t.MaxAttempts}
+}
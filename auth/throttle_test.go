@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+	"github.com/sirupsen/logrus"
+)
+
+// An in-memory cache.Counter that stores values as JSON like the real drivers.
+type memoryCache struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{values: map[string][]byte{}}
+}
+
+func (m *memoryCache) Has(key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.values[key]
+	return ok, nil
+}
+
+func (m *memoryCache) Get(key string) (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, err := cache.Decode(m.values[key])
+	if err != nil {
+		return nil, err
+	}
+	return entry[key], nil
+}
+
+func (m *memoryCache) Set(key string, value interface{}, expires ...int) error {
+	encoded, err := cache.Encode(cache.Entry{key: value})
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = encoded
+	return err
+}
+
+func (m *memoryCache) Increment(key string, expires int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, _ := strconv.Atoi(string(m.values[key]))
+	n++
+	m.values[key] = []byte(strconv.Itoa(n))
+	return n, nil
+}
+
+func (m *memoryCache) Forget(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+func (m *memoryCache) EmptyByMatch(string) error { return nil }
+
+func (m *memoryCache) Empty() error { return nil }
+
+func newThrottledAuth() (*Auth, *memoryCache, *bytes.Buffer) {
+	c := newMemoryCache()
+	var logs bytes.Buffer
+	log := logrus.New()
+	log.SetOutput(&logs)
+
+	a := &Auth{Throttle: NewLoginThrottle(c), Log: log}
+	a.Throttle.MaxAttempts = 3
+	return a, c, &logs
+}
+
+func loginRequest(ip string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/login", nil)
+	r.RemoteAddr = ip + ":51234"
+	return r
+}
+
+func TestLoginThrottle_LocksEmail(t *testing.T) {
+	a, c, logs := newThrottledAuth()
+	r := loginRequest("10.0.0.1")
+
+	for i := 0; i < 2; i++ {
+		a.loginFailed(r, "Me@Example.com")
+		if err := a.checkLoginThrottle(r, "me@example.com"); err != nil {
+			t.Fatalf("expected no lockout after %d failures, got %v", i+1, err)
+		}
+	}
+
+	a.loginFailed(r, "me@example.com")
+	var throttled *LoginThrottledError
+	if err := a.checkLoginThrottle(r, " ME@example.com"); !errors.As(err, &throttled) {
+		t.Fatalf("expected a lockout, got %v", err)
+	}
+	if throttled.RetryAfter <= 50*time.Second || throttled.RetryAfter > time.Minute {
+		t.Errorf("expected a one minute lockout, got %s", throttled.RetryAfter)
+	}
+	if !strings.Contains(logs.String(), "auth: login locked") || !strings.Contains(logs.String(), "me@example.com") {
+		t.Errorf("expected the lockout to be logged, got %s", logs)
+	}
+
+	// Login returns the lockout before looking the user up.
+	if _, err := a.Login(httptest.NewRecorder(), r, "me@example.com", "secret"); !errors.As(err, &throttled) {
+		t.Errorf("expected Login to be refused, got %v", err)
+	}
+
+	// Another address from a different IP is not affected.
+	if err := a.checkLoginThrottle(loginRequest("10.0.0.2"), "you@example.com"); err != nil {
+		t.Errorf("expected another email to pass, got %v", err)
+	}
+
+	if err := a.UnlockLogin("me@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.checkLoginThrottle(r, "me@example.com"); err != nil {
+		t.Errorf("expected the unlocked email to pass, got %v", err)
+	}
+	if len(c.values) != 1 {
+		t.Errorf("expected only the IP failures to remain, got %d keys", len(c.values))
+	}
+}
+
+func TestLoginThrottle_ProgressiveLockout(t *testing.T) {
+	a, _, _ := newThrottledAuth()
+	r := loginRequest("10.0.0.1")
+	key := a.Throttle.emailKey("me@example.com")
+
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute}
+	for i := 0; i < 2; i++ {
+		a.loginFailed(r, "me@example.com")
+	}
+	for _, lockout := range want {
+		a.loginFailed(r, "me@example.com")
+
+		if got := time.Until(a.lockedUntil(key)); got <= lockout-2*time.Second || got > lockout {
+			t.Errorf("expected a %s lockout, got %s", lockout, got)
+		}
+
+		// Let the lockout run out.
+		a.Throttle.Cache.Forget(key.lockName())
+	}
+
+	a.Throttle.MaxLockout = 5 * time.Minute
+	a.loginFailed(r, "me@example.com")
+	if got := time.Until(a.lockedUntil(key)); got > 5*time.Minute {
+		t.Errorf("expected the lockout to be capped, got %s", got)
+	}
+}
+
+func TestLoginThrottle_LocksIP(t *testing.T) {
+	a, _, _ := newThrottledAuth()
+	a.Throttle.MaxIPAttempts = 4
+	r := loginRequest("10.0.0.9")
+
+	for i := 0; i < 4; i++ {
+		a.loginFailed(r, strings.Repeat("x", i+1)+"@example.com")
+	}
+
+	if err := a.checkLoginThrottle(r, "fresh@example.com"); err == nil {
+		t.Error("expected the IP to be locked out for every email")
+	}
+	if err := a.checkLoginThrottle(loginRequest("10.0.0.10"), "fresh@example.com"); err != nil {
+		t.Errorf("expected another IP to pass, got %v", err)
+	}
+}
+
+func TestLoginThrottle_SuccessClearsEmail(t *testing.T) {
+	a, _, logs := newThrottledAuth()
+	r := loginRequest("10.0.0.1")
+	key := a.Throttle.emailKey("me@example.com")
+
+	a.loginFailed(r, "me@example.com")
+	a.loginSucceeded(r, "me@example.com")
+	if ok, _ := a.Throttle.Cache.Has(key.name); ok {
+		t.Error("expected a successful login to forget the failures of the email")
+	}
+	if strings.Contains(logs.String(), "unlocked") {
+		t.Error("expected no unlock event for an email that was never locked")
+	}
+}
+
+func TestLoginThrottle_CountsConcurrentFailures(t *testing.T) {
+	a, _, _ := newThrottledAuth()
+	a.Throttle.MaxAttempts = 50
+	key := a.Throttle.emailKey("me@example.com")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			a.loginFailed(loginRequest("10.0.0."+strconv.Itoa(i)), "me@example.com")
+		}(i)
+	}
+	wg.Wait()
+
+	if a.lockedUntil(key).IsZero() {
+		t.Error("expected 50 concurrent failures to lock the email out")
+	}
+	if failures, _ := a.Throttle.countFailure(key.name); failures != 51 {
+		t.Errorf("expected every concurrent failure to be counted, got %d", failures-1)
+	}
+}
+
+func TestLoginThrottle_CacheWithoutCounter(t *testing.T) {
+	a, c, _ := newThrottledAuth()
+	// Hide Increment, so failures are read and written back.
+	a.Throttle.Cache = struct{ cache.Cache }{c}
+	r := loginRequest("10.0.0.1")
+
+	for i := 0; i < 3; i++ {
+		a.loginFailed(r, "me@example.com")
+	}
+	if err := a.checkLoginThrottle(r, "me@example.com"); err == nil {
+		t.Error("expected a lockout after three failures")
+	}
+}
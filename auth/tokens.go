@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cidekar/adele-framework/database"
+	"github.com/cidekar/adele-framework/internal/bearer"
+	"github.com/cidekar/adele-framework/mux"
+)
+
+type contextKey string
+
+const tokenContextKey contextKey = "auth.token"
+
+// How often the last used time of a token is written back, so a busy client
+// does not cost an UPDATE per request.
+const tokenTouchInterval = time.Minute
+
+// Create a personal access token for a user. The plain text token is returned
+// once and cannot be recovered later; only its hash is stored. A zero expiresAt
+// creates a token that never expires, and the "*" scope grants every scope.
+func (a *Auth) CreateToken(ctx context.Context, userID int, name string, scopes []string, expiresAt time.Time) (string, *PersonalAccessToken, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	plainText := hex.EncodeToString(secret)
+
+	now := time.Now().UTC()
+	token := &PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		Token:     bearer.Hash(plainText),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	id, err := a.insert(ctx, `INSERT INTO personal_access_tokens (user_id, name, token, scopes, expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		userID, name, token.Token, strings.Join(scopes, " "), nullTime(expiresAt), now, now)
+	if err != nil {
+		return "", nil, err
+	}
+	token.ID = id
+
+	return plainText, token, nil
+}
+
+// Find the token matching a plain text bearer token. An unknown token returns
+// TokenInvalidError and an expired one TokenExpiredError. The last used time is
+// refreshed at most once a minute.
+func (a *Auth) FindToken(ctx context.Context, plainText string) (*PersonalAccessToken, error) {
+	if plainText == "" {
+		return nil, TokenInvalidError
+	}
+
+	row := a.DB.Pool.QueryRowContext(ctx, database.Rebind(a.dialect(), `SELECT id, user_id, name, token, scopes, last_used_at, expires_at, created_at, updated_at
+		FROM personal_access_tokens WHERE token = ?`), bearer.Hash(plainText))
+
+	token, err := scanToken(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, TokenInvalidError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if !token.ExpiresAt.IsZero() && !now.Before(token.ExpiresAt) {
+		return nil, TokenExpiredError
+	}
+
+	if now.Sub(token.LastUsedAt) >= tokenTouchInterval {
+		_, err = a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), "UPDATE personal_access_tokens SET last_used_at = ? WHERE id = ?"), now, token.ID)
+		if err != nil {
+			return nil, err
+		}
+		token.LastUsedAt = now
+	}
+
+	return token, nil
+}
+
+// List the personal access tokens of a user, oldest first.
+func (a *Auth) Tokens(ctx context.Context, userID int) ([]PersonalAccessToken, error) {
+	rows, err := a.DB.Pool.QueryContext(ctx, database.Rebind(a.dialect(), `SELECT id, user_id, name, token, scopes, last_used_at, expires_at, created_at, updated_at
+		FROM personal_access_tokens WHERE user_id = ? ORDER BY created_at, id`), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []PersonalAccessToken
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// Revoke one of a user's tokens. The user ID is part of the match so a user can
+// only revoke their own tokens.
+func (a *Auth) RevokeToken(ctx context.Context, userID int, id int64) error {
+	res, err := a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), "DELETE FROM personal_access_tokens WHERE id = ? AND user_id = ?"), id, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return TokenNotFoundError
+	}
+
+	return nil
+}
+
+// Revoke every token belonging to a user.
+func (a *Auth) RevokeTokens(ctx context.Context, userID int) error {
+	_, err := a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), "DELETE FROM personal_access_tokens WHERE user_id = ?"), userID)
+	return err
+}
+
+// Report whether the token grants every one of the given scopes.
+func (t *PersonalAccessToken) HasScopes(scopes ...string) bool {
+	return bearer.Grants(t.Scopes, scopes)
+}
+
+// Get the personal access token that authenticated the request, or nil when the
+// request did not pass through TokenMiddleware.
+func (a *Auth) Token(r *http.Request) *PersonalAccessToken {
+	return TokenFromContext(r.Context())
+}
+
+// Get the personal access token stored in a context by TokenMiddleware.
+func TokenFromContext(ctx context.Context) *PersonalAccessToken {
+	token, _ := ctx.Value(tokenContextKey).(*PersonalAccessToken)
+	return token
+}
+
+// TokenMiddleware authenticates a request with the personal access token in its
+// Authorization: Bearer header. A missing, unknown or expired token is answered
+// with 401. The request is then matched to the route chi serves it with and
+// answered with 403 when the token lacks any of the scopes annotated on the
+// route, e.g. m.Get("/api/photos[scopes:photos:read]", handler), or when the
+// route has no record in the mux tree. The token is stored in the request
+// context, where Token, Check and User find it.
+func (a *Auth) TokenMiddleware(m *mux.Mux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			plainText, ok := bearer.Token(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			token, err := a.FindToken(r.Context(), plainText)
+			if err != nil {
+				if !errors.Is(err, TokenInvalidError) && !errors.Is(err, TokenExpiredError) {
+					if a.ErrorLog != nil {
+						a.ErrorLog.Println("auth: find access token:", err)
+					}
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			route, err := m.RouteScopes(r)
+			if err != nil {
+				a.logError("auth: resolve route scopes:", err)
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			scopes := route.Scope
+			if !token.HasScopes(scopes...) {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(scopes, " ")))
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tokenContextKey, token)))
+		})
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanToken(row rowScanner) (*PersonalAccessToken, error) {
+	var (
+		token               PersonalAccessToken
+		scopes              string
+		lastUsed, expiresAt sql.NullTime
+	)
+
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.Token, &scopes, &lastUsed, &expiresAt, &token.CreatedAt, &token.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Scopes = strings.Fields(scopes)
+	token.LastUsedAt = lastUsed.Time
+	token.ExpiresAt = expiresAt.Time
+
+	return &token, nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// Convert the configured database type to the SQL dialect of the token queries.
+func (a *Auth) dialect() string {
+	return database.Dialect(a.DB.DataType)
+}
+
+// Run an INSERT and return the id of the new row, which postgres reports
+// through RETURNING and mysql through LastInsertId.
+func (a *Auth) insert(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	if a.dialect() == "mysql" {
+		res, err := a.DB.Pool.ExecContext(ctx, query, args...)
+		if err != nil {
+			return 0, err
+		}
+		return res.LastInsertId()
+	}
+
+	var id int64
+	err := a.DB.Pool.QueryRowContext(ctx, database.Rebind(a.dialect(), query+" RETURNING id"), args...).Scan(&id)
+	return id, err
+}
@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cidekar/adele-framework/database"
+	"github.com/cidekar/adele-framework/internal/bearer"
+	"github.com/cidekar/adele-framework/mux"
+)
+
+var tokenColumns = []string{"id", "user_id", "name", "token", "scopes", "last_used_at", "expires_at", "created_at", "updated_at"}
+
+func newTokenAuth(t *testing.T, dataType string) (*Auth, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return &Auth{DB: &database.Database{DataType: dataType, Pool: db}}, mock
+}
+
+func TestCreateToken_StoresHash(t *testing.T) {
+	a, mock := newTokenAuth(t, "postgres")
+
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO personal_access_tokens (user_id, name, token, scopes, expires_at, created_at, updated_at)")).
+		WithArgs(7, "cli", sqlmock.AnyArg(), "photos:read photos:write", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+
+	plainText, token, err := a.CreateToken(context.Background(), 7, "cli", []string{"photos:read", "photos:write"}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.ID != 42 {
+		t.Errorf("expected the returned id, got %d", token.ID)
+	}
+	if len(plainText) != 64 || token.Token == plainText || token.Token != bearer.Hash(plainText) {
+		t.Error("expected only the hash of the plain text token to be stored")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCreateToken_MySQL(t *testing.T) {
+	a, mock := newTokenAuth(t, "mariadb")
+
+	mock.ExpectExec(regexp.QuoteMeta("VALUES (?, ?, ?, ?, ?, ?, ?)")).
+		WillReturnResult(sqlmock.NewResult(9, 1))
+
+	_, token, err := a.CreateToken(context.Background(), 7, "cli", nil, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.ID != 9 {
+		t.Errorf("expected the last insert id, got %d", token.ID)
+	}
+}
+
+func TestFindToken(t *testing.T) {
+	a, mock := newTokenAuth(t, "postgres")
+	now := time.Now().UTC()
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM personal_access_tokens WHERE token = $1")).
+		WithArgs(bearer.Hash("valid")).
+		WillReturnRows(sqlmock.NewRows(tokenColumns).AddRow(1, 7, "cli", bearer.Hash("valid"), "photos:read", nil, nil, now, now))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE personal_access_tokens SET last_used_at = $1 WHERE id = $2")).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	token, err := a.FindToken(context.Background(), "valid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.UserID != 7 || !token.HasScopes("photos:read") || token.HasScopes("photos:write") {
+		t.Errorf("unexpected token %+v", token)
+	}
+	if token.LastUsedAt.IsZero() {
+		t.Error("expected the last used time to be refreshed")
+	}
+
+	// Used moments ago, so the last used time is not written again.
+	mock.ExpectQuery("FROM personal_access_tokens").
+		WillReturnRows(sqlmock.NewRows(tokenColumns).AddRow(1, 7, "cli", bearer.Hash("valid"), "*", now, nil, now, now))
+	if _, err := a.FindToken(context.Background(), "valid"); err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery("FROM personal_access_tokens").
+		WillReturnRows(sqlmock.NewRows(tokenColumns).AddRow(1, 7, "cli", bearer.Hash("old"), "", nil, now.Add(-time.Minute), now, now))
+	if _, err := a.FindToken(context.Background(), "old"); !errors.Is(err, TokenExpiredError) {
+		t.Errorf("expected TokenExpiredError, got %v", err)
+	}
+
+	mock.ExpectQuery("FROM personal_access_tokens").
+		WillReturnRows(sqlmock.NewRows(tokenColumns))
+	if _, err := a.FindToken(context.Background(), "unknown"); !errors.Is(err, TokenInvalidError) {
+		t.Errorf("expected TokenInvalidError, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	a, mock := newTokenAuth(t, "postgres")
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2")).
+		WithArgs(int64(3), 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := a.RevokeToken(context.Background(), 7, 3); !errors.Is(err, TokenNotFoundError) {
+		t.Errorf("expected TokenNotFoundError for another user's token, got %v", err)
+	}
+}
+
+func TestTokenMiddleware(t *testing.T) {
+	a, mock := newTokenAuth(t, "postgres")
+	now := time.Now().UTC()
+
+	m := mux.NewRouter()
+	m.Use(a.TokenMiddleware(m))
+	m.Get("/token-photos/{id}[scopes:photos:read]", func(w http.ResponseWriter, r *http.Request) {
+		if a.Token(r) == nil || !a.Check(r) {
+			t.Error("expected the token in the request context")
+		}
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		scopes string
+		want   int
+	}{
+		{"missing header", "", "", http.StatusUnauthorized},
+		{"unknown token", "Bearer unknown", "", http.StatusUnauthorized},
+		{"missing scope", "Bearer valid", "photos:write", http.StatusForbidden},
+		{"granted scope", "Bearer valid", "photos:read", http.StatusOK},
+		{"wildcard scope", "Bearer valid", "*", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			switch {
+			case tt.header == "":
+			case tt.scopes == "":
+				mock.ExpectQuery("FROM personal_access_tokens").WillReturnRows(sqlmock.NewRows(tokenColumns))
+			default:
+				mock.ExpectQuery("FROM personal_access_tokens").
+					WillReturnRows(sqlmock.NewRows(tokenColumns).AddRow(1, 7, "cli", bearer.Hash("valid"), tt.scopes, now, nil, now, now))
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/token-photos/5", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTokenMiddleware_Subrouters(t *testing.T) {
+	a, mock := newTokenAuth(t, "postgres")
+	now := time.Now().UTC()
+	h := func(w http.ResponseWriter, r *http.Request) {}
+
+	// A token middleware on a subrouter, and routes added to a subrouter
+	// after it is mounted.
+	root := mux.NewRouter()
+	api := mux.NewRouter()
+	api.Use(a.TokenMiddleware(api))
+	api.Get("/photos[scopes:photos:read]", h)
+	root.Mount("/token-sub", api)
+
+	late := mux.NewRouter()
+	web := mux.NewRouter()
+	web.Use(a.TokenMiddleware(web))
+	web.Mount("/token-late", late)
+	late.Get("/photos[scopes:photos:read]", h)
+	late.Mux.Get("/raw", h)
+	root.Mount("/", web)
+
+	tests := []struct {
+		path   string
+		scopes string
+		want   int
+	}{
+		{"/token-sub/photos", "photos:write", http.StatusForbidden},
+		{"/token-sub/photos", "photos:read", http.StatusOK},
+		{"/token-late/photos", "photos:write", http.StatusForbidden},
+		{"/token-late/photos", "photos:read", http.StatusOK},
+		{"/token-late/raw", "*", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		rows := sqlmock.NewRows(tokenColumns).AddRow(1, 7, "cli", bearer.Hash("valid"), tt.scopes, now, nil, now, now)
+		mock.ExpectQuery("FROM personal_access_tokens").WillReturnRows(rows)
+
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		r.Header.Set("Authorization", "Bearer valid")
+		w := httptest.NewRecorder()
+		root.ServeHTTP(w, r)
+
+		if w.Code != tt.want {
+			t.Errorf("GET %s with %q: expected %d, got %d", tt.path, tt.scopes, tt.want, w.Code)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,386 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"image/png"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cidekar/adele-framework/database"
+	"github.com/cidekar/adele-framework/internal/bearer"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// Session keys of a login awaiting its second factor, and of a session that
+// passed it.
+const (
+	twoFactorUserKey     = "twoFactorUserID"
+	twoFactorLoginKey    = "twoFactorLogin"
+	twoFactorStartedKey  = "twoFactorStartedAt"
+	twoFactorAttemptsKey = "twoFactorAttempts"
+	twoFactorVerifiedKey = "twoFactorVerified"
+)
+
+const (
+	// How long a user has to enter the code after their password.
+	twoFactorLoginTTL = 5 * time.Minute
+
+	// How many wrong codes end a pending login.
+	twoFactorMaxAttempts = 5
+
+	// How many recovery codes are issued at a time.
+	recoveryCodeCount = 8
+
+	// The TOTP period in seconds; codes of the periods next to the current one
+	// are accepted to allow for clock drift.
+	totpPeriod = 30
+)
+
+// Create a TOTP secret for a user. The secret is not used at login until the
+// user proves their authenticator app holds it with ConfirmTwoFactor; enrolling
+// again before that replaces it.
+func (a *Auth) EnableTwoFactor(ctx context.Context, user *User) (*TwoFactorEnrollment, error) {
+	enabled, err := a.TwoFactorEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, TwoFactorEnabledError
+	}
+
+	issuer := a.AppName
+	if issuer == "" {
+		issuer = "adele"
+	}
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: issuer, AccountName: user.Email})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), "DELETE FROM two_factor_credentials WHERE user_id = ?"), user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), "INSERT INTO two_factor_credentials (user_id, secret, created_at) VALUES (?, ?, ?)"),
+		user.ID, key.Secret(), time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorEnrollment{Secret: key.Secret(), URI: key.URL()}, nil
+}
+
+// Encode the provisioning URI as a PNG QR code of size by size pixels.
+func (e *TwoFactorEnrollment) QRCode(size int) ([]byte, error) {
+	key, err := otp.NewKeyFromURL(e.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := key.Image(size, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Finish the enrollment of the logged in user with a code from their
+// authenticator app. Two-factor authentication is enabled, the session counts
+// as verified for RequireTwoFactor, remember tokens of other browsers are
+// revoked, and the user's recovery codes are returned; they are shown once and
+// only their hashes are stored.
+func (a *Auth) ConfirmTwoFactor(r *http.Request, code string) ([]string, error) {
+	userID := a.Session.GetInt(r.Context(), "userID")
+	if userID == 0 {
+		return nil, TwoFactorNotEnabledError
+	}
+
+	secret, confirmed, lastStep, err := a.twoFactorCredential(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	if confirmed {
+		return nil, TwoFactorEnabledError
+	}
+
+	step, ok := totpStep(secret, code, time.Now())
+	if !ok || step <= lastStep {
+		return nil, TwoFactorInvalidError
+	}
+
+	_, err = a.DB.Pool.ExecContext(r.Context(), database.Rebind(a.dialect(), "UPDATE two_factor_credentials SET confirmed_at = ?, last_used_step = ? WHERE user_id = ?"),
+		time.Now().UTC(), step, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := a.RegenerateRecoveryCodes(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Browsers kept logged in by a password alone must pass the second factor.
+	if err := a.forgetOtherRemembered(r, userID); err != nil {
+		return nil, err
+	}
+
+	a.Session.Put(r.Context(), twoFactorVerifiedKey, true)
+	return codes, nil
+}
+
+// Turn two-factor authentication off for a user and drop their recovery codes.
+func (a *Auth) DisableTwoFactor(ctx context.Context, userID int) error {
+	_, err := a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), "DELETE FROM two_factor_recovery_codes WHERE user_id = ?"), userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), "DELETE FROM two_factor_credentials WHERE user_id = ?"), userID)
+	return err
+}
+
+// Check if a user has confirmed a two-factor enrollment.
+func (a *Auth) TwoFactorEnabled(ctx context.Context, userID int) (bool, error) {
+	_, confirmed, _, err := a.twoFactorCredential(ctx, userID)
+	if errors.Is(err, TwoFactorNotEnabledError) {
+		return false, nil
+	}
+
+	return confirmed, err
+}
+
+// Replace the recovery codes of a user with new ones. Each code logs the user
+// in once in place of a TOTP code.
+func (a *Auth) RegenerateRecoveryCodes(ctx context.Context, userID int) ([]string, error) {
+	_, err := a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), "DELETE FROM two_factor_recovery_codes WHERE user_id = ?"), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = recoveryCode(); err != nil {
+			return nil, err
+		}
+
+		_, err = a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), "INSERT INTO two_factor_recovery_codes (user_id, code, created_at) VALUES (?, ?, ?)"),
+			userID, bearer.Hash(codes[i]), now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+// Check if a login is waiting for VerifyTwoFactor, e.g. to decide whether to
+// show the two-factor challenge page.
+func (a *Auth) PendingTwoFactor(r *http.Request) bool {
+	return a.pendingTwoFactorUser(r) != 0
+}
+
+// Finish a login Login left waiting for the second factor with a TOTP code or
+// one of the user's recovery codes. Only then is the user id written into the
+// session. After too many wrong codes, or when the code comes too late, the
+// pending login is dropped and the user has to enter their password again.
+// Wrong codes count as failed logins for Throttle, whose failures of the login
+// are forgotten only once the user is logged in.
+func (a *Auth) VerifyTwoFactor(w http.ResponseWriter, r *http.Request, code string) (bool, error) {
+	userID := a.pendingTwoFactorUser(r)
+	if userID == 0 {
+		return false, TwoFactorNoLoginError
+	}
+
+	login := a.Session.GetString(r.Context(), twoFactorLoginKey)
+	if err := a.checkLoginThrottle(r, login); err != nil {
+		return false, err
+	}
+
+	ok, err := a.checkTwoFactorCode(r.Context(), userID, code)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		a.loginFailed(r, login)
+		attempts := a.Session.GetInt(r.Context(), twoFactorAttemptsKey) + 1
+		if attempts >= twoFactorMaxAttempts {
+			a.clearTwoFactor(r)
+		} else {
+			a.Session.Put(r.Context(), twoFactorAttemptsKey, attempts)
+		}
+		return false, TwoFactorInvalidError
+	}
+
+	a.clearTwoFactor(r)
+	if err := a.Session.RenewToken(r.Context()); err != nil {
+		return false, err
+	}
+	a.Session.Put(r.Context(), twoFactorVerifiedKey, true)
+
+	if ok, err := a.completeLogin(w, r, userID); !ok {
+		return false, err
+	}
+	a.loginSucceeded(r, login)
+	return true, nil
+}
+
+// RequireTwoFactor refuses requests through Forbidden unless the user logged
+// in with a second factor, or confirmed their enrollment, in this session.
+// Users without two-factor authentication are refused as well, e.g. to require
+// it on admin routes.
+func (a *Auth) RequireTwoFactor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Session.Exists(r.Context(), "userID") || !a.Session.GetBool(r.Context(), twoFactorVerifiedKey) {
+			a.forbidden(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Hold a user whose password checked out until VerifyTwoFactor, along with
+// the login they gave for the throttle. The session token is renewed so the
+// pending state cannot be planted by someone else.
+func (a *Auth) startTwoFactor(r *http.Request, userID int, login string) error {
+	if err := a.Session.RenewToken(r.Context()); err != nil {
+		return err
+	}
+	a.Session.Put(r.Context(), twoFactorUserKey, userID)
+	a.Session.Put(r.Context(), twoFactorLoginKey, login)
+	a.Session.Put(r.Context(), twoFactorStartedKey, time.Now().Unix())
+	a.Session.Remove(r.Context(), twoFactorAttemptsKey)
+	return nil
+}
+
+// Get the user of the pending login, or 0 if there is none or it expired.
+func (a *Auth) pendingTwoFactorUser(r *http.Request) int {
+	userID := a.Session.GetInt(r.Context(), twoFactorUserKey)
+	if userID == 0 {
+		return 0
+	}
+
+	started := time.Unix(a.Session.GetInt64(r.Context(), twoFactorStartedKey), 0)
+	if time.Since(started) > twoFactorLoginTTL {
+		a.clearTwoFactor(r)
+		return 0
+	}
+
+	return userID
+}
+
+func (a *Auth) clearTwoFactor(r *http.Request) {
+	a.Session.Remove(r.Context(), twoFactorUserKey)
+	a.Session.Remove(r.Context(), twoFactorLoginKey)
+	a.Session.Remove(r.Context(), twoFactorStartedKey)
+	a.Session.Remove(r.Context(), twoFactorAttemptsKey)
+}
+
+// Check a TOTP code, which may be used only once, or else use up a recovery
+// code.
+func (a *Auth) checkTwoFactorCode(ctx context.Context, userID int, code string) (bool, error) {
+	secret, confirmed, _, err := a.twoFactorCredential(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if !confirmed {
+		return false, TwoFactorNotEnabledError
+	}
+
+	if step, ok := totpStep(secret, code, time.Now()); ok {
+		// Only the request moving the last used step forward wins, so a code
+		// cannot be replayed, not even concurrently.
+		res, err := a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), "UPDATE two_factor_credentials SET last_used_step = ? WHERE user_id = ? AND (last_used_step IS NULL OR last_used_step < ?)"),
+			step, userID, step)
+		if err != nil {
+			return false, err
+		}
+		n, err := res.RowsAffected()
+		return n == 1, err
+	}
+
+	res, err := a.DB.Pool.ExecContext(ctx, database.Rebind(a.dialect(), "UPDATE two_factor_recovery_codes SET used_at = ? WHERE user_id = ? AND code = ? AND used_at IS NULL"),
+		time.Now().UTC(), userID, bearer.Hash(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Load the TOTP secret of a user, whether the enrollment is confirmed and the
+// last period a code was used in.
+func (a *Auth) twoFactorCredential(ctx context.Context, userID int) (string, bool, int64, error) {
+	var (
+		secret      string
+		confirmedAt sql.NullTime
+		lastStep    sql.NullInt64
+	)
+
+	err := a.DB.Pool.QueryRowContext(ctx, database.Rebind(a.dialect(), "SELECT secret, confirmed_at, last_used_step FROM two_factor_credentials WHERE user_id = ?"), userID).
+		Scan(&secret, &confirmedAt, &lastStep)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, 0, TwoFactorNotEnabledError
+	}
+	if err != nil {
+		return "", false, 0, err
+	}
+
+	return secret, confirmedAt.Valid, lastStep.Int64, nil
+}
+
+// Find the TOTP period, one before to one after the current one, whose code
+// matches. The step is returned so the code can be used only once.
+func totpStep(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != 6 {
+		return 0, false
+	}
+
+	step := now.Unix() / totpPeriod
+	for _, s := range []int64{step - 1, step, step + 1} {
+		want, err := totp.GenerateCodeCustom(secret, time.Unix(s*totpPeriod, 0), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return s, true
+		}
+	}
+
+	return 0, false
+}
+
+// Create a recovery code such as "k3j9x-2mq7a".
+func recoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
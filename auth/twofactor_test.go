@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alexedwards/scs/v2"
+	"github.com/cidekar/adele-framework/internal/bearer"
+	"github.com/pquerna/otp/totp"
+)
+
+const testSecret = "JBSWY3DPEHPK3PXP"
+
+// Run fn inside the session carried by cookie and return the session cookie
+// of the response.
+func inSession(a *Auth, cookie *http.Cookie, fn func(w http.ResponseWriter, r *http.Request)) *http.Cookie {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	a.Session.LoadAndSave(http.HandlerFunc(fn)).ServeHTTP(w, r)
+
+	for _, c := range w.Result().Cookies() {
+		if c.Name == a.Session.Cookie.Name {
+			return c
+		}
+	}
+	return cookie
+}
+
+func TestTotpStep(t *testing.T) {
+	now := time.Now()
+	code, err := totp.GenerateCode(testSecret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step, ok := totpStep(testSecret, code, now)
+	if !ok || step != now.Unix()/totpPeriod {
+		t.Errorf("expected the current code to match the current step, got %d %v", step, ok)
+	}
+
+	if _, ok := totpStep(testSecret, code, now.Add(2*time.Minute)); ok {
+		t.Error("expected an old code to be refused")
+	}
+	if _, ok := totpStep(testSecret, "12345", now); ok {
+		t.Error("expected a short code to be refused")
+	}
+}
+
+func TestVerifyTwoFactor_WrongCodes(t *testing.T) {
+	a, mock := newTokenAuth(t, "postgres")
+	a.Session = scs.New()
+
+	cookie := inSession(a, nil, func(w http.ResponseWriter, r *http.Request) {
+		if err := a.startTwoFactor(r, 7, "me@example.com"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	for i := 0; i < twoFactorMaxAttempts; i++ {
+		mock.ExpectQuery("SELECT secret, confirmed_at, last_used_step FROM two_factor_credentials").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"secret", "confirmed_at", "last_used_step"}).AddRow(testSecret, time.Now(), nil))
+		mock.ExpectExec("UPDATE two_factor_recovery_codes SET used_at").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		cookie = inSession(a, cookie, func(w http.ResponseWriter, r *http.Request) {
+			if !a.PendingTwoFactor(r) {
+				t.Fatal("expected the login to await a code")
+			}
+			if ok, err := a.VerifyTwoFactor(w, r, "not-a-code"); ok || !errors.Is(err, TwoFactorInvalidError) {
+				t.Errorf("expected TwoFactorInvalidError, got %v", err)
+			}
+			if a.Session.Exists(r.Context(), "userID") {
+				t.Error("expected no user id before the second factor")
+			}
+		})
+	}
+
+	inSession(a, cookie, func(w http.ResponseWriter, r *http.Request) {
+		if _, err := a.VerifyTwoFactor(w, r, "123456"); !errors.Is(err, TwoFactorNoLoginError) {
+			t.Errorf("expected too many wrong codes to end the login, got %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVerifyTwoFactor_WrongCodesLockLogin(t *testing.T) {
+	a, mock := newTokenAuth(t, "postgres")
+	a.Session = scs.New()
+	a.Throttle = NewLoginThrottle(newMemoryCache())
+	a.Throttle.MaxAttempts = 3
+
+	cookie := inSession(a, nil, func(w http.ResponseWriter, r *http.Request) {
+		if err := a.startTwoFactor(r, 7, "me@example.com"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	for i := 0; i < a.Throttle.MaxAttempts; i++ {
+		mock.ExpectQuery("SELECT secret, confirmed_at, last_used_step FROM two_factor_credentials").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"secret", "confirmed_at", "last_used_step"}).AddRow(testSecret, time.Now(), nil))
+		mock.ExpectExec("UPDATE two_factor_recovery_codes SET used_at").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		cookie = inSession(a, cookie, func(w http.ResponseWriter, r *http.Request) {
+			if _, err := a.VerifyTwoFactor(w, r, "not-a-code"); !errors.Is(err, TwoFactorInvalidError) {
+				t.Errorf("expected TwoFactorInvalidError, got %v", err)
+			}
+		})
+	}
+
+	// The wrong codes lock the login out, so neither the pending login nor a
+	// new one with the password gets another guess.
+	inSession(a, cookie, func(w http.ResponseWriter, r *http.Request) {
+		var throttled *LoginThrottledError
+		if _, err := a.VerifyTwoFactor(w, r, "123456"); !errors.As(err, &throttled) {
+			t.Errorf("expected wrong codes to lock the login, got %v", err)
+		}
+		if err := a.checkLoginThrottle(r, "me@example.com"); !errors.As(err, &throttled) {
+			t.Errorf("expected a new login to be refused, got %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVerifyTwoFactor_Expired(t *testing.T) {
+	a := &Auth{Session: scs.New()}
+
+	cookie := inSession(a, nil, func(w http.ResponseWriter, r *http.Request) {
+		a.Session.Put(r.Context(), twoFactorUserKey, 7)
+		a.Session.Put(r.Context(), twoFactorStartedKey, time.Now().Add(-twoFactorLoginTTL-time.Second).Unix())
+	})
+
+	inSession(a, cookie, func(w http.ResponseWriter, r *http.Request) {
+		if _, err := a.VerifyTwoFactor(w, r, "123456"); !errors.Is(err, TwoFactorNoLoginError) {
+			t.Errorf("expected an expired login to be refused, got %v", err)
+		}
+	})
+}
+
+func TestCheckTwoFactorCode_RecoveryCode(t *testing.T) {
+	a, mock := newTokenAuth(t, "mysql")
+
+	mock.ExpectQuery("SELECT secret, confirmed_at, last_used_step FROM two_factor_credentials").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"secret", "confirmed_at", "last_used_step"}).AddRow(testSecret, time.Now(), 10))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE two_factor_recovery_codes SET used_at = ? WHERE user_id = ? AND code = ? AND used_at IS NULL")).
+		WithArgs(sqlmock.AnyArg(), 7, bearer.Hash("abcde-12345")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ok, err := a.checkTwoFactorCode(context.Background(), 7, " ABCDE-12345 ")
+	if err != nil || !ok {
+		t.Errorf("expected the recovery code to be accepted, got %v %v", ok, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConfirmTwoFactor_RequireTwoFactor(t *testing.T) {
+	a, mock := newTokenAuth(t, "postgres")
+	a.Session = scs.New()
+
+	h := a.RequireTwoFactor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	status := func(cookie *http.Cookie) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		a.Session.LoadAndSave(h).ServeHTTP(w, r)
+		return w.Code
+	}
+
+	cookie := inSession(a, nil, func(w http.ResponseWriter, r *http.Request) {
+		a.Session.Put(r.Context(), "userID", 7)
+	})
+	if status(cookie) != http.StatusForbidden {
+		t.Error("expected a session without a second factor to be refused")
+	}
+
+	mock.ExpectQuery("SELECT secret, confirmed_at, last_used_step FROM two_factor_credentials").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"secret", "confirmed_at", "last_used_step"}).AddRow(testSecret, nil, nil))
+	mock.ExpectExec("UPDATE two_factor_credentials SET confirmed_at").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM two_factor_recovery_codes").WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 0))
+	for i := 0; i < recoveryCodeCount; i++ {
+		mock.ExpectExec("INSERT INTO two_factor_recovery_codes").WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec("DELETE FROM remember_tokens WHERE user_id").WithArgs(7, "").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	code, _ := totp.GenerateCode(testSecret, time.Now())
+	cookie = inSession(a, cookie, func(w http.ResponseWriter, r *http.Request) {
+		codes, err := a.ConfirmTwoFactor(r, code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(codes) != recoveryCodeCount || len(codes[0]) != 11 {
+			t.Errorf("unexpected recovery codes %v", codes)
+		}
+	})
+
+	if status(cookie) != http.StatusOK {
+		t.Error("expected the confirmed session to pass")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTwoFactorEnrollment_QRCode(t *testing.T) {
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: "adele", AccountName: "me@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	png, err := (&TwoFactorEnrollment{Secret: key.Secret(), URI: key.URL()}).QRCode(200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(png) < 8 || string(png[1:4]) != "PNG" {
+		t.Error("expected a PNG image")
+	}
+}
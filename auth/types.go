@@ -1,11 +1,18 @@
 package auth
 
 import (
+	"context"
 	"log"
+	"net/http"
+	"reflect"
+	"sync"
 	"time"
 
 	"github.com/alexedwards/scs/v2"
+	"github.com/cidekar/adele-framework/cache"
 	"github.com/cidekar/adele-framework/database"
+	"github.com/cidekar/adele-framework/mux"
+	"github.com/sirupsen/logrus"
 )
 
 type Auth struct {
@@ -13,8 +20,174 @@ type Auth struct {
 	DB       *database.Database
 	ErrorLog *log.Logger
 	Session  *scs.SessionManager
+
+	// Look users up and check their passwords. Defaults to a
+	// DatabaseUserProvider on the users table.
+	Users UserProvider
+
+	// The table remember-me tokens are stored in. Defaults to remember_tokens.
+	// Without DB, e.g. when only a UserProvider is configured, no remember
+	// tokens are issued.
+	RememberTable string
+
+	// Hash new passwords. Login rehashes passwords whose stored hash the
+	// Hasher finds weaker than its own. Defaults to bcrypt at cost 12.
+	Hasher Hasher
+
+	// Resolve named routes, such as the login route Logout redirects to.
+	Routes *mux.Mux
+
+	// Resolve the permissions granted to a user, which ScopeMiddleware checks
+	// against the scopes annotated on routes.
+	Permissions PermissionResolver
+
+	// Respond to a request refused by ScopeMiddleware or RequireTwoFactor.
+	// Defaults to a plain 403.
+	Forbidden http.HandlerFunc
+
+	// Ask users that enabled two-factor authentication for a TOTP or recovery
+	// code after their password. Requires the two_factor tables.
+	TwoFactor bool
+
+	// Lock out email addresses and client IPs after repeated failed logins.
+	// Nil disables throttling.
+	Throttle *LoginThrottle
+
+	// Send signed links proving users own their email address. Nil disables
+	// email verification.
+	Verification *EmailVerification
+
+	// Security events such as login lockouts are logged here.
+	Log *logrus.Logger
+
+	mu       sync.RWMutex
+	gates    map[string]Gate
+	policies map[reflect.Type]Policy
+}
+
+// A UserProvider finds the users Auth logs in and checks their credentials,
+// e.g. against a legacy table or a directory service. FindByID and
+// FindByCredentials return nil without an error for an unknown user.
+type UserProvider interface {
+	FindByID(ctx context.Context, id int) (*User, error)
+	FindByCredentials(ctx context.Context, credentials Credentials) (*User, error)
+	ValidateCredentials(ctx context.Context, user *User, credentials Credentials) (bool, error)
+}
+
+// Credentials are what a user logs in with. Login is the email address, or
+// whatever else the UserProvider identifies users by, such as a username.
+type Credentials struct {
+	Login    string
+	Password string
+}
+
+// A PasswordUpdater is a UserProvider that can store a new password hash for a
+// user, which Login uses to upgrade weaker hashes.
+type PasswordUpdater interface {
+	UpdatePassword(ctx context.Context, user *User, hash string) error
+}
+
+// A Hasher hashes passwords into self-describing strings naming their
+// algorithm and parameters, so a hash can be checked after the configured
+// algorithm changed.
+type Hasher interface {
+	Hash(password string) (string, error)
+	Check(password, hash string) (bool, error)
+
+	// Report whether a hash uses a weaker algorithm or weaker parameters than
+	// the hasher and should be replaced on the next login.
+	NeedsRehash(hash string) bool
+}
+
+// A BcryptHasher hashes passwords with bcrypt at Cost.
+type BcryptHasher struct {
+	Cost int
+}
+
+// An Argon2idHasher hashes passwords with argon2id, using Memory KiB, Iterations
+// passes and Parallelism lanes for a KeyLength byte key from a SaltLength byte
+// random salt.
+type Argon2idHasher struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// A DatabaseUserProvider loads users from Table, matching the login against
+// LoginColumn and the password against the hash in the password column with
+// Hasher, or CheckPassword when it is nil. When
+// Model is set to a pointer to the application's own user struct, e.g.
+// &models.User{}, the row is also decoded into a new value of that type and
+// stored in User.Model, so extra columns are available to the application.
+type DatabaseUserProvider struct {
+	DB          *database.Database
+	Table       string
+	LoginColumn string
+	Model       interface{}
+	Hasher      Hasher
+}
+
+// A UserSession is a session a user is logged in with, as listed by Sessions.
+// ID names the session to RevokeSession without revealing its token, and
+// Current marks the session of the request.
+type UserSession struct {
+	ID           string    `json:"id"`
+	IPAddress    string    `json:"ipAddress"`
+	UserAgent    string    `json:"userAgent"`
+	LastActivity time.Time `json:"lastActivity"`
+	Current      bool      `json:"current"`
+}
+
+// A LoginThrottle counts failed logins per email address and per client IP in
+// a cache. Once MaxAttempts failures for an email, or MaxIPAttempts for an IP,
+// are counted within Decay, the next login is locked out for Lockout, and every
+// further failure doubles the lockout up to MaxLockout. A Cache that is a
+// cache.Counter, like the redis and badger drivers, counts concurrent failures
+// atomically; any other cache may lose failures counted at the same time.
+type LoginThrottle struct {
+	Cache         cache.Cache
+	MaxAttempts   int
+	MaxIPAttempts int
+	Decay         time.Duration
+	Lockout       time.Duration
+	MaxLockout    time.Duration
+}
+
+// EmailVerification sends users a signed link to the verification route at
+// Path under URL, the application URL. The link expires after Expiry, counted
+// in whole minutes, and a user can ask for a new one every ResendInterval. The
+// Verified middleware sends unverified users to NoticeURL.
+type EmailVerification struct {
+	Signer         URLSigner
+	Send           func(user *User, link string) error
+	URL            string
+	Path           string
+	NoticeURL      string
+	Expiry         time.Duration
+	ResendInterval time.Duration
 }
 
+// A URLSigner signs links and verifies them later; *urlsigner.Signer is one.
+type URLSigner interface {
+	GenerateTokenFromString(data string) string
+	VerifyToken(token string) bool
+	Expired(token string, minutesUntilExpired int) bool
+}
+
+// A Gate decides whether a user may perform an ability, optionally on a
+// resource such as a *Post. The user is never nil.
+type Gate func(r *http.Request, user *User, resource interface{}) bool
+
+// A Policy holds the gates of one model type by ability, e.g.
+// auth.Policy{"update": canUpdatePost}.
+type Policy map[string]Gate
+
+// A PermissionResolver returns the permissions granted to a user, such as
+// "posts:write". The "*" permission grants every scope.
+type PermissionResolver func(r *http.Request, user *User) ([]string, error)
+
 type User struct {
 	ID        int       `db:"id,omitempty" json:"id"`
 	FirstName string    `db:"first_name"   json:"firstName"`
@@ -24,12 +197,57 @@ type User struct {
 	Password  string    `db:"password"     json:"-"`
 	CreatedAt time.Time `db:"created_at"   json:"createdAt"`
 	UpdatedAt time.Time `db:"updated_at"   json:"updatedAt"`
+
+	// Set once the user followed an email verification link; nil until then or
+	// when the users table has no email_verified_at column.
+	EmailVerifiedAt *time.Time `db:"email_verified_at,omitempty" json:"emailVerifiedAt"`
+
+	// The row decoded into the Model of a DatabaseUserProvider, or whatever
+	// another UserProvider attaches.
+	Model interface{} `db:"-" json:"-"`
 }
 
+// A Role groups permissions, such as "posts:write", that are granted to every
+// user the role is assigned to.
+type Role struct {
+	ID        int64     `db:"id,omitempty" json:"id"`
+	Name      string    `db:"name"         json:"name"`
+	CreatedAt time.Time `db:"created_at"   json:"createdAt"`
+}
+
+// A TwoFactorEnrollment is the TOTP secret created by EnableTwoFactor. The user
+// adds it to an authenticator app, usually by scanning the QR code of URI, and
+// proves it with ConfirmTwoFactor.
+type TwoFactorEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// A RememberToken keeps a user logged in across browser sessions. The cookie
+// holds the Selector and a validator; only the SHA-256 hash of the validator is
+// stored in RememberToken, and it is replaced every time the cookie is used.
 type RememberToken struct {
 	ID            int       `db:"id,omitempty"   json:"id"`
 	UserID        int       `db:"user_id"        json:"userId"`
+	Selector      string    `db:"selector"       json:"-"`
 	RememberToken string    `db:"remember_token" json:"-"`
 	CreatedAt     time.Time `db:"created_at"     json:"createdAt"`
 	UpdatedAt     time.Time `db:"updated_at"     json:"updatedAt"`
 }
+
+// A personal access token lets a client that cannot hold a cookie session, such
+// as a mobile app or a CLI, authenticate with an Authorization: Bearer header.
+// Only the SHA-256 hash of the token is stored; the plain text is returned once
+// by CreateToken. A zero ExpiresAt never expires and a zero LastUsedAt has never
+// been used.
+type PersonalAccessToken struct {
+	ID         int64     `db:"id,omitempty" json:"id"`
+	UserID     int       `db:"user_id"      json:"userId"`
+	Name       string    `db:"name"         json:"name"`
+	Token      string    `db:"token"        json:"-"`
+	Scopes     []string  `db:"scopes"       json:"scopes"`
+	LastUsedAt time.Time `db:"last_used_at" json:"lastUsedAt"`
+	ExpiresAt  time.Time `db:"expires_at"   json:"expiresAt"`
+	CreatedAt  time.Time `db:"created_at"   json:"createdAt"`
+	UpdatedAt  time.Time `db:"updated_at"   json:"updatedAt"`
+}
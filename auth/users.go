@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/cidekar/adele-framework/database"
+	up "github.com/upper/db/v4"
+)
+
+// Create a provider loading users from the users table by email address.
+func NewDatabaseUserProvider(db *database.Database) *DatabaseUserProvider {
+	return &DatabaseUserProvider{DB: db, Table: "users", LoginColumn: "email"}
+}
+
+// Find the user with the given id.
+func (p *DatabaseUserProvider) FindByID(ctx context.Context, id int) (*User, error) {
+	return p.find(up.Cond{"id =": id})
+}
+
+// Find the user whose LoginColumn matches the login of the credentials. The
+// password is not checked.
+func (p *DatabaseUserProvider) FindByCredentials(ctx context.Context, credentials Credentials) (*User, error) {
+	column := p.LoginColumn
+	if column == "" {
+		column = "email"
+	}
+
+	return p.find(up.Cond{column + " =": credentials.Login})
+}
+
+// Check the password of the credentials against the hash of the user.
+func (p *DatabaseUserProvider) ValidateCredentials(ctx context.Context, user *User, credentials Credentials) (bool, error) {
+	if p.Hasher != nil {
+		return p.Hasher.Check(credentials.Password, user.Password)
+	}
+
+	return CheckPassword(credentials.Password, user.Password)
+}
+
+// Store a new password hash for a user.
+func (p *DatabaseUserProvider) UpdatePassword(ctx context.Context, user *User, hash string) error {
+	session := p.DB.NewSession()
+	if session == nil {
+		return errors.New("auth: no database session for the user provider")
+	}
+
+	return session.Collection(p.table()).Find(up.Cond{"id =": user.ID}).Update(map[string]interface{}{"password": hash})
+}
+
+func (p *DatabaseUserProvider) find(cond up.Cond) (*User, error) {
+	session := p.DB.NewSession()
+	if session == nil {
+		return nil, errors.New("auth: no database session for the user provider")
+	}
+
+	res := session.Collection(p.table()).Find(cond)
+
+	var user User
+	if err := res.One(&user); err != nil {
+		if errors.Is(err, up.ErrNoMoreRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if p.Model != nil {
+		model := reflect.New(reflect.TypeOf(p.Model).Elem()).Interface()
+		if err := res.One(model); err != nil {
+			return nil, err
+		}
+		user.Model = model
+	}
+
+	return &user, nil
+}
+
+func (p *DatabaseUserProvider) table() string {
+	if p.Table == "" {
+		return "users"
+	}
+	return p.Table
+}
+
+// The provider users are looked up with.
+func (a *Auth) users() UserProvider {
+	if a.Users == nil {
+		return NewDatabaseUserProvider(a.DB)
+	}
+	return a.Users
+}
+
+// The table users are stored in, for the queries Auth runs itself.
+func (a *Auth) usersTable() string {
+	if p, ok := a.Users.(*DatabaseUserProvider); ok {
+		return p.table()
+	}
+	return "users"
+}
+
+func (a *Auth) rememberTable() string {
+	if a.RememberTable == "" {
+		return "remember_tokens"
+	}
+	return a.RememberTable
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alexedwards/scs/v2"
+)
+
+// A UserProvider logging users in by username, standing in for a directory
+// service.
+type usernameProvider map[string]*User
+
+func (p usernameProvider) FindByID(ctx context.Context, id int) (*User, error) {
+	for _, user := range p {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (p usernameProvider) FindByCredentials(ctx context.Context, credentials Credentials) (*User, error) {
+	return p[credentials.Login], nil
+}
+
+func (p usernameProvider) ValidateCredentials(ctx context.Context, user *User, credentials Credentials) (bool, error) {
+	return user.Password == "hash:"+credentials.Password, nil
+}
+
+func TestLogin_UserProvider(t *testing.T) {
+	a, mock, _ := newRememberAuth(t)
+	a.Users = usernameProvider{"ada": {ID: 7, Password: "hash:secret"}}
+
+	var cookie *http.Cookie
+	login := func(username, password string) (ok bool, err error) {
+		cookie = inSession(a, nil, func(w http.ResponseWriter, r *http.Request) {
+			ok, err = a.Login(w, r, username, password)
+		})
+		return ok, err
+	}
+
+	if ok, err := login("grace", "secret"); ok || err != nil {
+		t.Errorf("expected an unknown user to be refused, got %v %v", ok, err)
+	}
+	if ok, err := login("ada", "wrong"); ok || !errors.Is(err, InvalidPasswordOrUserError) {
+		t.Errorf("expected InvalidPasswordOrUserError, got %v %v", ok, err)
+	}
+
+	mock.ExpectExec("INSERT INTO remember_tokens").WithArgs(7, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	if ok, err := login("ada", "secret"); !ok || err != nil {
+		t.Fatalf("expected ada to be logged in, got %v %v", ok, err)
+	}
+
+	inSession(a, cookie, func(w http.ResponseWriter, r *http.Request) {
+		if user := a.User(r); user == nil || user.ID != 7 {
+			t.Errorf("expected the provider's user 7, got %v", user)
+		}
+	})
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLogin_UserProviderWithoutDatabase(t *testing.T) {
+	a := &Auth{Session: scs.New(), Users: usernameProvider{"ada": {ID: 7, Password: "hash:secret"}}}
+
+	var ok bool
+	var err error
+	cookie := inSession(a, nil, func(w http.ResponseWriter, r *http.Request) {
+		ok, err = a.Login(w, r, "ada", "secret")
+		for _, c := range w.Header().Values("Set-Cookie") {
+			if strings.HasPrefix(c, a.rememberCookieName()+"=") {
+				t.Error("expected no remember cookie without a database")
+			}
+		}
+	})
+	if !ok || err != nil {
+		t.Fatalf("expected ada to be logged in, got %v %v", ok, err)
+	}
+
+	inSession(a, cookie, func(w http.ResponseWriter, r *http.Request) {
+		if user := a.User(r); user == nil || user.ID != 7 {
+			t.Errorf("expected the provider's user 7, got %v", user)
+		}
+		if ok, err := a.Logout(w, r); !ok || err != nil {
+			t.Errorf("expected the user to be logged out, got %v %v", ok, err)
+		}
+	})
+}
+
+func TestUser_Guest(t *testing.T) {
+	a, _, _ := newRememberAuth(t)
+	a.Users = usernameProvider{}
+
+	inSession(a, nil, func(w http.ResponseWriter, r *http.Request) {
+		if user := a.User(r); user != nil {
+			t.Errorf("expected no user for a guest, got %v", user)
+		}
+	})
+}
+
+func TestAuth_Tables(t *testing.T) {
+	a := &Auth{Users: &DatabaseUserProvider{Table: "accounts"}, RememberTable: "account_remember_tokens"}
+	if a.usersTable() != "accounts" || a.rememberTable() != "account_remember_tokens" {
+		t.Errorf("expected the configured tables, got %s %s", a.usersTable(), a.rememberTable())
+	}
+
+	a = &Auth{Users: usernameProvider{}}
+	if a.usersTable() != "users" || a.rememberTable() != "remember_tokens" {
+		t.Errorf("expected the default tables, got %s %s", a.usersTable(), a.rememberTable())
+	}
+}
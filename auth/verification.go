@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cidekar/adele-framework/database"
+)
+
+// Session key holding when the last verification email was sent.
+const verificationSentKey = "verificationSentAt"
+
+// Send a user a signed link verifying their email address, e.g. after they
+// registered. The link names the address it was sent to, so it stops working
+// when the user changes their email.
+func (a *Auth) SendVerificationEmail(user *User) error {
+	v := a.Verification
+	if v == nil {
+		return EmailVerificationDisabledError
+	}
+
+	link := fmt.Sprintf("%s%s?id=%d&email=%s", strings.TrimRight(v.URL, "/"), v.Path, user.ID, emailHash(user.Email))
+	return v.Send(user, v.Signer.GenerateTokenFromString(link))
+}
+
+// Verify the email address of the user a verification link was sent to, for
+// the handler of the verification route, and return the id of the user. A
+// forged or altered link returns VerificationLinkInvalidError and an old one
+// VerificationLinkExpiredError. Following a link again is not an error.
+func (a *Auth) VerifyEmail(r *http.Request) (int, error) {
+	v := a.Verification
+	if v == nil {
+		return 0, EmailVerificationDisabledError
+	}
+
+	link := strings.TrimRight(v.URL, "/") + r.URL.RequestURI()
+	if !v.Signer.VerifyToken(link) {
+		return 0, VerificationLinkInvalidError
+	}
+	if v.Signer.Expired(link, int(v.Expiry/time.Minute)) {
+		return 0, VerificationLinkExpiredError
+	}
+
+	userID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		return 0, VerificationLinkInvalidError
+	}
+
+	var (
+		email      string
+		verifiedAt sql.NullTime
+	)
+	err = a.DB.Pool.QueryRowContext(r.Context(), database.Rebind(a.dialect(), fmt.Sprintf("SELECT email, email_verified_at FROM %s WHERE id = ?", a.usersTable())), userID).
+		Scan(&email, &verifiedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, VerificationLinkInvalidError
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(emailHash(email)), []byte(r.URL.Query().Get("email"))) != 1 {
+		return 0, VerificationLinkInvalidError
+	}
+
+	if !verifiedAt.Valid {
+		_, err = a.DB.Pool.ExecContext(r.Context(), database.Rebind(a.dialect(), fmt.Sprintf("UPDATE %s SET email_verified_at = ? WHERE id = ? AND email_verified_at IS NULL", a.usersTable())),
+			time.Now().UTC(), userID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return userID, nil
+}
+
+// Check if a user has verified their email address.
+func (a *Auth) EmailVerified(ctx context.Context, userID int) (bool, error) {
+	var verifiedAt sql.NullTime
+	err := a.DB.Pool.QueryRowContext(ctx, database.Rebind(a.dialect(), fmt.Sprintf("SELECT email_verified_at FROM %s WHERE id = ?", a.usersTable())), userID).Scan(&verifiedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	return verifiedAt.Valid, err
+}
+
+// Verified redirects logged in users that have not verified their email
+// address to the NoticeURL of Verification. Guests are refused through
+// Forbidden, so the middleware belongs behind the application's auth check.
+func (a *Auth) Verified(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := a.userID(r)
+		if userID == 0 {
+			a.forbidden(w, r)
+			return
+		}
+
+		verified, err := a.EmailVerified(r.Context(), userID)
+		if err != nil {
+			a.logError("auth: check email verification:", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if !verified {
+			notice := "/"
+			if a.Verification != nil {
+				notice = a.Verification.NoticeURL
+			}
+			http.Redirect(w, r, notice, http.StatusSeeOther)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ResendVerification is a handler for the route an unverified user posts to
+// for a new verification link. The user is sent back to the NoticeURL with a
+// flash message; asking again within the ResendInterval is answered with 429
+// and a Retry-After header.
+func (a *Auth) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	if !a.Check(r) {
+		a.forbidden(w, r)
+		return
+	}
+
+	err := a.resendVerification(r, a.User(r))
+
+	var throttled *VerificationThrottledError
+	switch {
+	case err == nil:
+		a.Session.Put(r.Context(), "flash", "A new verification link has been sent to your email address.")
+	case errors.Is(err, EmailAlreadyVerifiedError):
+		a.Session.Put(r.Context(), "flash", "Your email address is already verified.")
+	case errors.As(err, &throttled):
+		w.Header().Set("Retry-After", strconv.Itoa(int(throttled.RetryAfter.Round(time.Second)/time.Second)))
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	default:
+		a.logError("auth: resend verification email:", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, a.Verification.NoticeURL, http.StatusSeeOther)
+}
+
+// Send a new verification link unless the user is verified or was sent one
+// within the ResendInterval.
+func (a *Auth) resendVerification(r *http.Request, user *User) error {
+	v := a.Verification
+	if v == nil {
+		return EmailVerificationDisabledError
+	}
+	if user == nil {
+		return errors.New("auth: resend verification email: no user")
+	}
+
+	verified, err := a.EmailVerified(r.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+	if verified {
+		return EmailAlreadyVerifiedError
+	}
+
+	if sent := a.Session.GetInt64(r.Context(), verificationSentKey); sent != 0 {
+		if wait := time.Until(time.Unix(sent, 0).Add(v.ResendInterval)); wait > 0 {
+			return &VerificationThrottledError{RetryAfter: wait}
+		}
+	}
+
+	if err := a.SendVerificationEmail(user); err != nil {
+		return err
+	}
+
+	a.Session.Put(r.Context(), verificationSentKey, time.Now().Unix())
+	return nil
+}
+
+// Get the id of the current user from a personal access token or the
+// session, or 0 for a guest.
+func (a *Auth) userID(r *http.Request) int {
+	if token := a.Token(r); token != nil {
+		return token.UserID
+	}
+
+	return a.Session.GetInt(r.Context(), "userID")
+}
+
+// The value a verification link carries to tie it to an email address.
+func emailHash(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alexedwards/scs/v2"
+	"github.com/cidekar/adele-framework/internal/bearer"
+)
+
+// A URLSigner appending a keyed checksum, standing in for urlsigner.Signer.
+type testSigner struct {
+	expired bool
+}
+
+func (s *testSigner) GenerateTokenFromString(data string) string {
+	return data + "&hash=" + bearer.Hash("secret"+data)
+}
+
+func (s *testSigner) VerifyToken(token string) bool {
+	data, hash, ok := strings.Cut(token, "&hash=")
+	return ok && hash == bearer.Hash("secret"+data)
+}
+
+func (s *testSigner) Expired(token string, minutesUntilExpired int) bool {
+	return s.expired
+}
+
+func newVerificationAuth(t *testing.T) (*Auth, sqlmock.Sqlmock, *[]string) {
+	a, mock := newTokenAuth(t, "postgres")
+	a.Session = scs.New()
+
+	var sent []string
+	a.Verification = &EmailVerification{
+		Signer: &testSigner{},
+		Send: func(user *User, link string) error {
+			sent = append(sent, link)
+			return nil
+		},
+		URL:            "https://app.test/",
+		Path:           "/email/verify/confirm",
+		NoticeURL:      "/email/verify",
+		Expiry:         time.Hour,
+		ResendInterval: time.Minute,
+	}
+	return a, mock, &sent
+}
+
+func TestVerifyEmail(t *testing.T) {
+	a, mock, sent := newVerificationAuth(t)
+
+	if err := a.SendVerificationEmail(&User{ID: 7, Email: "Me@Example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	link, _ := url.Parse((*sent)[0])
+	if !strings.HasPrefix(link.String(), "https://app.test/email/verify/confirm?id=7&email=") {
+		t.Fatalf("unexpected link %s", link)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT email, email_verified_at FROM users WHERE id = $1")).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "email_verified_at"}).AddRow("me@example.com", nil))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE users SET email_verified_at = $1 WHERE id = $2 AND email_verified_at IS NULL")).
+		WithArgs(sqlmock.AnyArg(), 7).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	userID, err := a.VerifyEmail(httptest.NewRequest(http.MethodGet, link.RequestURI(), nil))
+	if err != nil || userID != 7 {
+		t.Fatalf("expected user 7 to be verified, got %d %v", userID, err)
+	}
+
+	// The address changed since the link was sent.
+	mock.ExpectQuery("SELECT email, email_verified_at FROM users").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "email_verified_at"}).AddRow("new@example.com", nil))
+	if _, err := a.VerifyEmail(httptest.NewRequest(http.MethodGet, link.RequestURI(), nil)); !errors.Is(err, VerificationLinkInvalidError) {
+		t.Errorf("expected a link for an old address to be refused, got %v", err)
+	}
+
+	tampered := strings.Replace(link.RequestURI(), "id=7", "id=8", 1)
+	if _, err := a.VerifyEmail(httptest.NewRequest(http.MethodGet, tampered, nil)); !errors.Is(err, VerificationLinkInvalidError) {
+		t.Errorf("expected a tampered link to be refused, got %v", err)
+	}
+
+	a.Verification.Signer = &testSigner{expired: true}
+	if _, err := a.VerifyEmail(httptest.NewRequest(http.MethodGet, link.RequestURI(), nil)); !errors.Is(err, VerificationLinkExpiredError) {
+		t.Errorf("expected an expired link to be refused, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVerified_RedirectsUnverifiedUsers(t *testing.T) {
+	a, mock, _ := newVerificationAuth(t)
+
+	cookie := inSession(a, nil, func(w http.ResponseWriter, r *http.Request) {
+		a.Session.Put(r.Context(), "userID", 7)
+	})
+
+	h := a.Session.LoadAndSave(a.Verified(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+		r.AddCookie(cookie)
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	mock.ExpectQuery("SELECT email_verified_at FROM users").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"email_verified_at"}).AddRow(nil))
+	if w := serve(); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/email/verify" {
+		t.Errorf("expected a redirect to the notice, got %d %s", w.Code, w.Header().Get("Location"))
+	}
+
+	mock.ExpectQuery("SELECT email_verified_at FROM users").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"email_verified_at"}).AddRow(time.Now()))
+	if w := serve(); w.Code != http.StatusOK {
+		t.Errorf("expected a verified user to pass, got %d", w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected a guest to be refused, got %d", w.Code)
+	}
+}
+
+func TestResendVerification_Throttled(t *testing.T) {
+	a, mock, sent := newVerificationAuth(t)
+	user := &User{ID: 7, Email: "me@example.com"}
+
+	var cookie *http.Cookie
+	for i, want := range []bool{true, false} {
+		mock.ExpectQuery("SELECT email_verified_at FROM users").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"email_verified_at"}).AddRow(nil))
+
+		cookie = inSession(a, cookie, func(w http.ResponseWriter, r *http.Request) {
+			err := a.resendVerification(r, user)
+
+			var throttled *VerificationThrottledError
+			if want && err != nil {
+				t.Errorf("attempt %d: expected the email to be sent, got %v", i+1, err)
+			}
+			if !want && (!errors.As(err, &throttled) || throttled.RetryAfter <= 0) {
+				t.Errorf("attempt %d: expected the resend to be throttled, got %v", i+1, err)
+			}
+		})
+	}
+
+	if len(*sent) != 1 {
+		t.Errorf("expected one email, got %d", len(*sent))
+	}
+
+	mock.ExpectQuery("SELECT email_verified_at FROM users").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"email_verified_at"}).AddRow(time.Now()))
+	inSession(a, nil, func(w http.ResponseWriter, r *http.Request) {
+		if err := a.resendVerification(r, user); !errors.Is(err, EmailAlreadyVerifiedError) {
+			t.Errorf("expected EmailAlreadyVerifiedError, got %v", err)
+		}
+	})
+}
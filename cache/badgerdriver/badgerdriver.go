@@ -1,7 +1,9 @@
-// Package badgerdriver provides a Badger-backed embedded implementation of the framework's cache.Cache interface (Has/Get/Set/Forget/EmptyByMatch/Empty).
+// Package badgerdriver provides a Badger-backed embedded implementation of the framework's cache.Cache interface (Has/Get/Set/Forget/EmptyByMatch/Empty), counting atomically as a cache.Counter.
 package badgerdriver
 
 import (
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/cidekar/adele-framework/cache"
@@ -89,6 +91,39 @@ func (b *BadgerCache) Set(str string, value interface{}, expires ...int) error {
 	return nil
 }
 
+// Increment adds one to the counter stored under a key inside a Badger write transaction (Update),
+// sets the key to expire after expires seconds and returns the new count. A transaction that
+// conflicts with a concurrent increment is retried, so every increment is counted.
+func (b *BadgerCache) Increment(str string, expires int) (int, error) {
+	for {
+		var n int
+		err := b.Conn.Update(func(txn *badger.Txn) error {
+			n = 0
+			item, err := txn.Get([]byte(str))
+			if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+				return err
+			}
+			if err == nil {
+				err = item.Value(func(val []byte) error {
+					n, err = strconv.Atoi(string(val))
+					return err
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			n++
+			e := badger.NewEntry([]byte(str), []byte(strconv.Itoa(n))).WithTTL(time.Second * time.Duration(expires))
+			return txn.SetEntry(e)
+		})
+		if errors.Is(err, badger.ErrConflict) {
+			continue
+		}
+		return n, err
+	}
+}
+
 // Forget deletes a single key from the cache inside a Badger write transaction (Update).
 func (b *BadgerCache) Forget(str string) error {
 	err := b.Conn.Update(func(txn *badger.Txn) error {
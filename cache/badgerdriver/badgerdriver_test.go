@@ -1,6 +1,9 @@
 package badgerdriver
 
-import "testing"
+import (
+	"sync"
+	"testing"
+)
 
 func TestBadgerCache_Has(t *testing.T) {
 	err := testBadgerCache.Forget("foo")
@@ -140,3 +143,29 @@ func TestBadgerCache_EmptyByMatch(t *testing.T) {
 		t.Error("beta not found in cache, and it should be there")
 	}
 }
+
+func TestBadgerCache_Increment(t *testing.T) {
+	_ = testBadgerCache.Forget("counter")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := testBadgerCache.Increment("counter", 60); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	n, err := testBadgerCache.Increment("counter", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 51 {
+		t.Errorf("expected every concurrent increment to be counted, got %d", n)
+	}
+
+	_ = testBadgerCache.Forget("counter")
+}
@@ -9,25 +9,33 @@ package cache
 
 import (
 	"encoding/json"
-	"os"
+
+	"github.com/cidekar/adele-framework/config"
 )
 
 // Checks if any framework service is configured to use Badger.
-// Returns true if the CACHE environment variable
+// Returns true if CACHE is badger in the .env file of the working
+// directory or the environment.
 func UsesBadger() bool {
-	return os.Getenv("CACHE") == "badger"
+	var c config.Cache
+	if err := config.Load(".", &c); err != nil {
+		return false
+	}
+	return c.Driver == "badger"
 }
 
 // Checks if any framework service is configured to use Redis.
-// Returns true if any of the CACHE, SESSION_TYPE, or QUEUE_TYPE environment variable
+// Returns true if any of CACHE, SESSION_TYPE or QUEUE_TYPE is redis in the
+// .env file of the working directory or the environment. An unreadable
+// .env file configures neither service.
 func UsesRedis() bool {
-	redisServices := []string{"CACHE", "SESSION_TYPE", "QUEUE_TYPE"}
-	for _, service := range redisServices {
-		if os.Getenv(service) == "redis" {
-			return true
-		}
+	var c config.Cache
+	var s config.Session
+	var q config.Queue
+	if err := config.Load(".", &c, &s, &q); err != nil {
+		return false
 	}
-	return false
+	return c.Driver == "redis" || s.Type == "redis" || q.Type == "redis"
 }
 
 // Encode serializes a cache Entry (map[string]interface{}) into JSON bytes for storage.
@@ -1,6 +1,7 @@
 package redisdriver
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/cidekar/adele-framework/cache"
@@ -159,3 +160,29 @@ func TestRedisCache_Decode(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestRedisCache_Increment(t *testing.T) {
+	_ = testRedisCache.Forget("counter")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := testRedisCache.Increment("counter", 60); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	n, err := testRedisCache.Increment("counter", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 51 {
+		t.Errorf("expected every concurrent increment to be counted, got %d", n)
+	}
+
+	_ = testRedisCache.Forget("counter")
+}
@@ -1,4 +1,4 @@
-// Package redisdriver provides a Redis-backed implementation of the framework's cache.Cache interface (Has/Get/Set/Forget/EmptyByMatch/Empty).
+// Package redisdriver provides a Redis-backed implementation of the framework's cache.Cache interface (Has/Get/Set/Forget/EmptyByMatch/Empty), counting atomically as a cache.Counter.
 package redisdriver
 
 import (
@@ -88,6 +88,24 @@ func (c *RedisCache) Set(str string, value interface{}, expires ...int) error {
 	return nil
 }
 
+// Count a key up and set its expiry in one step. KEYS: counter. ARGV: expiry
+// in seconds.
+var incrementScript = redis.NewScript(1, `
+local n = redis.call('incr', KEYS[1])
+redis.call('expire', KEYS[1], ARGV[1])
+return n
+`)
+
+// Increment atomically adds one to the counter stored under the prefixed key with
+// INCR, sets the key to expire after expires seconds and returns the new count.
+func (c *RedisCache) Increment(str string, expires int) (int, error) {
+	key := fmt.Sprintf("%s:%s", c.Prefix, str)
+	conn := c.Conn.Get()
+	defer conn.Close()
+
+	return redis.Int(incrementScript.Do(conn, key, expires))
+}
+
 // Forget deletes the single prefixed key from Redis using the DEL command.
 func (c *RedisCache) Forget(str string) error {
 	key := fmt.Sprintf("%s:%s", c.Prefix, str)
@@ -9,4 +9,13 @@ type Cache interface {
 	Empty() error
 }
 
+// A Counter is a Cache that counts atomically, so concurrent increments of the
+// same key are all counted. Increment adds one to the counter stored under key,
+// which then expires expires seconds later, and returns the new count. A
+// counter is read back through Increment only, not through Get.
+type Counter interface {
+	Cache
+	Increment(key string, expires int) (int, error)
+}
+
 type Entry map[string]interface{}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+)
+
+// runAppCommand builds the application in the current directory into a
+// temporary binary and runs it with args, so commands such as queue:work,
+// schedule:list and route:list see the jobs, tasks and routes the application
+// registers. The binary handles them through Adele.RunCommand. SIGINT and
+// SIGTERM are forwarded to the application, which decides how to wind down, and
+// the CLI waits for it to exit.
+func runAppCommand(args ...string) error {
+	dir, err := os.MkdirTemp("", "adele-app-")
+	if err != nil {
+		return fmt.Errorf("create build dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	bin := filepath.Join(dir, "app")
+	build := exec.Command("go", "build", "-o", bin, ".")
+	build.Stdout = os.Stdout
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		return fmt.Errorf("build application: %w", err)
+	}
+
+	app := exec.Command(bin, args...)
+	app.Stdin = os.Stdin
+	app.Stdout = os.Stdout
+	app.Stderr = os.Stderr
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	if err := app.Start(); err != nil {
+		return fmt.Errorf("start application: %w", err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				_ = app.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	if err := app.Wait(); err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+
+	return nil
+}
@@ -11,9 +11,9 @@ import (
 var InstallCommand = &Command{
 	Name:        "install",
 	Help:        "Install a kit into the current project",
-	Description: "Install a packaged kit (such as a frontend pipeline) or generate a new application key into the current working directory",
+	Description: "Install a packaged kit (such as a frontend pipeline), the sessions, queue, schedule lock, access token, OAuth, roles, two-factor, email verification, remember token or session device migrations, or generate a new application key into the current working directory",
 	Usage:       "adele install <kit> [options]",
-	Examples: []string{
+	Examples: append([]string{
 		"adele install starter-kit",
 		"adele install starter-kit --skip",
 		"adele install starter-kit --no-tailwind",
@@ -24,7 +24,8 @@ var InstallCommand = &Command{
 		"adele install starter-kit --vue3 --with-auth",
 		"adele install key",
 		"adele install key --force",
-	},
+		"adele install sessions --mysql",
+	}, migrationKitExamples()...),
 	Options: map[string]string{
 		"--skip":        "keep your existing templates; you must wire up the toolchain manually",
 		"--no-tailwind": "skip Tailwind CSS scaffolding (default: included)",
@@ -33,6 +34,8 @@ var InstallCommand = &Command{
 		"--vue3":        "alias for --vue=3",
 		"--with-auth":   "scaffold a working password-auth flow (vanilla or vue3)",
 		"--force":       "(key only) overwrite an existing KEY value without prompting",
+		"--postgres":    "(" + strings.Join(migrationKitNames(), ", ") + ") install the postgres migration regardless of DATABASE_TYPE",
+		"--mysql":       "(" + strings.Join(migrationKitNames(), ", ") + ") install the mysql migration regardless of DATABASE_TYPE",
 	},
 }
 
@@ -45,10 +48,14 @@ func NewInstall() *Install {
 func (c *Install) Handle() error {
 	args := Registry.GetArgs()
 	if len(args) < 2 {
-		return fmt.Errorf("missing kit name (available: starter-kit, key)\nusage: %s", InstallCommand.Usage)
+		return fmt.Errorf("missing kit name (available: %s)\nusage: %s", installKitNames(), InstallCommand.Usage)
 	}
 
 	kit := args[1]
+	if _, ok := findMigrationKit(kit); ok {
+		return NewInstallMigration(kit, dialectOption()).Handle()
+	}
+
 	switch kit {
 	case "key":
 		return NewInstallKey(HasOption("--force")).Handle()
@@ -75,8 +82,33 @@ func (c *Install) Handle() error {
 		// remove?" gate avoids friction on the empty target.
 		return NewStarterKit(variant, skip, withTailwind, justScaffolded, withAuth).Handle()
 	default:
-		return fmt.Errorf("unknown kit %q (available: starter-kit, key)", kit)
+		return fmt.Errorf("unknown kit %q (available: %s)", kit, installKitNames())
+	}
+}
+
+// installKitNames lists every kit `adele install` accepts.
+func installKitNames() string {
+	return strings.Join(append([]string{"starter-kit", "key"}, migrationKitNames()...), ", ")
+}
+
+// migrationKitExamples returns an `adele install <kit>` example per migration kit.
+func migrationKitExamples() []string {
+	examples := make([]string, 0, len(migrationKits))
+	for _, kit := range migrationKitNames() {
+		examples = append(examples, "adele install "+kit)
+	}
+	return examples
+}
+
+// dialectOption returns the migration dialect forced with --postgres or --mysql,
+// or an empty string to fall back to DATABASE_TYPE.
+func dialectOption() string {
+	if HasOption("--postgres") {
+		return "postgres"
+	} else if HasOption("--mysql") {
+		return "mysql"
 	}
+	return ""
 }
 
 // ensureAdeleApp gates `adele install starter-kit` on being inside an adele
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// migrationVersion matches the leading numeric version of a golang-migrate file.
+var migrationVersion = regexp.MustCompile(`^(\d+)_`)
+
+// migrationKit is a kit installed by copying a migration shipped under
+// templates/migrations/<dir> for the application's database dialect into
+// ./migrations, numbered after the newest existing migration, together with
+// any templates the application is expected to restyle.
+type migrationKit struct {
+	kit   string
+	dir   string
+	name  string
+	help  string
+	files []kitFile
+}
+
+// kitFile is a template copied along with a migration. An existing target is
+// kept, so re-running an install never overwrites a restyled file.
+type kitFile struct {
+	source string
+	target string
+}
+
+// migrationKits are the kits of `adele install <kit>` that install a
+// migration, in the order they are listed by the help. The name is the
+// golang-migrate name of the installed files, e.g.
+// 0004_create_jobs_table.up.sql, and help is printed once they are written.
+var migrationKits = []migrationKit{
+	{
+		kit:  "sessions",
+		dir:  "sessions",
+		name: "create_sessions_table",
+		help: "Run `adele migrate up` to create the sessions table, and `adele install session-devices` to track the devices sessions are used from.",
+	},
+	{
+		kit:  "queue",
+		dir:  "jobs",
+		name: "create_jobs_table",
+		help: "Run `adele migrate up` to create the jobs and failed_jobs tables. The SQL driver reserves jobs with FOR UPDATE SKIP LOCKED, which needs PostgreSQL 9.5, MySQL 8.0 or MariaDB 10.6 or later.",
+	},
+	{
+		kit:  "schedule",
+		dir:  "schedule",
+		name: "create_schedule_locks_table",
+		help: "Run `adele migrate up` to create the schedule_locks table.",
+	},
+	{
+		kit:  "tokens",
+		dir:  "tokens",
+		name: "create_personal_access_tokens_table",
+		help: "Run `adele migrate up` to create the personal_access_tokens table.",
+	},
+	{
+		kit:  "oauth",
+		dir:  "oauth",
+		name: "create_oauth_tables",
+		help: "Run `adele migrate up` to create the oauth tables, then mount the server's routes, e.g. a.App.Routes.Mount(\"/oauth\", a.App.OAuth.Routes()).",
+		// The consent page matches the default OAUTH_CONSENT_VIEW of
+		// oauth/authorize under resources/views.
+		files: []kitFile{
+			{source: "templates/oauth/authorize.jet", target: filepath.Join("resources", "views", "oauth", "authorize.jet")},
+		},
+	},
+	{
+		kit:  "roles",
+		dir:  "roles",
+		name: "create_roles_tables",
+		help: "Run `adele migrate up` to create the roles and permissions tables.",
+	},
+	{
+		kit:  "two-factor",
+		dir:  "twofactor",
+		name: "create_two_factor_tables",
+		help: "Run `adele migrate up` to create the two-factor tables, then set AUTH_TWO_FACTOR=true.",
+	},
+	{
+		kit:  "email-verification",
+		dir:  "verification",
+		name: "add_email_verified_at_to_users",
+		help: "Run `adele migrate up` to add the email_verified_at column, then route AUTH_VERIFY_PATH to a handler calling a.App.Auth.VerifyEmail.",
+		// The mailer looks in resources/mail for the template named by
+		// Auth.SendVerificationEmail.
+		files: []kitFile{
+			{source: "templates/mail/verify-email.html.jet", target: filepath.Join("resources", "mail", "verify-email.html.jet")},
+			{source: "templates/mail/verify-email.plain.jet", target: filepath.Join("resources", "mail", "verify-email.plain.jet")},
+		},
+	},
+	{
+		// The migration deletes the tokens written before the selector
+		// column, so every user is asked to log in once more.
+		kit:  "remember-tokens",
+		dir:  "remember",
+		name: "add_selector_to_remember_tokens",
+		help: "Run `adele migrate up` to add the selector column, then replace your CheckRemember middleware with a.App.Auth.RememberMiddleware.",
+	},
+	{
+		kit:  "session-devices",
+		dir:  "sessiondevices",
+		name: "add_device_columns_to_sessions",
+		help: "Run `adele migrate up` to add the device columns, then add a.App.Auth.TrackSessions to your web routes.",
+	},
+}
+
+// findMigrationKit returns the migration kit installed by `adele install kit`.
+func findMigrationKit(kit string) (migrationKit, bool) {
+	for _, k := range migrationKits {
+		if k.kit == kit {
+			return k, true
+		}
+	}
+	return migrationKit{}, false
+}
+
+// migrationKitNames returns the kit names of migrationKits in order.
+func migrationKitNames() []string {
+	names := make([]string, 0, len(migrationKits))
+	for _, k := range migrationKits {
+		names = append(names, k.kit)
+	}
+	return names
+}
+
+// InstallMigration copies the migration of a migration kit for the
+// application's database dialect into ./migrations, along with the templates
+// the kit ships. Apply the migration with `adele migrate up`.
+//
+// The dialect is taken from --postgres or --mysql when given, otherwise from
+// DATABASE_TYPE in .env or the environment.
+type InstallMigration struct {
+	Kit     string
+	Dialect string
+}
+
+func NewInstallMigration(kit, dialect string) *InstallMigration {
+	return &InstallMigration{Kit: kit, Dialect: dialect}
+}
+
+func (c *InstallMigration) Handle() error {
+	kit, ok := findMigrationKit(c.Kit)
+	if !ok {
+		return fmt.Errorf("unknown migration kit %q (available: %s)", c.Kit, strings.Join(migrationKitNames(), ", "))
+	}
+
+	if !IsAdeleApp() {
+		return fmt.Errorf("adele install %s must be run from the root of an adele application (no go.mod referencing the framework)", kit.kit)
+	}
+
+	dialect, err := resolveMigrationDialect(c.Dialect)
+	if err != nil {
+		return err
+	}
+
+	if err := installMigration(kit.dir, kit.name, dialect); err != nil {
+		return err
+	}
+
+	for _, file := range kit.files {
+		if fileExists(file.target) {
+			color.Yellow("Keeping the existing %s", file.target)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(file.target), 0755); err != nil {
+			return fmt.Errorf("create %s: %w", filepath.Dir(file.target), err)
+		}
+		if err := copyFileFromTemplate(file.source, file.target); err != nil {
+			return fmt.Errorf("write %s: %w", file.target, err)
+		}
+		color.Green("Created %s", file.target)
+	}
+
+	fmt.Println(kit.help)
+	return nil
+}
+
+// resolveMigrationDialect maps a flag or DATABASE_TYPE value onto one of the
+// shipped migration dialects.
+func resolveMigrationDialect(dialect string) (string, error) {
+	if dialect == "" {
+		dialect = os.Getenv("DATABASE_TYPE")
+		if value, err := readEnvValue(keyEnvFile, "DATABASE_TYPE"); err == nil && value != "" {
+			dialect = value
+		}
+	}
+
+	switch dialect {
+	case "postgres", "postgresql", "pgx":
+		return "postgres", nil
+	case "mysql", "mariadb":
+		return "mysql", nil
+	case "":
+		return "", errors.New("DATABASE_TYPE is not set in .env; pass --postgres or --mysql")
+	default:
+		return "", fmt.Errorf("unsupported DATABASE_TYPE %q (expected postgres or mysql)", dialect)
+	}
+}
+
+// installMigration copies the up and down migrations shipped under
+// templates/migrations/<kit> for dialect into ./migrations, named
+// <version>_<name> and numbered after the newest existing migration.
+func installMigration(kit, name, dialect string) error {
+	if err := os.MkdirAll("migrations", 0755); err != nil {
+		return fmt.Errorf("create migrations dir: %w", err)
+	}
+
+	version, err := nextMigrationVersion("migrations", name)
+	if err != nil {
+		return err
+	}
+
+	for _, direction := range []string{"up", "down"} {
+		target := filepath.Join("migrations", fmt.Sprintf("%04d_%s.%s.sql", version, name, direction))
+		source := fmt.Sprintf("templates/migrations/%s/%s.%s.sql", kit, dialect, direction)
+		if err := copyFileFromTemplate(source, target); err != nil {
+			return fmt.Errorf("write %s: %w", target, err)
+		}
+		color.Green("Created %s", target)
+	}
+
+	return nil
+}
+
+// nextMigrationVersion returns one past the highest version found in dir, or 1
+// for an empty directory. A migration with the same name already present is an
+// error so re-running an install never creates a second table definition.
+func nextMigrationVersion(dir, name string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("read %s: %w", dir, err)
+	}
+
+	highest := 0
+	for _, entry := range entries {
+		match := migrationVersion.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		if strings.Contains(entry.Name(), "_"+name+".") {
+			return 0, fmt.Errorf("a %s migration already exists: %s", name, filepath.Join(dir, entry.Name()))
+		}
+		if v, err := strconv.Atoi(match[1]); err == nil && v > highest {
+			highest = v
+		}
+	}
+
+	return highest + 1, nil
+}
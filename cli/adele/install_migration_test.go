@@ -0,0 +1,252 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallMigration_Kits(t *testing.T) {
+	tests := []struct {
+		kit     string
+		dialect string
+		name    string
+		want    []string
+		files   map[string]string
+	}{
+		{
+			kit:     "sessions",
+			dialect: "postgres",
+			name:    "create_sessions_table",
+			want:    []string{"CREATE TABLE sessions", "BYTEA"},
+		},
+		{
+			kit:     "queue",
+			dialect: "mysql",
+			name:    "create_jobs_table",
+			want:    []string{"CREATE TABLE jobs", "CREATE TABLE failed_jobs", "LONGBLOB"},
+		},
+		{
+			kit:     "schedule",
+			dialect: "postgres",
+			name:    "create_schedule_locks_table",
+			want:    []string{"CREATE TABLE schedule_locks"},
+		},
+		{
+			kit:     "tokens",
+			dialect: "postgres",
+			name:    "create_personal_access_tokens_table",
+			want:    []string{"CREATE TABLE personal_access_tokens", "token CHAR(64) NOT NULL UNIQUE", "TIMESTAMPTZ"},
+		},
+		{
+			kit:     "oauth",
+			dialect: "mysql",
+			name:    "create_oauth_tables",
+			want:    []string{"CREATE TABLE oauth_clients", "CREATE TABLE oauth_refresh_tokens", "FOREIGN KEY (client_id)"},
+			files: map[string]string{
+				filepath.Join("resources", "views", "oauth", "authorize.jet"): `name="approve" value="yes"`,
+			},
+		},
+		{
+			kit:     "roles",
+			dialect: "mysql",
+			name:    "create_roles_tables",
+			want:    []string{"CREATE TABLE roles", "CREATE TABLE permissions", "CREATE TABLE role_permissions", "CREATE TABLE user_roles", "AUTO_INCREMENT"},
+		},
+		{
+			kit:     "two-factor",
+			dialect: "postgres",
+			name:    "create_two_factor_tables",
+			want:    []string{"CREATE TABLE two_factor_credentials", "CREATE TABLE two_factor_recovery_codes", "code CHAR(64) NOT NULL"},
+		},
+		{
+			kit:     "email-verification",
+			dialect: "postgres",
+			name:    "add_email_verified_at_to_users",
+			want:    []string{"ADD COLUMN email_verified_at TIMESTAMPTZ NULL"},
+			files: map[string]string{
+				filepath.Join("resources", "mail", "verify-email.html.jet"):  "{{ data.Link }}",
+				filepath.Join("resources", "mail", "verify-email.plain.jet"): "",
+			},
+		},
+		{
+			kit:     "remember-tokens",
+			dialect: "mysql",
+			name:    "add_selector_to_remember_tokens",
+			want:    []string{"ADD COLUMN selector", "DELETE FROM remember_tokens"},
+		},
+		{
+			kit:     "session-devices",
+			dialect: "postgres",
+			name:    "add_device_columns_to_sessions",
+			want:    []string{"ADD COLUMN user_id", "last_activity TIMESTAMPTZ"},
+		},
+	}
+
+	if len(tests) != len(migrationKits) {
+		t.Fatalf("expected a case for each of the %d migration kits, got %d", len(migrationKits), len(tests))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kit, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			seedAdeleApp(t)
+
+			if err := NewInstallMigration(tt.kit, tt.dialect).Handle(); err != nil {
+				t.Fatalf("Handle() error: %v", err)
+			}
+
+			up, err := os.ReadFile("migrations/0001_" + tt.name + ".up.sql")
+			if err != nil {
+				t.Fatalf("read up migration: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(up), want) {
+					t.Errorf("expected the %s migration to contain %q, got: %s", tt.dialect, want, up)
+				}
+			}
+			if !fileExists("migrations/0001_" + tt.name + ".down.sql") {
+				t.Error("expected the down migration to be written")
+			}
+
+			for target, want := range tt.files {
+				content, err := os.ReadFile(target)
+				if err != nil {
+					t.Fatalf("read %s: %v", target, err)
+				}
+				if !strings.Contains(string(content), want) {
+					t.Errorf("expected %s to contain %q, got: %s", target, want, content)
+				}
+			}
+
+			err = NewInstallMigration(tt.kit, tt.dialect).Handle()
+			if err == nil || !strings.Contains(err.Error(), "already exists") {
+				t.Errorf("expected a second install to be refused, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestInstallMigration_UnknownKit(t *testing.T) {
+	t.Chdir(t.TempDir())
+	seedAdeleApp(t)
+
+	err := NewInstallMigration("bogus", "postgres").Handle()
+	if err == nil || !strings.Contains(err.Error(), "bogus") {
+		t.Errorf("expected an unknown kit to be refused, got: %v", err)
+	}
+}
+
+func TestInstallMigration_NotAdeleApp(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	err := NewInstallMigration("sessions", "postgres").Handle()
+	if err == nil {
+		t.Fatal("expected error outside an adele application")
+	}
+}
+
+func TestInstallMigration_KeepsExistingFiles(t *testing.T) {
+	t.Chdir(t.TempDir())
+	seedAdeleApp(t)
+	view := filepath.Join("resources", "views", "oauth", "authorize.jet")
+	if err := os.MkdirAll(filepath.Dir(view), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(view, []byte("restyled"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewInstallMigration("oauth", "postgres").Handle(); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	content, err := os.ReadFile(view)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "restyled" {
+		t.Errorf("expected the restyled consent view to be kept, got: %s", content)
+	}
+}
+
+func TestInstallMigration_DialectFromEnvFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	seedAdeleApp(t)
+	if err := os.WriteFile(".env", []byte("DATABASE_TYPE=mariadb\n"), 0644); err != nil {
+		t.Fatalf("seed .env: %v", err)
+	}
+
+	if err := NewInstallMigration("sessions", "").Handle(); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	up, err := os.ReadFile("migrations/0001_create_sessions_table.up.sql")
+	if err != nil {
+		t.Fatalf("read up migration: %v", err)
+	}
+	if !strings.Contains(string(up), "BLOB") {
+		t.Errorf("expected the mysql migration, got: %s", up)
+	}
+}
+
+func TestInstallMigration_NumbersAfterExistingMigrations(t *testing.T) {
+	t.Chdir(t.TempDir())
+	seedAdeleApp(t)
+	if err := os.MkdirAll("migrations", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"0001_create_users_table.up.sql", "0002_create_remember_tokens_table.up.sql"} {
+		if err := os.WriteFile("migrations/"+f, []byte("--"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := NewInstallMigration("queue", "postgres").Handle(); err != nil {
+		t.Fatalf("Handle() error: %v", err)
+	}
+
+	if !fileExists("migrations/0003_create_jobs_table.up.sql") {
+		t.Error("expected the migration to be numbered after the existing ones")
+	}
+}
+
+func TestInstallMigration_MissingDialect(t *testing.T) {
+	t.Chdir(t.TempDir())
+	seedAdeleApp(t)
+	t.Setenv("DATABASE_TYPE", "")
+
+	err := NewInstallMigration("sessions", "").Handle()
+	if err == nil || !strings.Contains(err.Error(), "DATABASE_TYPE") {
+		t.Errorf("expected error about DATABASE_TYPE, got: %v", err)
+	}
+}
+
+func TestInstallMigration_SessionDevicesAfterSessions(t *testing.T) {
+	t.Chdir(t.TempDir())
+	seedAdeleApp(t)
+
+	for _, kit := range []string{"sessions", "session-devices"} {
+		if err := NewInstallMigration(kit, "postgres").Handle(); err != nil {
+			t.Fatalf("install %s: %v", kit, err)
+		}
+	}
+
+	sessions, err := os.ReadFile("migrations/0001_create_sessions_table.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	devices, err := os.ReadFile("migrations/0002_add_device_columns_to_sessions.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, column := range []string{"user_id", "ip_address", "user_agent", "last_activity"} {
+		if strings.Contains(string(sessions), column) {
+			t.Errorf("expected the %s column to be left to the session-devices kit", column)
+		}
+		if !strings.Contains(string(devices), "ADD COLUMN "+column) {
+			t.Errorf("expected the session-devices kit to add the %s column", column)
+		}
+	}
+}
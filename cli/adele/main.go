@@ -1,6 +1,7 @@
 // Command adele is the command-line tool for scaffolding and managing Adele
 // framework projects, providing subcommands to create new projects, install
-// components, run database migrations, and report the framework version.
+// components, run database migrations, run queue workers and manage failed jobs,
+// list scheduled tasks, and report the framework version.
 package main
 
 import (
@@ -106,6 +107,48 @@ func (c *Cli) Handle() error {
 		if err != nil {
 			return err
 		}
+
+	case "queue:work":
+		c := NewQueueWork()
+		err := c.Handle()
+		if err != nil {
+			return err
+		}
+
+	case "queue:failed":
+		c := NewQueueFailed()
+		err := c.Handle()
+		if err != nil {
+			return err
+		}
+
+	case "queue:retry":
+		c := NewQueueRetry()
+		err := c.Handle()
+		if err != nil {
+			return err
+		}
+
+	case "queue:flush":
+		c := NewQueueFlush()
+		err := c.Handle()
+		if err != nil {
+			return err
+		}
+
+	case "schedule:list":
+		c := NewScheduleList()
+		err := c.Handle()
+		if err != nil {
+			return err
+		}
+
+	case "route:list":
+		c := NewRouteList()
+		err := c.Handle()
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
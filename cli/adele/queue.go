@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	adele "github.com/cidekar/adele-framework"
+	"github.com/cidekar/adele-framework/config"
+	"github.com/fatih/color"
+)
+
+var QueueWorkCommand = &Command{
+	Name:        "queue:work",
+	Help:        "Run a queue worker",
+	Description: "Build the application and run it as a queue worker that processes jobs until stopped; SIGINT or SIGTERM lets the jobs in flight finish first",
+	Usage:       "adele queue:work [options]",
+	Examples: []string{
+		"adele queue:work",
+		"adele queue:work --queue=emails,default",
+		"adele queue:work --concurrency=4",
+		"adele queue:work --max-jobs=1000 --max-time=3600",
+	},
+	Options: map[string]string{
+		"--queue":       "comma separated queues to work, highest priority first (default: QUEUE_DEFAULT)",
+		"--concurrency": "number of jobs to run at once (default: QUEUE_CONCURRENCY)",
+		"--max-jobs":    "stop after this many jobs so a process manager can restart the worker",
+		"--max-time":    "stop after this many seconds so a process manager can restart the worker",
+	},
+}
+
+var QueueFailedCommand = &Command{
+	Name:        "queue:failed",
+	Help:        "List failed jobs",
+	Description: "List the jobs that exhausted their attempts, oldest first",
+	Usage:       "adele queue:failed",
+	Examples: []string{
+		"adele queue:failed",
+	},
+}
+
+var QueueRetryCommand = &Command{
+	Name:        "queue:retry",
+	Help:        "Retry failed jobs",
+	Description: "Push one failed job, or every failed job, back onto its queue with its attempts reset",
+	Usage:       "adele queue:retry <id|all>",
+	Examples: []string{
+		"adele queue:retry 5f2b9c0e4d6a4e1c8b3f7a9d2e1c0b4a",
+		"adele queue:retry all",
+	},
+}
+
+var QueueFlushCommand = &Command{
+	Name:        "queue:flush",
+	Help:        "Delete all failed jobs",
+	Description: "Remove every job from the failed jobs store",
+	Usage:       "adele queue:flush",
+	Examples: []string{
+		"adele queue:flush",
+	},
+}
+
+type QueueWork struct{}
+
+func NewQueueWork() *QueueWork {
+	return &QueueWork{}
+}
+
+// Handle runs the application as a queue worker. SIGINT and SIGTERM are passed
+// on so the worker can finish the jobs in flight before exiting.
+func (c *QueueWork) Handle() error {
+	if !IsAdeleApp() {
+		return errors.New("adele queue:work must be run from the root of an adele application (no go.mod referencing the framework)")
+	}
+
+	return runAppCommand(append([]string{"queue:work"}, os.Args[2:]...)...)
+}
+
+type QueueFailed struct{}
+
+func NewQueueFailed() *QueueFailed {
+	return &QueueFailed{}
+}
+
+func (c *QueueFailed) Handle() error {
+	app, err := openQueue()
+	if err != nil {
+		return err
+	}
+	defer app.Shutdown(context.Background())
+
+	jobs, err := app.Queue.Failed(context.Background())
+	if err != nil {
+		return fmt.Errorf("list failed jobs: %w", err)
+	}
+
+	if len(jobs) == 0 {
+		color.Green("No failed jobs.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tQUEUE\tJOB\tFAILED AT\tERROR")
+	for _, job := range jobs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			job.Envelope.ID,
+			job.Envelope.Queue,
+			job.Envelope.Name,
+			job.FailedAt.Local().Format("2006-01-02 15:04:05"),
+			firstLine(job.Error),
+		)
+	}
+	return w.Flush()
+}
+
+type QueueRetry struct{}
+
+func NewQueueRetry() *QueueRetry {
+	return &QueueRetry{}
+}
+
+func (c *QueueRetry) Handle() error {
+	args := Registry.GetArgs()
+	if len(args) < 2 {
+		return fmt.Errorf("missing failed job id (or all)\nusage: %s", QueueRetryCommand.Usage)
+	}
+
+	app, err := openQueue()
+	if err != nil {
+		return err
+	}
+	defer app.Shutdown(context.Background())
+
+	if args[1] == "all" {
+		n, err := app.Queue.RetryAll(context.Background())
+		if err != nil {
+			return fmt.Errorf("retry failed jobs: %w", err)
+		}
+		color.Green("Pushed %d failed jobs back onto their queues.", n)
+		return nil
+	}
+
+	if err := app.Queue.Retry(context.Background(), args[1]); err != nil {
+		return fmt.Errorf("retry job %s: %w", args[1], err)
+	}
+	color.Green("Pushed job %s back onto its queue.", args[1])
+	return nil
+}
+
+type QueueFlush struct{}
+
+func NewQueueFlush() *QueueFlush {
+	return &QueueFlush{}
+}
+
+func (c *QueueFlush) Handle() error {
+	app, err := openQueue()
+	if err != nil {
+		return err
+	}
+	defer app.Shutdown(context.Background())
+
+	if err := app.Queue.Flush(context.Background()); err != nil {
+		return fmt.Errorf("flush failed jobs: %w", err)
+	}
+	color.Green("All failed jobs deleted.")
+	return nil
+}
+
+// openQueue connects to the application's queue driver from its configuration.
+// Listing, retrying and flushing failed jobs only touch stored envelopes, so
+// unlike queue:work they do not need the application's job types.
+func openQueue() (*adele.Adele, error) {
+	if !IsAdeleApp() {
+		return nil, errors.New("adele queue commands must be run from the root of an adele application (no go.mod referencing the framework)")
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("getwd: %w", err)
+	}
+
+	cfg, err := config.New(cwd)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(cfg.Queue.Type) {
+	case "redis", "database":
+	default:
+		return nil, fmt.Errorf("QUEUE_TYPE=%s keeps jobs inside the web process; the queue commands need QUEUE_TYPE set to redis or database", cfg.Queue.Type)
+	}
+
+	app := &adele.Adele{RootPath: cwd, Config: cfg}
+	if strings.EqualFold(cfg.Queue.Type, "database") {
+		if err := app.BootstrapDatabase(); err != nil {
+			return nil, fmt.Errorf("connect to database: %w", err)
+		}
+	}
+	if err := app.BootstrapQueue(); err != nil {
+		return nil, err
+	}
+
+	return app, nil
+}
+
+// firstLine returns the first line of a possibly multi-line error message.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+func init() {
+	for _, cmd := range []*Command{QueueWorkCommand, QueueFailedCommand, QueueRetryCommand, QueueFlushCommand} {
+		if err := Registry.Register(cmd); err != nil {
+			panic(fmt.Sprintf("Failed to register %s command: %v", cmd.Name, err))
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestQueueCommands_Registration(t *testing.T) {
+	for _, want := range []*Command{QueueWorkCommand, QueueFailedCommand, QueueRetryCommand, QueueFlushCommand} {
+		cmd, exists := Registry.GetCommand(want.Name)
+		if !exists {
+			t.Errorf("Expected %q command to be registered in Registry", want.Name)
+			continue
+		}
+		if cmd != want {
+			t.Errorf("Expected Registry's %q command to be the registered value", want.Name)
+		}
+	}
+}
+
+func TestQueueWork_NotAdeleApp(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	err := NewQueueWork().Handle()
+	if err == nil || !strings.Contains(err.Error(), "root of an adele application") {
+		t.Errorf("expected error outside an adele application, got: %v", err)
+	}
+}
+
+func TestQueueRetry_MissingID(t *testing.T) {
+	originalArgs := Registry.GetArgs()
+	defer Registry.SetArgs(originalArgs)
+
+	Registry.SetArgs([]string{"queue:retry"})
+
+	err := NewQueueRetry().Handle()
+	if err == nil || !strings.Contains(err.Error(), "missing failed job id") {
+		t.Errorf("expected missing id error, got: %v", err)
+	}
+}
+
+func TestOpenQueue_RequiresSharedDriver(t *testing.T) {
+	t.Chdir(t.TempDir())
+	seedAdeleApp(t)
+	t.Setenv("QUEUE_TYPE", "")
+	if err := os.WriteFile(".env", []byte("QUEUE_TYPE=memory\n"), 0644); err != nil {
+		t.Fatalf("seed .env: %v", err)
+	}
+
+	_, err := openQueue()
+	if err == nil || !strings.Contains(err.Error(), "redis or database") {
+		t.Errorf("expected the memory driver to be refused, got: %v", err)
+	}
+}
+
+func TestOpenQueue_Redis(t *testing.T) {
+	t.Chdir(t.TempDir())
+	seedAdeleApp(t)
+	if err := os.WriteFile(".env", []byte("QUEUE_TYPE=redis\nREDIS_HOST=127.0.0.1\n"), 0644); err != nil {
+		t.Fatalf("seed .env: %v", err)
+	}
+
+	app, err := openQueue()
+	if err != nil {
+		t.Fatalf("openQueue() error: %v", err)
+	}
+	if app.Queue == nil || app.RedisPool == nil {
+		t.Error("expected the redis queue to be booted")
+	}
+}
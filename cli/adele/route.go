@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var RouteListCommand = &Command{
+	Name:        "route:list",
+	Help:        "List routes",
+	Description: "Build the application and list the routes it serves with their method, full pattern, name, scopes and middleware",
+	Usage:       "adele route:list [options]",
+	Examples: []string{
+		"adele route:list",
+		"adele route:list --method=POST",
+		"adele route:list --prefix=/api",
+		"adele route:list --json",
+	},
+	Options: map[string]string{
+		"--method": "only list routes of this HTTP method",
+		"--prefix": "only list routes whose pattern starts with this path",
+		"--json":   "print the routes as JSON",
+	},
+}
+
+type RouteList struct{}
+
+func NewRouteList() *RouteList {
+	return &RouteList{}
+}
+
+// Handle runs the application with the route:list command so the routes it
+// registers at startup, including mounted subrouters, are listed.
+func (c *RouteList) Handle() error {
+	if !IsAdeleApp() {
+		return errors.New("adele route:list must be run from the root of an adele application (no go.mod referencing the framework)")
+	}
+
+	return runAppCommand(append([]string{"route:list"}, os.Args[2:]...)...)
+}
+
+func init() {
+	if err := Registry.Register(RouteListCommand); err != nil {
+		panic(fmt.Sprintf("Failed to register route:list command: %v", err))
+	}
+}
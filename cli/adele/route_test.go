@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRouteListCommand_Registration(t *testing.T) {
+	cmd, exists := Registry.GetCommand("route:list")
+	if !exists {
+		t.Fatal("Expected 'route:list' command to be registered in Registry")
+	}
+	if cmd != RouteListCommand {
+		t.Error("Expected Registry's 'route:list' command to be the same as RouteListCommand")
+	}
+}
+
+func TestRouteList_NotAdeleApp(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	err := NewRouteList().Handle()
+	if err == nil || !strings.Contains(err.Error(), "root of an adele application") {
+		t.Errorf("expected error outside an adele application, got: %v", err)
+	}
+}
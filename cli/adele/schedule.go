@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ScheduleListCommand = &Command{
+	Name:        "schedule:list",
+	Help:        "List scheduled tasks",
+	Description: "Build the application and list its scheduled tasks with their next run time and the duration and result of their last run",
+	Usage:       "adele schedule:list",
+	Examples: []string{
+		"adele schedule:list",
+	},
+}
+
+type ScheduleList struct{}
+
+func NewScheduleList() *ScheduleList {
+	return &ScheduleList{}
+}
+
+// Handle runs the application with the schedule:list command so the tasks it
+// registers at startup are listed. Last runs are read from the cache, so they
+// are shown only when the application has a cache configured.
+func (c *ScheduleList) Handle() error {
+	if !IsAdeleApp() {
+		return errors.New("adele schedule:list must be run from the root of an adele application (no go.mod referencing the framework)")
+	}
+
+	return runAppCommand("schedule:list")
+}
+
+func init() {
+	if err := Registry.Register(ScheduleListCommand); err != nil {
+		panic(fmt.Sprintf("Failed to register schedule:list command: %v", err))
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScheduleListCommand_Registration(t *testing.T) {
+	cmd, exists := Registry.GetCommand("schedule:list")
+	if !exists {
+		t.Fatal("Expected 'schedule:list' command to be registered in Registry")
+	}
+	if cmd != ScheduleListCommand {
+		t.Error("Expected Registry's 'schedule:list' command to be the same as ScheduleListCommand")
+	}
+}
+
+func TestScheduleList_NotAdeleApp(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	err := NewScheduleList().Handle()
+	if err == nil || !strings.Contains(err.Error(), "root of an adele application") {
+		t.Errorf("expected error outside an adele application, got: %v", err)
+	}
+}
@@ -39,7 +39,6 @@ var sharedAuthCode = map[string]string{
 	"handlers/convenience.go":     "templates/aerra/code/handlers/convenience.go",
 	"main.go":                     "templates/aerra/code/main.go",
 	"middleware/authenticated.go": "templates/aerra/code/middleware/authenticated.go",
-	"middleware/remember.go":      "templates/aerra/code/middleware/remember.go",
 	"models/user.go":              "templates/aerra/code/models/user.go",
 	"models/remember_token.go":    "templates/aerra/code/models/remember_token.go",
 	"models/models.go":            "templates/aerra/code/models/models.go",
@@ -63,7 +63,6 @@ var expectedAerraAuthFiles = []string{
 	"handlers/convenience.go",
 	"main.go",
 	"middleware/authenticated.go",
-	"middleware/remember.go",
 	"models/user.go",
 	"models/remember_token.go",
 	"models/models.go",
@@ -1248,17 +1247,17 @@ func TestStarterKit_StageFiles_SubstitutesAppName(t *testing.T) {
 		t.Errorf("Expected import to contain 'myproject/foo/models', got: %s", gotStr)
 	}
 
-	// remember.go also has `$APPNAME$/models` — confirm it was substituted there
+	// main.go also has `$APPNAME$/models` — confirm it was substituted there
 	// too, since stageFiles iterates the whole map.
-	rem, err := os.ReadFile("middleware/remember.go")
+	mainGo, err := os.ReadFile("main.go")
 	if err != nil {
-		t.Fatalf("Failed to read middleware/remember.go: %v", err)
+		t.Fatalf("Failed to read main.go: %v", err)
 	}
-	if strings.Contains(string(rem), "$APPNAME$") {
-		t.Errorf("Expected `$APPNAME$` token to be substituted in remember.go, got: %s", string(rem))
+	if strings.Contains(string(mainGo), "$APPNAME$") {
+		t.Errorf("Expected `$APPNAME$` token to be substituted in main.go, got: %s", string(mainGo))
 	}
-	if !strings.Contains(string(rem), `"myproject/foo/models"`) {
-		t.Errorf("Expected remember.go import to contain 'myproject/foo/models', got: %s", string(rem))
+	if !strings.Contains(string(mainGo), `"myproject/foo/models"`) {
+		t.Errorf("Expected main.go import to contain 'myproject/foo/models', got: %s", string(mainGo))
 	}
 }
 
@@ -1410,7 +1409,6 @@ var expectedVue3WithAuthFiles = []string{
 	"handlers/convenience.go",
 	"main.go",
 	"middleware/authenticated.go",
-	"middleware/remember.go",
 	"models/user.go",
 	"models/remember_token.go",
 	"models/models.go",
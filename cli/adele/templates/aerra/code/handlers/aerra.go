@@ -125,13 +125,13 @@ func (h *Handlers) LoginPost(w http.ResponseWriter, r *http.Request) {
 	if wantsJSON(r) {
 		h.respondJSON(w, http.StatusOK, map[string]any{
 			"ok":       true,
-			"redirect": "/dashboard/home",
+			"redirect": h.route("dashboard"),
 			"message":  "Success! You are logged into the application.",
 		})
 		return
 	}
 	h.App.Session.Put(r.Context(), "flash", "Success! You are logged into the application.")
-	http.Redirect(w, r, "/dashboard/home", http.StatusSeeOther)
+	http.Redirect(w, r, h.route("dashboard"), http.StatusSeeOther)
 }
 
 func (h *Handlers) Logout(w http.ResponseWriter, r *http.Request) {
@@ -145,20 +145,46 @@ func (h *Handlers) Logout(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		h.App.Session.Put(r.Context(), "error", "Unable to process your request at this time. Please try again later.")
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		http.Redirect(w, r, h.route("login"), http.StatusSeeOther)
 		return
 	}
 
 	if wantsJSON(r) {
 		h.respondJSON(w, http.StatusOK, map[string]any{
 			"ok":       true,
-			"redirect": "/login",
+			"redirect": h.route("login"),
 			"message":  "You have been logged out.",
 		})
 		return
 	}
 	h.App.Session.Put(r.Context(), "flash", "You have been logged out.")
-	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	http.Redirect(w, r, h.route("login"), http.StatusSeeOther)
+}
+
+func (h *Handlers) StopImpersonating(w http.ResponseWriter, r *http.Request) {
+	if err := h.App.Auth.StopImpersonating(r); err != nil {
+		if wantsJSON(r) {
+			h.respondJSON(w, http.StatusBadRequest, map[string]any{
+				"ok":      false,
+				"message": "You are not impersonating a user.",
+			})
+			return
+		}
+		h.App.Session.Put(r.Context(), "error", "You are not impersonating a user.")
+		http.Redirect(w, r, h.route("dashboard"), http.StatusSeeOther)
+		return
+	}
+
+	if wantsJSON(r) {
+		h.respondJSON(w, http.StatusOK, map[string]any{
+			"ok":       true,
+			"redirect": h.route("dashboard"),
+			"message":  "You are signed in as yourself again.",
+		})
+		return
+	}
+	h.App.Session.Put(r.Context(), "flash", "You are signed in as yourself again.")
+	http.Redirect(w, r, h.route("dashboard"), http.StatusSeeOther)
 }
 
 func (h *Handlers) Forgot(w http.ResponseWriter, r *http.Request) {
@@ -184,7 +210,7 @@ func (h *Handlers) ForgotPost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		h.App.Session.Put(r.Context(), "error", "Unable to process your request at this time. Please try again later.")
-		http.Redirect(w, r, "/forgot", http.StatusBadRequest)
+		http.Redirect(w, r, h.route("forgot"), http.StatusBadRequest)
 		return
 	}
 
@@ -202,7 +228,7 @@ func (h *Handlers) ForgotPost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		h.App.Session.Put(r.Context(), "error", "Unable to process your request at this time. Please try again later.")
-		http.Redirect(w, r, "/forgot", http.StatusSeeOther)
+		http.Redirect(w, r, h.route("forgot"), http.StatusSeeOther)
 		return
 	}
 
@@ -215,7 +241,7 @@ func (h *Handlers) ForgotPost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		h.App.Session.Put(r.Context(), "flash", "A password reset link was sent to your email address.")
-		http.Redirect(w, r, "/forgot", http.StatusSeeOther)
+		http.Redirect(w, r, h.route("forgot"), http.StatusSeeOther)
 		return
 	}
 
@@ -253,7 +279,7 @@ func (h *Handlers) ForgotPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	h.App.Session.Put(r.Context(), "flash", "A password reset link was sent to your email address.")
-	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	http.Redirect(w, r, h.route("login"), http.StatusSeeOther)
 }
 
 func (h *Handlers) Registration(w http.ResponseWriter, r *http.Request) {
@@ -351,13 +377,13 @@ func (h *Handlers) RegistrationPost(w http.ResponseWriter, r *http.Request) {
 	if wantsJSON(r) {
 		h.respondJSON(w, http.StatusOK, map[string]any{
 			"ok":       true,
-			"redirect": "/login",
+			"redirect": h.route("login"),
 			"message":  "Registration complete - please check your email and login.",
 		})
 		return
 	}
 	h.App.Session.Put(r.Context(), "flash", "Registration complete - please check your email and login.")
-	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	http.Redirect(w, r, h.route("login"), http.StatusSeeOther)
 }
 
 // NotFound is intentionally left in the user's existing handlers.go; this file
@@ -466,7 +492,7 @@ func (h *Handlers) ResetPasswordPost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		h.App.Session.Put(r.Context(), "error", "The password reset link is invalid. Please request a new one.")
-		http.Redirect(w, r, "/forgot", http.StatusBadRequest)
+		http.Redirect(w, r, h.route("forgot"), http.StatusBadRequest)
 		return
 	}
 
@@ -482,7 +508,7 @@ func (h *Handlers) ResetPasswordPost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		h.App.Session.Put(r.Context(), "error", "Unable to process your request at this time. Please try again later.")
-		http.Redirect(w, r, "/forgot", http.StatusSeeOther)
+		http.Redirect(w, r, h.route("forgot"), http.StatusSeeOther)
 		return
 	}
 
@@ -495,7 +521,7 @@ func (h *Handlers) ResetPasswordPost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		h.App.Session.Put(r.Context(), "error", "Unable to process your request at this time. Please try again later.")
-		http.Redirect(w, r, "/forgot", http.StatusSeeOther)
+		http.Redirect(w, r, h.route("forgot"), http.StatusSeeOther)
 		return
 	}
 
@@ -515,13 +541,13 @@ func (h *Handlers) ResetPasswordPost(w http.ResponseWriter, r *http.Request) {
 	if wantsJSON(r) {
 		h.respondJSON(w, http.StatusOK, map[string]any{
 			"ok":       true,
-			"redirect": "/login",
+			"redirect": h.route("login"),
 			"message":  "Password reset complete - please login.",
 		})
 		return
 	}
 	h.App.Session.Put(r.Context(), "flash", "Password reset complete - please login.")
-	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	http.Redirect(w, r, h.route("login"), http.StatusSeeOther)
 }
 
 func (h *Handlers) CreateUser(w http.ResponseWriter, r *http.Request) {
@@ -730,7 +756,7 @@ func (h *Handlers) ProfilePasswordPost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		h.App.Session.Put(r.Context(), "error", "Unable to process your request at this time. Please try again later.")
-		http.Redirect(w, r, "/profile", http.StatusSeeOther)
+		http.Redirect(w, r, h.route("profile"), http.StatusSeeOther)
 		return
 	}
 
@@ -743,7 +769,7 @@ func (h *Handlers) ProfilePasswordPost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		h.App.Session.Put(r.Context(), "error", "Unable to process your request at this time. Please try again later.")
-		http.Redirect(w, r, "/profile", http.StatusSeeOther)
+		http.Redirect(w, r, h.route("profile"), http.StatusSeeOther)
 		return
 	}
 
@@ -764,12 +790,12 @@ func (h *Handlers) ProfilePasswordPost(w http.ResponseWriter, r *http.Request) {
 	if wantsJSON(r) {
 		h.respondJSON(w, http.StatusOK, map[string]any{
 			"ok":       true,
-			"redirect": "/login",
+			"redirect": h.route("login"),
 			"message":  "Password reset complete - please login.",
 		})
 		return
 	}
 	h.App.Session.Put(r.Context(), "flash", "Password reset complete - please login.")
-	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	http.Redirect(w, r, h.route("login"), http.StatusSeeOther)
 
 }
@@ -25,6 +25,17 @@ func (h *Handlers) render(w http.ResponseWriter, r *http.Request, template strin
 	return h.App.Render.Page(w, r, template, vars, data)
 }
 
+// route returns the path of a named route from routes-web.go, e.g.
+// h.route("login"). An unknown name is logged and sends the user home.
+func (h *Handlers) route(name string, params ...interface{}) string {
+	link, err := h.App.Routes.URL(name, params...)
+	if err != nil {
+		h.App.ErrorLog.Println(err)
+		return "/"
+	}
+	return link
+}
+
 func (h *Handlers) encrypt(text string) (string, error) {
 	enc := helpers.Encryption{Key: []byte(h.App.EncryptionKey)}
 
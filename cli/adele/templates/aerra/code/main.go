@@ -8,57 +8,52 @@ import (
 	"$APPNAME$/middleware"
 	"$APPNAME$/models"
 	"os"
-	"os/signal"
-	"sync"
-	"syscall"
 
 	"github.com/cidekar/adele-framework"
 	"github.com/cidekar/adele-framework/httpserver"
-	"github.com/cidekar/adele-framework/provider"
 	"github.com/cidekar/adele-framework/rpcserver"
 )
 
-var wg sync.WaitGroup
-
 func main() {
 
 	a := bootstrapApplication()
 
-	go a.Mail.ListenForMail()
+	a.jobsSchedule()
 
-	go a.listenForShutdown()
+	// Commands such as `adele queue:work` and `adele route:list` run this binary
+	// with the command as the first argument instead of starting the web
+	// server. The routes are registered by bootstrapApplication beforehand.
+	if handled, err := a.App.RunCommand(os.Args[1:]); handled {
+		if err != nil {
+			a.App.Log.Error(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	go a.Mail.ListenForMail()
 
 	err := rpcserver.Start(a.App)
 	if err != nil {
 		log.Fatalf("failed to start rpc: %s", err)
 	}
 
-	a.jobsSchedule()
-
+	// Start runs the scheduler and blocks until SIGINT or SIGTERM, drains in-flight requests and then
+	// shuts down the scheduler, mailer, RPC listener, cache and database.
 	err = httpserver.Start(a.App)
-
-	a.App.Log.Error(err)
-
-}
-
-func (a *application) listenForShutdown() {
-
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	s := <-quit
-
-	a.App.Log.Info("Application received signal", s.String())
-
-	err := rpcserver.Stop(a.App)
 	if err != nil {
-		log.Fatal("RPC server failed to stop:", err)
+		a.App.Log.Error(err)
+		os.Exit(1)
 	}
 
 	a.App.Log.Info("Good bye!")
-
-	os.Exit(0)
 }
 
+// Register the application's scheduled tasks. They run while the web server is
+// up and are listed by `adele schedule:list`, for example:
+//
+//	a.App.Schedule.Add("prune-reports", "0 3 * * *", a.pruneReports,
+//		schedule.WithoutOverlapping(0), schedule.OnOneServer())
 func (a *application) jobsSchedule() {
 	// ...
 }
@@ -79,7 +74,7 @@ func bootstrapApplication() *application {
 
 	// Construct Models first so Handlers and Middleware can be wired against
 	// the same instance — h.Models / m.Models would otherwise be nil and any
-	// receiver that touches them (e.g. Registration, Login, CheckRemember)
+	// receiver that touches them (e.g. Registration, Login, AuthenticatedGuard)
 	// would nil-panic at request time.
 	m := models.New(a)
 
@@ -103,17 +98,5 @@ func bootstrapApplication() *application {
 
 	app.App.Routes = app.routes()
 
-	p := &provider.Provider{
-		EnabledProviders: make(map[string]bool),
-		ProviderConfigs:  make(map[string]map[string]interface{}),
-	}
-
-	a.Provider = p
-
-	if err := a.Provider.LoadProviders(app.App); err != nil {
-		a.Log.Error(err)
-		os.Exit(1)
-	}
-
 	return app
 }
@@ -1,80 +0,0 @@
-//go:build aerra_template
-
-package middleware
-
-import (
-	"fmt"
-	"$APPNAME$/models"
-	"net/http"
-	"strconv"
-	"strings"
-	"time"
-)
-
-func (m *Middleware) CheckRemember(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
-		if !m.App.Session.Exists(r.Context(), "userID") {
-			// user is not logged in
-			cookie, err := r.Cookie(fmt.Sprintf("_%s_remember", m.App.AppName))
-
-			if err != nil {
-				// no cookie, move to the next middleware
-				next.ServeHTTP(w, r)
-			} else {
-				// found cookie in request, validate it
-				key := cookie.Value
-				var u models.User
-				if len(key) > 0 {
-					// cookie as data, validate it
-					split := strings.Split(key, "|")
-					uid, hash := split[0], split[1]
-					id, _ := strconv.Atoi(uid)
-					validHash := u.CheckForRememberToken(id, hash)
-
-					if !validHash {
-						m.deleteRememberCookie(w, r)
-						m.App.Session.Put(r.Context(), "error", "You have been logged out from another device")
-						next.ServeHTTP(w, r)
-					} else {
-						// token is valid, log them into the system
-						user, _ := u.Get(id)
-						m.App.Session.Put(r.Context(), "userID", user.ID)
-						m.App.Session.Put(r.Context(), "remember_token", hash)
-						next.ServeHTTP(w, r)
-					}
-				} else {
-					// key length is zero, older cookie since user has not closed the browser
-					m.deleteRememberCookie(w, r)
-					next.ServeHTTP(w, r)
-				}
-			}
-		} else {
-			next.ServeHTTP(w, r)
-		}
-	})
-}
-
-func (m *Middleware) deleteRememberCookie(w http.ResponseWriter, r *http.Request) {
-	_ = m.App.Session.RenewToken(r.Context())
-	// delete the cookie
-	newCookie := http.Cookie{
-		Name:     fmt.Sprintf("_%s_remember", m.App.AppName),
-		Value:    "",
-		Path:     "/",
-		Expires:  time.Now().Add(-100 * time.Hour),
-		HttpOnly: true,
-		Domain:   m.App.Session.Cookie.Domain,
-		MaxAge:   -1,
-		Secure:   m.App.Session.Cookie.Secure,
-		SameSite: http.SameSiteStrictMode,
-	}
-	http.SetCookie(w, &newCookie)
-
-	// log user out
-	m.App.Session.Remove(r.Context(), "userID")
-	m.App.Session.Destroy(r.Context())
-
-	// let's renew, just to be safe
-	_ = m.App.Session.RenewToken(r.Context())
-}
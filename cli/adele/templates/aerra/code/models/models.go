@@ -6,11 +6,16 @@ import (
 	"fmt"
 
 	"github.com/cidekar/adele-framework"
+	"github.com/cidekar/adele-framework/auth"
 	upper "github.com/upper/db/v4"
 )
 
 var DB upper.Session
 
+// Hasher hashes passwords with the algorithm set by AUTH_HASHER, so hashes
+// written here are the ones auth.Login checks and upgrades.
+var Hasher auth.Hasher
+
 // The Models struct is the single place to register and access all database models throughout
 // your application. The Models struct acts as a container that holds all your application's
 // data models— automatic database session setup.
@@ -24,6 +29,7 @@ func New(a *adele.Adele) *Models {
 
 	// Sets Up Database Session
 	DB = a.DB.NewSession()
+	Hasher = a.Auth.Hasher
 
 	// Returns any initialized Models
 	return &Models{
@@ -8,9 +8,13 @@ import (
 	up "github.com/upper/db/v4"
 )
 
+// RememberToken is a row written by the framework's auth.Login and rotated by
+// auth.RememberMiddleware. RememberToken holds the hash of the cookie's
+// validator, never the cookie value itself.
 type RememberToken struct {
 	ID            int       `db:"id,omitempty"`
 	UserID        int       `db:"user_id"`
+	Selector      string    `db:"selector"`
 	RememberToken string    `db:"remember_token"`
 	CreatedAt     time.Time `db:"created_at"`
 	UpdatedAt     time.Time `db:"updated_at"`
@@ -20,26 +24,10 @@ func (t *RememberToken) Table() string {
 	return "remember_tokens"
 }
 
-func (t *RememberToken) InsertToken(userID int, token string) error {
+// DeleteForUser logs a user out of every browser they were kept logged in on.
+func (t *RememberToken) DeleteForUser(userID int) error {
 	collection := DB.Collection(t.Table())
-	rememberToken := RememberToken{
-		UserID:        userID,
-		RememberToken: token,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-	}
-
-	_, err := collection.Insert(rememberToken)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (t *RememberToken) DeleteToken(token string) error {
-	collection := DB.Collection(t.Table())
-	res := collection.Find(up.Cond{"remember_token": token})
+	res := collection.Find(up.Cond{"user_id": userID})
 	err := res.Delete()
 	if err != nil {
 		return err
@@ -3,11 +3,9 @@
 package models
 
 import (
-	"errors"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
-
+	"github.com/cidekar/adele-framework/auth"
 	up "github.com/upper/db/v4"
 )
 
@@ -102,14 +100,14 @@ func (u *User) Delete(id int) error {
 
 // Add a new user
 func (u *User) Insert(theUser User) (int, error) {
-	newHash, err := bcrypt.GenerateFromPassword([]byte(theUser.Password), 12)
+	newHash, err := Hasher.Hash(theUser.Password)
 	if err != nil {
 		return 0, err
 	}
 
 	theUser.CreatedAt = time.Now()
 	theUser.UpdatedAt = time.Now()
-	theUser.Password = string(newHash)
+	theUser.Password = newHash
 
 	collection := DB.Collection(u.Table())
 	res, err := collection.Insert(theUser)
@@ -124,7 +122,7 @@ func (u *User) Insert(theUser User) (int, error) {
 
 // Given a user, reset a user's password
 func (u *User) ResetPassword(id int, password string) error {
-	newHash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	newHash, err := Hasher.Hash(password)
 	if err != nil {
 		return err
 	}
@@ -134,7 +132,7 @@ func (u *User) ResetPassword(id int, password string) error {
 		return err
 	}
 
-	u.Password = string(newHash)
+	u.Password = newHash
 
 	err = theUser.Update(*u)
 	if err != nil {
@@ -149,23 +147,5 @@ func (u *User) ResetPassword(id int, password string) error {
 // error. Note that an error is only returned if something goes wrong (since an invalid password
 // is not an error -- it's just the wrong password))
 func (u *User) PasswordMatches(plainText string) (bool, error) {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(plainText))
-	if err != nil {
-		switch {
-		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
-			return false, nil
-		default:
-			return false, err
-		}
-	}
-	return true, nil
-}
-
-func (u *User) CheckForRememberToken(id int, token string) bool {
-	var rememberToken RememberToken
-	rt := RememberToken{}
-	collection := DB.Collection(rt.Table()) // Get the table name
-	res := collection.Find(up.Cond{"user_id": id, "remember_token": token})
-	err := res.One(&rememberToken)
-	return err == nil
+	return auth.CheckPassword(plainText, u.Password)
 }
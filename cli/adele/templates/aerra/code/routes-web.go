@@ -15,30 +15,32 @@ func (a *application) WebRoutes() http.Handler {
 
 	r.Use(a.Middleware.NoSurf)
 
-	r.Use(a.Middleware.CheckRemember)
+	r.Use(a.App.Auth.RememberMiddleware)
+
+	r.Use(a.App.Auth.TrackSessions)
 
 	// Public routes
 	r.Group(func(mux chi.Router) {
 
-		r.Get("/", a.Handlers.Home)
+		r.Get("/[name:home]", a.Handlers.Home)
 
-		r.Get("/login", a.Handlers.Login)
+		r.Get("/login[name:login]", a.Handlers.Login)
 
 		r.Post("/login", a.Handlers.LoginPost)
 
-		r.Get("/logout", a.Handlers.Logout)
+		r.Get("/logout[name:logout]", a.Handlers.Logout)
 
 		r.Post("/logout", a.Handlers.Logout)
 
-		r.Get("/forgot", a.Handlers.Forgot)
+		r.Get("/forgot[name:forgot]", a.Handlers.Forgot)
 
 		r.Post("/forgot", a.Handlers.ForgotPost)
 
-		r.Get("/registration", a.Handlers.Registration)
+		r.Get("/registration[name:registration]", a.Handlers.Registration)
 
 		r.Post("/registration", a.Handlers.RegistrationPost)
 
-		r.Get("/reset-password", a.Handlers.ResetPassword)
+		r.Get("/reset-password[name:password.reset]", a.Handlers.ResetPassword)
 
 		r.Post("/reset-password", a.Handlers.ResetPasswordPost)
 
@@ -54,13 +56,17 @@ func (a *application) WebRoutes() http.Handler {
 
 	privateRoutes.Group(func(mux chi.Router) {
 
-		privateRoutes.Get("/home", a.Handlers.Dashboard)
+		privateRoutes.Get("/home[name:dashboard]", a.Handlers.Dashboard)
+
+		privateRoutes.Get("/profile[name:profile]", a.Handlers.Profile)
 
-		privateRoutes.Get("/profile", a.Handlers.Profile)
+		// Support staff impersonating a user may look but not change the
+		// user's credentials.
+		privateRoutes.With(a.App.Auth.ForbidImpersonation).Post("/profile", a.Handlers.ProfilePost)
 
-		privateRoutes.Post("/profile", a.Handlers.ProfilePost)
+		privateRoutes.With(a.App.Auth.ForbidImpersonation).Post("/profile-password", a.Handlers.ProfilePasswordPost)
 
-		privateRoutes.Post("/profile-password", a.Handlers.ProfilePasswordPost)
+		privateRoutes.Post("/impersonate/stop[name:impersonate.stop]", a.Handlers.StopImpersonating)
 	})
 
 	a.App.Routes.Mount("/dashboard", privateRoutes)
@@ -1,6 +1,7 @@
 CREATE TABLE remember_tokens (
     id SERIAL PRIMARY KEY,
     user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    selector character varying(32) NOT NULL UNIQUE,
     remember_token character varying(100) NOT NULL,
     created_at timestamp without time zone NOT NULL DEFAULT now(),
     updated_at timestamp without time zone NOT NULL DEFAULT now()
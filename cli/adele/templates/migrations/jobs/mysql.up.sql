@@ -0,0 +1,21 @@
+-- Reserving jobs uses FOR UPDATE SKIP LOCKED, which needs MySQL 8.0 or MariaDB 10.6 or later.
+CREATE TABLE jobs (
+    id CHAR(32) PRIMARY KEY,
+    queue VARCHAR(255) NOT NULL,
+    payload LONGBLOB NOT NULL,
+    attempts INT UNSIGNED NOT NULL DEFAULT 0,
+    reservation CHAR(32) NULL,
+    reserved_at TIMESTAMP(6) NULL,
+    available_at TIMESTAMP(6) NOT NULL,
+    created_at TIMESTAMP(6) NOT NULL
+);
+
+CREATE INDEX jobs_queue_available_at_idx ON jobs (queue, available_at);
+
+CREATE TABLE failed_jobs (
+    id CHAR(32) PRIMARY KEY,
+    queue VARCHAR(255) NOT NULL,
+    payload LONGBLOB NOT NULL,
+    exception TEXT NOT NULL,
+    failed_at TIMESTAMP(6) NOT NULL
+);
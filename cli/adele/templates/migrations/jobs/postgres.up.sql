@@ -0,0 +1,21 @@
+-- Reserving jobs uses FOR UPDATE SKIP LOCKED, which needs PostgreSQL 9.5 or later.
+CREATE TABLE jobs (
+    id CHAR(32) PRIMARY KEY,
+    queue VARCHAR(255) NOT NULL,
+    payload BYTEA NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    reservation CHAR(32) NULL,
+    reserved_at TIMESTAMPTZ NULL,
+    available_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX jobs_queue_available_at_idx ON jobs (queue, available_at);
+
+CREATE TABLE failed_jobs (
+    id CHAR(32) PRIMARY KEY,
+    queue VARCHAR(255) NOT NULL,
+    payload BYTEA NOT NULL,
+    exception TEXT NOT NULL,
+    failed_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS oauth_refresh_tokens;
+DROP TABLE IF EXISTS oauth_access_tokens;
+DROP TABLE IF EXISTS oauth_auth_codes;
+DROP TABLE IF EXISTS oauth_clients;
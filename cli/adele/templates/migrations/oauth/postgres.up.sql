@@ -0,0 +1,39 @@
+CREATE TABLE oauth_clients (
+    id CHAR(32) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    secret VARCHAR(64) NOT NULL DEFAULT '',
+    redirect_uris TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE oauth_auth_codes (
+    id CHAR(64) PRIMARY KEY,
+    client_id CHAR(32) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL,
+    scopes TEXT NOT NULL,
+    redirect_uri TEXT NOT NULL,
+    code_challenge VARCHAR(128) NOT NULL DEFAULT '',
+    code_challenge_method VARCHAR(10) NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE oauth_access_tokens (
+    id CHAR(64) PRIMARY KEY,
+    client_id CHAR(32) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id INTEGER NULL,
+    scopes TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX oauth_access_tokens_user_id_idx ON oauth_access_tokens (user_id);
+
+CREATE TABLE oauth_refresh_tokens (
+    id CHAR(64) PRIMARY KEY,
+    access_token_id CHAR(64) NOT NULL,
+    client_id CHAR(32) NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id INTEGER NULL,
+    scopes TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
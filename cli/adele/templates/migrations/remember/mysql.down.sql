@@ -0,0 +1,2 @@
+ALTER TABLE remember_tokens DROP INDEX remember_tokens_selector_idx;
+ALTER TABLE remember_tokens DROP COLUMN selector;
@@ -0,0 +1,3 @@
+DELETE FROM remember_tokens;
+ALTER TABLE remember_tokens ADD COLUMN selector VARCHAR(32) NOT NULL;
+CREATE UNIQUE INDEX remember_tokens_selector_idx ON remember_tokens (selector);
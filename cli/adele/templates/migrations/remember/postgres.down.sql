@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS remember_tokens_selector_idx;
+ALTER TABLE remember_tokens DROP COLUMN selector;
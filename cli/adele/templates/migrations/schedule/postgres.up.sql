@@ -0,0 +1,5 @@
+CREATE TABLE schedule_locks (
+    name VARCHAR(255) PRIMARY KEY,
+    owner CHAR(32) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,5 @@
+ALTER TABLE sessions ADD COLUMN user_id BIGINT NULL;
+ALTER TABLE sessions ADD COLUMN ip_address VARCHAR(45) NULL;
+ALTER TABLE sessions ADD COLUMN user_agent VARCHAR(255) NULL;
+ALTER TABLE sessions ADD COLUMN last_activity TIMESTAMP(6) NULL;
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
@@ -0,0 +1,12 @@
+CREATE TABLE personal_access_tokens (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    token CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    last_used_at TIMESTAMP(6) NULL,
+    expires_at TIMESTAMP(6) NULL,
+    created_at TIMESTAMP(6) NOT NULL,
+    updated_at TIMESTAMP(6) NOT NULL,
+    INDEX personal_access_tokens_user_id_idx (user_id)
+);
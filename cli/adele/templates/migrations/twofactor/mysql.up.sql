@@ -0,0 +1,16 @@
+CREATE TABLE two_factor_credentials (
+    user_id INT PRIMARY KEY,
+    secret VARCHAR(64) NOT NULL,
+    last_used_step BIGINT NULL,
+    confirmed_at TIMESTAMP(6) NULL,
+    created_at TIMESTAMP(6) NOT NULL
+);
+
+CREATE TABLE two_factor_recovery_codes (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    code CHAR(64) NOT NULL,
+    used_at TIMESTAMP(6) NULL,
+    created_at TIMESTAMP(6) NOT NULL,
+    INDEX two_factor_recovery_codes_user_id_idx (user_id)
+);
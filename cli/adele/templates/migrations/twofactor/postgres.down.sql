@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS two_factor_recovery_codes;
+DROP TABLE IF EXISTS two_factor_credentials;
@@ -0,0 +1,17 @@
+CREATE TABLE two_factor_credentials (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    last_used_step BIGINT NULL,
+    confirmed_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE two_factor_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    code CHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX two_factor_recovery_codes_user_id_idx ON two_factor_recovery_codes (user_id);
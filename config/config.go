@@ -0,0 +1,265 @@
+// Package config loads the typed, validated configuration of an Adele application.
+//
+// Values are resolved per key from, in increasing order of precedence, the
+// defaults declared on the configuration structs, an optional YAML file per
+// section under config/ (for example config/redis.yml), the application's .env
+// file and the real process environment. Every key that cannot be parsed or
+// fails validation is collected and reported in a single error.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v2"
+)
+
+// Load the configuration for the application rooted at rootPath. A missing .env
+// file or section YAML file is not an error. When any key is invalid the returned
+// error is a *ValidationError listing every problem, and the partially populated
+// configuration is returned alongside it. Keys of the .env file that configure
+// nothing are not an error either; they are reported by Warnings.
+func New(rootPath string) (*Config, error) {
+	var problems []string
+
+	dotenv, err := readDotenv(filepath.Join(rootPath, ".env"))
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	cfg := &Config{}
+	root := reflect.ValueOf(cfg).Elem()
+	known := make(map[string]bool)
+
+	for i := 0; i < root.NumField(); i++ {
+		field := root.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		problems = append(problems, loadSection(root.Field(i), field.Tag.Get("yaml"), rootPath, dotenv)...)
+
+		for j := 0; j < field.Type.NumField(); j++ {
+			known[field.Type.Field(j).Tag.Get("env")] = true
+		}
+	}
+
+	for key := range dotenv {
+		if !known[key] {
+			cfg.warnings = append(cfg.warnings, fmt.Sprintf(".env: unknown key %q", key))
+		}
+	}
+	sort.Strings(cfg.warnings)
+
+	problems = append(problems, cfg.validate()...)
+
+	if len(problems) > 0 {
+		return cfg, &ValidationError{Problems: problems}
+	}
+
+	return cfg, nil
+}
+
+// Load individual sections of the configuration the same way New does, for
+// code that runs without the application's Config. Each section is a pointer
+// to a section struct such as *Log or *HTTP. The sections are not validated;
+// a value that cannot be parsed is reported in a *ValidationError and its field
+// is left at the zero value.
+func Load(rootPath string, sections ...interface{}) error {
+	var problems []string
+
+	dotenv, err := readDotenv(filepath.Join(rootPath, ".env"))
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	configType := reflect.TypeOf(Config{})
+	for _, section := range sections {
+		v := reflect.ValueOf(section)
+		if v.Kind() != reflect.Ptr || v.IsNil() {
+			return fmt.Errorf("config: section must be a non-nil pointer, got %T", section)
+		}
+
+		name := ""
+		for i := 0; i < configType.NumField(); i++ {
+			if configType.Field(i).Type == v.Elem().Type() {
+				name = configType.Field(i).Tag.Get("yaml")
+			}
+		}
+		if name == "" {
+			return fmt.Errorf("config: %T is not a configuration section", section)
+		}
+
+		problems = append(problems, loadSection(v.Elem(), name, rootPath, dotenv)...)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+// The keys of the .env file New found no setting for, e.g. a misspelled
+// REDIS_MAX_IDEL, one warning per key.
+func (c *Config) Warnings() []string {
+	return c.warnings
+}
+
+// Every problem found while loading the configuration, on one line.
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// The host:port address of the Redis server.
+func (r Redis) Addr() string {
+	return r.Host + ":" + r.Port
+}
+
+// Populate the fields of one section from its config/<name>.yml file, the .env
+// values and the environment, returning a problem for every value that cannot
+// be parsed into its field and for every unknown key in the section file.
+func loadSection(section reflect.Value, name, rootPath string, dotenv map[string]string) []string {
+	var problems []string
+
+	file := filepath.Join("config", name+".yml")
+	fileValues, err := readSectionFile(filepath.Join(rootPath, file))
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("%s: %v", file, err))
+	}
+
+	known := make(map[string]bool)
+
+	for i := 0; i < section.NumField(); i++ {
+		field := section.Type().Field(i)
+		key := field.Tag.Get("yaml")
+		env := field.Tag.Get("env")
+		known[key] = true
+
+		raw, source := field.Tag.Get("default"), env
+		if v, ok := fileValues[key]; ok && v != "" {
+			raw, source = v, file+": "+key
+		}
+		if v := lookup(env, dotenv); v != "" {
+			raw, source = v, env
+		}
+
+		if err := setField(section.Field(i), raw); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", source, err))
+		}
+	}
+
+	for key := range fileValues {
+		if !known[key] {
+			problems = append(problems, fmt.Sprintf("%s: unknown key %q", file, key))
+		}
+	}
+
+	return problems
+}
+
+// Find the value of an environment variable, preferring the process
+// environment over the .env file.
+func lookup(name string, dotenv map[string]string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return dotenv[name]
+}
+
+// Parse a raw string into a configuration field.
+func setField(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		if raw == "" {
+			v.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		if raw == "" {
+			v.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Kind())
+	}
+
+	return nil
+}
+
+// Read the .env file without modifying the process environment.
+func readDotenv(path string) (map[string]string, error) {
+	values, err := godotenv.Read(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return map[string]string{}, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	return values, nil
+}
+
+// Read a flat section YAML file into strings keyed by the YAML key. Lists are
+// joined with commas so they parse the same way as a comma-separated variable.
+func readSectionFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(doc))
+	for key, value := range doc {
+		switch v := value.(type) {
+		case nil:
+			values[key] = ""
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+
+	return values, nil
+}
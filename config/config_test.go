@@ -0,0 +1,253 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestNew_Defaults(t *testing.T) {
+	cfg, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if cfg.HTTP.Port != "4000" {
+		t.Errorf("expected HTTP port 4000, got %q", cfg.HTTP.Port)
+	}
+	if cfg.HTTP.RateLimit != 100 {
+		t.Errorf("expected rate limit 100, got %d", cfg.HTTP.RateLimit)
+	}
+	if cfg.Redis.Addr() != "localhost:6379" {
+		t.Errorf("expected redis addr localhost:6379, got %q", cfg.Redis.Addr())
+	}
+	if !cfg.Session.CookiePersist {
+		t.Error("expected cookie persist to default to true")
+	}
+	if cfg.Filesystem.MaxUploadSize != 10<<20 {
+		t.Errorf("expected max upload size %d, got %d", 10<<20, cfg.Filesystem.MaxUploadSize)
+	}
+}
+
+func TestNew_Precedence(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "config", "redis.yml"), "host: yaml-host\nport: 6380\nprefix: yaml\n")
+	writeFile(t, filepath.Join(root, ".env"), "REDIS_HOST=dotenv-host\nREDIS_PREFIX=dotenv\n")
+	t.Setenv("REDIS_PREFIX", "env")
+
+	cfg, err := New(root)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	if cfg.Redis.Port != "6380" {
+		t.Errorf("expected port from yaml, got %q", cfg.Redis.Port)
+	}
+	if cfg.Redis.Host != "dotenv-host" {
+		t.Errorf("expected host from .env, got %q", cfg.Redis.Host)
+	}
+	if cfg.Redis.Prefix != "env" {
+		t.Errorf("expected prefix from environment, got %q", cfg.Redis.Prefix)
+	}
+}
+
+func TestNew_WarnsOnUnknownEnvKeys(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "REDIS_MAX_IDEL=7\nREDIS_MAX_IDLE=8\n")
+
+	cfg, err := New(root)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if cfg.Redis.MaxIdle != 8 {
+		t.Errorf("expected only REDIS_MAX_IDLE to set MaxIdle, got %d", cfg.Redis.MaxIdle)
+	}
+
+	warnings := cfg.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `unknown key "REDIS_MAX_IDEL"`) {
+		t.Errorf("expected a warning for the misspelled key, got %v", warnings)
+	}
+}
+
+func TestLoad_Sections(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "config", "log.yml"), "format: json\n")
+	writeFile(t, filepath.Join(root, ".env"), "HTTP_RATE_LIMIT=ten\n")
+	t.Setenv("RPC_SERVER_PORT", "4141")
+
+	var log Log
+	var rpc RPC
+	if err := Load(root, &log, &rpc); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if log.Format != "json" || rpc.Port != "4141" || rpc.Addr != "127.0.0.1" {
+		t.Errorf("unexpected sections %+v %+v", log, rpc)
+	}
+
+	var http HTTP
+	err := Load(root, &http)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || !strings.Contains(err.Error(), "HTTP_RATE_LIMIT") {
+		t.Errorf("expected the invalid rate limit to be reported, got %v", err)
+	}
+
+	if err := Load(root, &struct{}{}); err == nil {
+		t.Error("expected a type that is not a section to be refused")
+	}
+}
+
+func TestNew_ListValues(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "config", "http.yml"), "maintenance_urls:\n  - /healthz\n  - /readyz\n")
+
+	cfg, err := New(root)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if len(cfg.HTTP.MaintenanceURLs) != 2 || cfg.HTTP.MaintenanceURLs[1] != "/readyz" {
+		t.Errorf("unexpected maintenance urls: %v", cfg.HTTP.MaintenanceURLs)
+	}
+
+	t.Setenv("FILE_TYPES_ALLOWED", "text/plain, text/csv")
+	cfg, err = New(root)
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if len(cfg.Filesystem.AllowedFileTypes) != 2 || cfg.Filesystem.AllowedFileTypes[1] != "text/csv" {
+		t.Errorf("unexpected allowed file types: %v", cfg.Filesystem.AllowedFileTypes)
+	}
+}
+
+func TestNew_ReportsEveryProblem(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ".env"), "HTTP_PORT=abc\nHTTP_RATE_LIMIT=ten\nCACHE=memcached\nCOOKIE_SECURE=maybe\n")
+	writeFile(t, filepath.Join(root, "config", "redis.yml"), "max_idel: 5\n")
+
+	_, err := New(root)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %T", err)
+	}
+
+	for _, want := range []string{"HTTP_PORT", "HTTP_RATE_LIMIT", "CACHE", "COOKIE_SECURE", `unknown key "max_idel"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %s, got: %v", want, err)
+		}
+	}
+}
+
+func TestNew_SQLSessionRequiresDatabase(t *testing.T) {
+	t.Setenv("SESSION_TYPE", "postgres")
+
+	_, err := New(t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "requires DATABASE_TYPE") {
+		t.Errorf("expected database requirement error, got: %v", err)
+	}
+}
+
+func TestNew_Queue(t *testing.T) {
+	t.Setenv("QUEUE_TYPE", "redis")
+
+	cfg, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if !cfg.UsesRedis() {
+		t.Error("expected a redis queue to require redis")
+	}
+	if cfg.Queue.MaxAttempts != 3 || cfg.Queue.Timeout != 60 {
+		t.Errorf("unexpected queue defaults: %+v", cfg.Queue)
+	}
+
+	t.Setenv("QUEUE_TYPE", "database")
+	_, err = New(t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "QUEUE_TYPE") {
+		t.Errorf("expected database requirement error, got: %v", err)
+	}
+}
+
+func TestNew_OAuth(t *testing.T) {
+	cfg, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if cfg.OAuth.AccessTokenTTL != 3600 || cfg.OAuth.ConsentView != "oauth/authorize" {
+		t.Errorf("unexpected oauth defaults: %+v", cfg.OAuth)
+	}
+
+	t.Setenv("OAUTH_AUTH_CODE_TTL", "0")
+	_, err = New(t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "OAUTH_AUTH_CODE_TTL") {
+		t.Errorf("expected a code lifetime error, got: %v", err)
+	}
+}
+
+func TestNew_Auth(t *testing.T) {
+	cfg, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if cfg.Auth.TwoFactor {
+		t.Error("expected two-factor authentication to be off by default")
+	}
+
+	t.Setenv("AUTH_TWO_FACTOR", "true")
+	cfg, err = New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+	if !cfg.Auth.TwoFactor {
+		t.Error("expected AUTH_TWO_FACTOR to enable two-factor authentication")
+	}
+	if cfg.Auth.ThrottleMaxAttempts != 5 || cfg.Auth.ThrottleLockout != 60 {
+		t.Errorf("unexpected throttle defaults: %+v", cfg.Auth)
+	}
+
+	if cfg.Auth.VerifyPath != "/email/verify/confirm" || cfg.Auth.VerifyExpiry != 60 {
+		t.Errorf("unexpected verification defaults: %+v", cfg.Auth)
+	}
+
+	if cfg.Auth.UsersTable != "users" || cfg.Auth.LoginColumn != "email" || cfg.Auth.RememberTable != "remember_tokens" {
+		t.Errorf("unexpected user provider defaults: %+v", cfg.Auth)
+	}
+
+	t.Setenv("AUTH_THROTTLE_MAX_LOCKOUT", "30")
+	_, err = New(t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "AUTH_THROTTLE_MAX_LOCKOUT") {
+		t.Errorf("expected a maximum lockout error, got: %v", err)
+	}
+	t.Setenv("AUTH_THROTTLE_MAX_LOCKOUT", "3600")
+
+	if cfg.Auth.Hasher != "bcrypt" || cfg.Auth.BcryptCost != 12 || cfg.Auth.Argon2Memory != 65536 {
+		t.Errorf("unexpected hasher defaults: %+v", cfg.Auth)
+	}
+
+	t.Setenv("AUTH_HASHER", "md5")
+	_, err = New(t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "AUTH_HASHER") {
+		t.Errorf("expected a hasher error, got: %v", err)
+	}
+	t.Setenv("AUTH_HASHER", "argon2id")
+
+	t.Setenv("AUTH_LOGIN_COLUMN", "username; DROP TABLE users")
+	_, err = New(t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "AUTH_LOGIN_COLUMN") {
+		t.Errorf("expected a login column error, got: %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package config
+
+// Config is the typed application configuration. Each section is loaded from an
+// optional config/<section>.yml file, the application's .env file and the real
+// environment, in increasing order of precedence, on top of the defaults declared
+// in the struct tags.
+type Config struct {
+	App        App        `yaml:"app"`
+	HTTP       HTTP       `yaml:"http"`
+	Database   Database   `yaml:"database"`
+	Redis      Redis      `yaml:"redis"`
+	Cache      Cache      `yaml:"cache"`
+	Session    Session    `yaml:"session"`
+	Mail       Mail       `yaml:"mail"`
+	Log        Log        `yaml:"log"`
+	RPC        RPC        `yaml:"rpc"`
+	Filesystem Filesystem `yaml:"filesystem"`
+	Vite       Vite       `yaml:"vite"`
+	Health     Health     `yaml:"health"`
+	Queue      Queue      `yaml:"queue"`
+	Schedule   Schedule   `yaml:"schedule"`
+	OAuth      OAuth      `yaml:"oauth"`
+	Auth       Auth       `yaml:"auth"`
+
+	warnings []string
+}
+
+// App holds the application identity and global flags.
+type App struct {
+	Name             string `yaml:"name" env:"APP_NAME"`
+	Key              string `yaml:"key" env:"APP_KEY"`
+	URL              string `yaml:"url" env:"APP_URL"`
+	Debug            bool   `yaml:"debug" env:"APP_DEBUG" default:"false"`
+	Renderer         string `yaml:"renderer" env:"RENDERER" default:"jet"`
+	ViewsTemplateDir string `yaml:"views_template_dir" env:"VIEWS_TEMPLATE_DIR" default:"resources/views"`
+}
+
+// HTTP holds the web server, rate limiter, proxy and maintenance settings.
+type HTTP struct {
+	Port                string   `yaml:"port" env:"HTTP_PORT" default:"4000"`
+	RateLimit           int      `yaml:"rate_limit" env:"HTTP_RATE_LIMIT" default:"100"`
+	RateDuration        int      `yaml:"rate_duration" env:"HTTP_RATE_DURATION" default:"1"`
+	ShutdownGracePeriod int      `yaml:"shutdown_grace_period" env:"SHUTDOWN_GRACE_PERIOD" default:"30"`
+	TrustedProxies      string   `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	TrustProxyHeaders   string   `yaml:"trust_proxy_headers" env:"TRUST_PROXY_HEADERS"`
+	MaintenanceURLs     []string `yaml:"maintenance_urls" env:"MAINTENANCE_URL"`
+}
+
+// Database holds the SQL connection settings.
+type Database struct {
+	Type     string `yaml:"type" env:"DATABASE_TYPE"`
+	Host     string `yaml:"host" env:"DATABASE_HOST" default:"localhost"`
+	Port     string `yaml:"port" env:"DATABASE_PORT" default:"5432"`
+	User     string `yaml:"user" env:"DATABASE_USER"`
+	Password string `yaml:"password" env:"DATABASE_PASSWORD"`
+	Name     string `yaml:"name" env:"DATABASE_NAME"`
+	SSLMode  string `yaml:"ssl_mode" env:"DATABASE_SSL_MODE"`
+}
+
+// Redis holds the connection pool settings shared by the cache, sessions and queue.
+type Redis struct {
+	Host        string `yaml:"host" env:"REDIS_HOST" default:"localhost"`
+	Port        string `yaml:"port" env:"REDIS_PORT" default:"6379"`
+	Username    string `yaml:"username" env:"REDIS_USERNAME"`
+	Password    string `yaml:"password" env:"REDIS_PASSWORD"`
+	Prefix      string `yaml:"prefix" env:"REDIS_PREFIX"`
+	MaxIdle     int    `yaml:"max_idle" env:"REDIS_MAX_IDLE" default:"50"`
+	MaxActive   int    `yaml:"max_active" env:"REDIS_MAX_ACTIVE_CONNECTIONS" default:"10000"`
+	IdleTimeout int    `yaml:"idle_timeout" env:"REDIS_TIMEOUT" default:"240"`
+}
+
+// Cache selects the cache driver.
+type Cache struct {
+	Driver string `yaml:"driver" env:"CACHE"`
+}
+
+// Session holds the session store and cookie settings.
+type Session struct {
+	Type            string `yaml:"type" env:"SESSION_TYPE"`
+	CleanupSchedule string `yaml:"cleanup_schedule" env:"SESSION_CLEANUP_SCHEDULE" default:"@every 5m"`
+	CookieName      string `yaml:"cookie_name" env:"COOKIE_NAME" default:"adele"`
+	CookieDomain    string `yaml:"cookie_domain" env:"COOKIE_DOMAIN" default:"localhost"`
+	CookieLifetime  int    `yaml:"cookie_lifetime" env:"COOKIE_LIFETIME" default:"1"`
+	CookiePersist   bool   `yaml:"cookie_persist" env:"COOKIE_PERSIST" default:"true"`
+	CookieSecure    bool   `yaml:"cookie_secure" env:"COOKIE_SECURE" default:"false"`
+}
+
+// Mail holds the SMTP and API mailer settings.
+type Mail struct {
+	Domain      string `yaml:"domain" env:"MAIL_DOMAIN"`
+	Host        string `yaml:"host" env:"SMTP_HOST"`
+	Port        int    `yaml:"port" env:"SMTP_PORT" default:"1025"`
+	Username    string `yaml:"username" env:"SMTP_USERNAME"`
+	Password    string `yaml:"password" env:"SMTP_PASSWORD"`
+	Encryption  string `yaml:"encryption" env:"SMTP_ENCRYPTION"`
+	FromName    string `yaml:"from_name" env:"MAILER_FROM_NAME"`
+	FromAddress string `yaml:"from_address" env:"MAILER_FROM_ADDRESS"`
+	API         string `yaml:"api" env:"MAILER_API"`
+	APIKey      string `yaml:"api_key" env:"MAILER_KEY"`
+	APIUrl      string `yaml:"api_url" env:"MAILER_URL"`
+}
+
+// Log holds the logger format and level.
+type Log struct {
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+	Level  string `yaml:"level" env:"LOG_LEVEL"`
+	Debug  bool   `yaml:"debug" env:"DEBUG" default:"false"`
+}
+
+// RPC holds the out of band control server settings.
+type RPC struct {
+	Disable bool   `yaml:"disable" env:"RPC_SERVER_DISABLE" default:"false"`
+	Addr    string `yaml:"addr" env:"RPC_SERVER_ADDR" default:"127.0.0.1"`
+	Port    string `yaml:"port" env:"RPC_SERVER_PORT" default:"4040"`
+}
+
+// Filesystem holds the remote filesystem credentials and upload limits. A remote
+// filesystem is configured only when its identifying key is set.
+type Filesystem struct {
+	S3Key            string   `yaml:"s3_key" env:"S3_KEY"`
+	S3Secret         string   `yaml:"s3_secret" env:"S3_SECRET"`
+	S3Region         string   `yaml:"s3_region" env:"S3_REGION"`
+	S3Bucket         string   `yaml:"s3_bucket" env:"S3_BUCKET"`
+	MinioEndpoint    string   `yaml:"minio_endpoint" env:"MINIO_ENDPOINT"`
+	MinioKey         string   `yaml:"minio_key" env:"MINIO_KEY"`
+	MinioSecret      string   `yaml:"minio_secret" env:"MINIO_SECRET"`
+	MinioUseSSL      bool     `yaml:"minio_use_ssl" env:"MINIO_USESSL" default:"false"`
+	MinioRegion      string   `yaml:"minio_region" env:"MINIO_REGION"`
+	MinioBucket      string   `yaml:"minio_bucket" env:"MINIO_BUCKET"`
+	SFTPHost         string   `yaml:"sftp_host" env:"SFTP_HOST"`
+	SFTPUser         string   `yaml:"sftp_user" env:"SFTP_USER"`
+	SFTPPassword     string   `yaml:"sftp_password" env:"SFTP_PASSWORD"`
+	SFTPPort         string   `yaml:"sftp_port" env:"SFTP_PORT"`
+	WebDAVHost       string   `yaml:"webdav_host" env:"WEBDAV_HOST"`
+	WebDAVUser       string   `yaml:"webdav_user" env:"WEBDAV_USER"`
+	WebDAVPassword   string   `yaml:"webdav_password" env:"WEBDAV_PASSWORD"`
+	MaxUploadSize    int64    `yaml:"max_upload_size" env:"FILE_MAX_UPLOAD_SIZE" default:"10485760"`
+	AllowedFileTypes []string `yaml:"allowed_file_types" env:"FILE_TYPES_ALLOWED"`
+}
+
+// Vite holds the asset bundler integration settings.
+type Vite struct {
+	Host            string `yaml:"host" env:"VITE_HOST"`
+	Port            string `yaml:"port" env:"VITE_PORT"`
+	RootDir         string `yaml:"root_dir" env:"VITE_ROOT_DIR"`
+	DistDir         string `yaml:"dist_dir" env:"VITE_DIST_DIR"`
+	Manifest        string `yaml:"manifest" env:"VITE_MANIFEST"`
+	DevelopmentMode string `yaml:"development_mode" env:"VITE_DEVELOPMENT_MODE"`
+}
+
+// Health holds the liveness and readiness endpoint settings.
+type Health struct {
+	LivenessPath  string `yaml:"liveness_path" env:"HEALTH_LIVENESS_PATH" default:"/healthz"`
+	ReadinessPath string `yaml:"readiness_path" env:"HEALTH_READINESS_PATH" default:"/readyz"`
+	Timeout       int    `yaml:"timeout" env:"HEALTH_CHECK_TIMEOUT" default:"5"`
+}
+
+// Queue holds the background job driver and worker settings. Durations are in
+// seconds.
+type Queue struct {
+	Type        string `yaml:"type" env:"QUEUE_TYPE" default:"memory"`
+	Default     string `yaml:"default" env:"QUEUE_DEFAULT" default:"default"`
+	MaxAttempts int    `yaml:"max_attempts" env:"QUEUE_MAX_ATTEMPTS" default:"3"`
+	Timeout     int    `yaml:"timeout" env:"QUEUE_TIMEOUT" default:"60"`
+	RetryAfter  int    `yaml:"retry_after" env:"QUEUE_RETRY_AFTER" default:"90"`
+	Concurrency int    `yaml:"concurrency" env:"QUEUE_CONCURRENCY" default:"1"`
+	Backoff     int    `yaml:"backoff" env:"QUEUE_BACKOFF" default:"5"`
+	Sleep       int    `yaml:"sleep" env:"QUEUE_SLEEP" default:"3"`
+}
+
+// Schedule selects where scheduled task locks are held. Only redis and database
+// locks coordinate on-one-server and without-overlapping tasks across servers.
+type Schedule struct {
+	Lock string `yaml:"lock" env:"SCHEDULE_LOCK" default:"memory"`
+}
+
+// OAuth holds the authorization server settings. Lifetimes are in seconds.
+type OAuth struct {
+	AccessTokenTTL  int    `yaml:"access_token_ttl" env:"OAUTH_ACCESS_TOKEN_TTL" default:"3600"`
+	RefreshTokenTTL int    `yaml:"refresh_token_ttl" env:"OAUTH_REFRESH_TOKEN_TTL" default:"2592000"`
+	AuthCodeTTL     int    `yaml:"auth_code_ttl" env:"OAUTH_AUTH_CODE_TTL" default:"600"`
+	LoginURL        string `yaml:"login_url" env:"OAUTH_LOGIN_URL" default:"/login"`
+	ConsentView     string `yaml:"consent_view" env:"OAUTH_CONSENT_VIEW" default:"oauth/authorize"`
+}
+
+// Auth holds the user authentication settings. Failed logins are throttled per
+// email address and per client IP when a cache is configured; a maximum of zero
+// turns that limit off. Durations are in seconds.
+type Auth struct {
+	TwoFactor             bool `yaml:"two_factor" env:"AUTH_TWO_FACTOR" default:"false"`
+	ThrottleMaxAttempts   int  `yaml:"throttle_max_attempts" env:"AUTH_THROTTLE_MAX_ATTEMPTS" default:"5"`
+	ThrottleMaxIPAttempts int  `yaml:"throttle_max_ip_attempts" env:"AUTH_THROTTLE_MAX_IP_ATTEMPTS" default:"20"`
+	ThrottleDecay         int  `yaml:"throttle_decay" env:"AUTH_THROTTLE_DECAY" default:"900"`
+	ThrottleLockout       int  `yaml:"throttle_lockout" env:"AUTH_THROTTLE_LOCKOUT" default:"60"`
+	ThrottleMaxLockout    int  `yaml:"throttle_max_lockout" env:"AUTH_THROTTLE_MAX_LOCKOUT" default:"3600"`
+
+	// Email verification links lead to VerifyPath and expire after VerifyExpiry
+	// minutes; a new link can be requested every VerifyResendInterval seconds.
+	VerifyPath           string `yaml:"verify_path" env:"AUTH_VERIFY_PATH" default:"/email/verify/confirm"`
+	VerifyNoticeURL      string `yaml:"verify_notice_url" env:"AUTH_VERIFY_NOTICE_URL" default:"/email/verify"`
+	VerifyExpiry         int    `yaml:"verify_expiry" env:"AUTH_VERIFY_EXPIRY" default:"60"`
+	VerifyResendInterval int    `yaml:"verify_resend_interval" env:"AUTH_VERIFY_RESEND_INTERVAL" default:"60"`
+
+	// The default user provider logs users in from UsersTable by LoginColumn;
+	// remember-me tokens are kept in RememberTable.
+	UsersTable    string `yaml:"users_table" env:"AUTH_USERS_TABLE" default:"users"`
+	LoginColumn   string `yaml:"login_column" env:"AUTH_LOGIN_COLUMN" default:"email"`
+	RememberTable string `yaml:"remember_table" env:"AUTH_REMEMBER_TABLE" default:"remember_tokens"`
+
+	// Passwords are hashed with Hasher, bcrypt or argon2id; weaker hashes are
+	// replaced when their users log in. Argon2Memory is in KiB.
+	Hasher            string `yaml:"hasher" env:"AUTH_HASHER" default:"bcrypt"`
+	BcryptCost        int    `yaml:"bcrypt_cost" env:"AUTH_BCRYPT_COST" default:"12"`
+	Argon2Memory      int    `yaml:"argon2_memory" env:"AUTH_ARGON2_MEMORY" default:"65536"`
+	Argon2Iterations  int    `yaml:"argon2_iterations" env:"AUTH_ARGON2_ITERATIONS" default:"3"`
+	Argon2Parallelism int    `yaml:"argon2_parallelism" env:"AUTH_ARGON2_PARALLELISM" default:"2"`
+}
+
+// ValidationError lists every configuration key that could not be parsed or
+// failed validation.
+type ValidationError struct {
+	Problems []string
+}
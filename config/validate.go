@@ -0,0 +1,268 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	cacheDrivers    = []string{"", "redis", "badger"}
+	hashers         = []string{"bcrypt", "argon2id"}
+	databaseTypes   = []string{"", "postgres", "postgresql", "pgx", "mysql", "mariadb"}
+	logFormats      = []string{"", "json", "text"}
+	logLevels       = []string{"", "panic", "fatal", "error", "warn", "warning", "info", "debug", "trace"}
+	mailAPIs        = []string{"", "mailgun", "sparkpost", "sendgrid"}
+	mailEncryptions = []string{"", "none", "ssl", "tls"}
+	queueTypes      = []string{"memory", "redis", "database"}
+	scheduleLocks   = []string{"memory", "redis", "database"}
+	renderers       = []string{"jet", "go"}
+	sessionTypes    = []string{"", "cookie", "memory", "redis", "postgres", "postgresql", "mysql", "mariadb"}
+	sqlSessionTypes = []string{"postgres", "postgresql", "mysql", "mariadb"}
+
+	// Names interpolated into SQL, optionally qualified with a schema.
+	sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+)
+
+// Check the loaded values against the rules of each section and return a problem
+// for every key that fails.
+func (c *Config) validate() []string {
+	var problems []string
+	add := func(key, format string, args ...interface{}) {
+		problems = append(problems, key+": "+fmt.Sprintf(format, args...))
+	}
+
+	if c.App.URL != "" {
+		if u, err := url.Parse(c.App.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			add("APP_URL", "must be an absolute URL, got %q", c.App.URL)
+		}
+	}
+	if !oneOf(renderers, c.App.Renderer) {
+		add("RENDERER", "must be one of %s, got %q", list(renderers), c.App.Renderer)
+	}
+
+	if !validPort(c.HTTP.Port) {
+		add("HTTP_PORT", "must be a port number between 1 and 65535, got %q", c.HTTP.Port)
+	}
+	if c.HTTP.RateLimit <= 0 {
+		add("HTTP_RATE_LIMIT", "must be greater than zero, got %d", c.HTTP.RateLimit)
+	}
+	if c.HTTP.RateDuration <= 0 {
+		add("HTTP_RATE_DURATION", "must be greater than zero, got %d", c.HTTP.RateDuration)
+	}
+	if c.HTTP.ShutdownGracePeriod < 0 {
+		add("SHUTDOWN_GRACE_PERIOD", "must not be negative, got %d", c.HTTP.ShutdownGracePeriod)
+	}
+
+	if !oneOf(databaseTypes, c.Database.Type) {
+		add("DATABASE_TYPE", "must be one of %s, got %q", list(databaseTypes), c.Database.Type)
+	}
+	if c.Database.Type != "" {
+		if !validPort(c.Database.Port) {
+			add("DATABASE_PORT", "must be a port number between 1 and 65535, got %q", c.Database.Port)
+		}
+		if c.Database.Name == "" {
+			add("DATABASE_NAME", "is required when DATABASE_TYPE is set")
+		}
+	}
+
+	if !oneOf(cacheDrivers, c.Cache.Driver) {
+		add("CACHE", "must be one of %s, got %q", list(cacheDrivers), c.Cache.Driver)
+	}
+
+	if !oneOf(sessionTypes, c.Session.Type) {
+		add("SESSION_TYPE", "must be one of %s, got %q", list(sessionTypes), c.Session.Type)
+	}
+	if oneOf(sqlSessionTypes, c.Session.Type) && c.Database.Type == "" {
+		add("SESSION_TYPE", "%q requires DATABASE_TYPE to be set", c.Session.Type)
+	}
+	if c.Session.CookieLifetime <= 0 {
+		add("COOKIE_LIFETIME", "must be greater than zero, got %d", c.Session.CookieLifetime)
+	}
+
+	if c.UsesRedis() {
+		if !validPort(c.Redis.Port) {
+			add("REDIS_PORT", "must be a port number between 1 and 65535, got %q", c.Redis.Port)
+		}
+		if c.Redis.MaxIdle < 0 {
+			add("REDIS_MAX_IDLE", "must not be negative, got %d", c.Redis.MaxIdle)
+		}
+		if c.Redis.MaxActive < 0 {
+			add("REDIS_MAX_ACTIVE_CONNECTIONS", "must not be negative, got %d", c.Redis.MaxActive)
+		}
+		if c.Redis.IdleTimeout < 0 {
+			add("REDIS_TIMEOUT", "must not be negative, got %d", c.Redis.IdleTimeout)
+		}
+	}
+
+	if c.Mail.Port < 1 || c.Mail.Port > 65535 {
+		add("SMTP_PORT", "must be a port number between 1 and 65535, got %d", c.Mail.Port)
+	}
+	if !oneOf(mailEncryptions, c.Mail.Encryption) {
+		add("SMTP_ENCRYPTION", "must be one of %s, got %q", list(mailEncryptions), c.Mail.Encryption)
+	}
+	if !oneOf(mailAPIs, c.Mail.API) {
+		add("MAILER_API", "must be one of %s, got %q", list(mailAPIs), c.Mail.API)
+	}
+
+	if !oneOf(logFormats, c.Log.Format) {
+		add("LOG_FORMAT", "must be one of %s, got %q", list(logFormats), c.Log.Format)
+	}
+	if !oneOf(logLevels, c.Log.Level) {
+		add("LOG_LEVEL", "must be one of %s, got %q", list(logLevels), c.Log.Level)
+	}
+
+	if !c.RPC.Disable && !validPort(c.RPC.Port) {
+		add("RPC_SERVER_PORT", "must be a port number between 1 and 65535, got %q", c.RPC.Port)
+	}
+
+	if c.Filesystem.MaxUploadSize <= 0 {
+		add("FILE_MAX_UPLOAD_SIZE", "must be greater than zero, got %d", c.Filesystem.MaxUploadSize)
+	}
+
+	if !strings.HasPrefix(c.Health.LivenessPath, "/") {
+		add("HEALTH_LIVENESS_PATH", "must start with /, got %q", c.Health.LivenessPath)
+	}
+	if !strings.HasPrefix(c.Health.ReadinessPath, "/") {
+		add("HEALTH_READINESS_PATH", "must start with /, got %q", c.Health.ReadinessPath)
+	}
+	if c.Health.Timeout <= 0 {
+		add("HEALTH_CHECK_TIMEOUT", "must be greater than zero, got %d", c.Health.Timeout)
+	}
+
+	if !oneOf(queueTypes, c.Queue.Type) {
+		add("QUEUE_TYPE", "must be one of %s, got %q", list(queueTypes), c.Queue.Type)
+	}
+	if strings.EqualFold(c.Queue.Type, "database") && c.Database.Type == "" {
+		add("QUEUE_TYPE", "%q requires DATABASE_TYPE to be set", c.Queue.Type)
+	}
+	if c.Queue.Default == "" {
+		add("QUEUE_DEFAULT", "must not be empty")
+	}
+	if c.Queue.MaxAttempts <= 0 {
+		add("QUEUE_MAX_ATTEMPTS", "must be greater than zero, got %d", c.Queue.MaxAttempts)
+	}
+	if c.Queue.Timeout <= 0 {
+		add("QUEUE_TIMEOUT", "must be greater than zero, got %d", c.Queue.Timeout)
+	}
+	if c.Queue.RetryAfter <= 0 {
+		add("QUEUE_RETRY_AFTER", "must be greater than zero, got %d", c.Queue.RetryAfter)
+	}
+	if c.Queue.Concurrency <= 0 {
+		add("QUEUE_CONCURRENCY", "must be greater than zero, got %d", c.Queue.Concurrency)
+	}
+	if c.Queue.Backoff < 0 {
+		add("QUEUE_BACKOFF", "must not be negative, got %d", c.Queue.Backoff)
+	}
+	if c.Queue.Sleep < 0 {
+		add("QUEUE_SLEEP", "must not be negative, got %d", c.Queue.Sleep)
+	}
+
+	if !oneOf(scheduleLocks, c.Schedule.Lock) {
+		add("SCHEDULE_LOCK", "must be one of %s, got %q", list(scheduleLocks), c.Schedule.Lock)
+	}
+	if strings.EqualFold(c.Schedule.Lock, "database") && c.Database.Type == "" {
+		add("SCHEDULE_LOCK", "%q requires DATABASE_TYPE to be set", c.Schedule.Lock)
+	}
+
+	if c.OAuth.AccessTokenTTL <= 0 {
+		add("OAUTH_ACCESS_TOKEN_TTL", "must be greater than zero, got %d", c.OAuth.AccessTokenTTL)
+	}
+	if c.OAuth.RefreshTokenTTL <= 0 {
+		add("OAUTH_REFRESH_TOKEN_TTL", "must be greater than zero, got %d", c.OAuth.RefreshTokenTTL)
+	}
+	if c.OAuth.AuthCodeTTL <= 0 {
+		add("OAUTH_AUTH_CODE_TTL", "must be greater than zero, got %d", c.OAuth.AuthCodeTTL)
+	}
+	if c.OAuth.LoginURL == "" {
+		add("OAUTH_LOGIN_URL", "must not be empty")
+	}
+
+	if c.Auth.ThrottleMaxAttempts < 0 {
+		add("AUTH_THROTTLE_MAX_ATTEMPTS", "must not be negative, got %d", c.Auth.ThrottleMaxAttempts)
+	}
+	if c.Auth.ThrottleMaxIPAttempts < 0 {
+		add("AUTH_THROTTLE_MAX_IP_ATTEMPTS", "must not be negative, got %d", c.Auth.ThrottleMaxIPAttempts)
+	}
+	if c.Auth.ThrottleDecay <= 0 {
+		add("AUTH_THROTTLE_DECAY", "must be greater than zero, got %d", c.Auth.ThrottleDecay)
+	}
+	if c.Auth.ThrottleLockout <= 0 {
+		add("AUTH_THROTTLE_LOCKOUT", "must be greater than zero, got %d", c.Auth.ThrottleLockout)
+	}
+	if c.Auth.ThrottleMaxLockout < c.Auth.ThrottleLockout {
+		add("AUTH_THROTTLE_MAX_LOCKOUT", "must not be less than AUTH_THROTTLE_LOCKOUT, got %d", c.Auth.ThrottleMaxLockout)
+	}
+	if !strings.HasPrefix(c.Auth.VerifyPath, "/") {
+		add("AUTH_VERIFY_PATH", "must start with /, got %q", c.Auth.VerifyPath)
+	}
+	if c.Auth.VerifyNoticeURL == "" {
+		add("AUTH_VERIFY_NOTICE_URL", "must not be empty")
+	}
+	if c.Auth.VerifyExpiry <= 0 {
+		add("AUTH_VERIFY_EXPIRY", "must be greater than zero, got %d", c.Auth.VerifyExpiry)
+	}
+	if c.Auth.VerifyResendInterval < 0 {
+		add("AUTH_VERIFY_RESEND_INTERVAL", "must not be negative, got %d", c.Auth.VerifyResendInterval)
+	}
+	for _, name := range []struct{ key, value string }{
+		{"AUTH_USERS_TABLE", c.Auth.UsersTable},
+		{"AUTH_LOGIN_COLUMN", c.Auth.LoginColumn},
+		{"AUTH_REMEMBER_TABLE", c.Auth.RememberTable},
+	} {
+		if !sqlIdentifier.MatchString(name.value) {
+			add(name.key, "must be a table or column name, got %q", name.value)
+		}
+	}
+	if !oneOf(hashers, c.Auth.Hasher) {
+		add("AUTH_HASHER", "must be one of %s, got %q", list(hashers), c.Auth.Hasher)
+	}
+	if c.Auth.BcryptCost < 4 || c.Auth.BcryptCost > 31 {
+		add("AUTH_BCRYPT_COST", "must be between 4 and 31, got %d", c.Auth.BcryptCost)
+	}
+	if c.Auth.Argon2Memory < 8*c.Auth.Argon2Parallelism {
+		add("AUTH_ARGON2_MEMORY", "must be at least 8 KiB per lane, got %d", c.Auth.Argon2Memory)
+	}
+	if c.Auth.Argon2Iterations <= 0 {
+		add("AUTH_ARGON2_ITERATIONS", "must be greater than zero, got %d", c.Auth.Argon2Iterations)
+	}
+	if c.Auth.Argon2Parallelism <= 0 || c.Auth.Argon2Parallelism > 255 {
+		add("AUTH_ARGON2_PARALLELISM", "must be between 1 and 255, got %d", c.Auth.Argon2Parallelism)
+	}
+
+	return problems
+}
+
+// Reports whether the cache, the session store, the queue or the scheduler locks
+// are configured to use Redis.
+func (c *Config) UsesRedis() bool {
+	return strings.EqualFold(c.Cache.Driver, "redis") ||
+		strings.EqualFold(c.Session.Type, "redis") ||
+		strings.EqualFold(c.Queue.Type, "redis") ||
+		strings.EqualFold(c.Schedule.Lock, "redis")
+}
+
+// Reports whether a value is one of the allowed values, ignoring case.
+func oneOf(allowed []string, value string) bool {
+	return slices.Contains(allowed, strings.ToLower(value))
+}
+
+// Format the allowed values for an error message, leaving out the empty value.
+func list(allowed []string) string {
+	var values []string
+	for _, v := range allowed {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return strings.Join(values, ", ")
+}
+
+// Reports whether a string is a TCP port number.
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
@@ -0,0 +1,203 @@
+package adele
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// Run a framework command passed to the application binary in place of starting
+// the web server. The adele CLI builds the application and runs it with the
+// command as its first argument, for example `adele queue:work` runs
+// `<app> queue:work --queue=emails`, so the command sees the jobs and services
+// the application registers. The returned bool is false when args do not name a
+// framework command and the caller should carry on starting the application.
+func (a *Adele) RunCommand(args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	switch args[0] {
+	case "queue:work":
+		return true, a.runQueueWorker(args[1:])
+	case "schedule:list":
+		return true, a.listSchedule(os.Stdout)
+	case "route:list":
+		return true, a.listRoutes(os.Stdout, args[1:])
+	}
+
+	return false, nil
+}
+
+// Run a queue worker until SIGINT or SIGTERM, or until its max-jobs or max-time
+// limit is reached. A signal stops the worker from reserving new jobs; jobs in
+// flight are finished before the application's subsystems are shut down.
+func (a *Adele) runQueueWorker(args []string) error {
+	flags := flag.NewFlagSet("queue:work", flag.ContinueOnError)
+	queues := flags.String("queue", "", "comma separated queues to work, highest priority first")
+	concurrency := flags.Int("concurrency", 0, "number of jobs to run at once")
+	maxJobs := flags.Int("max-jobs", 0, "stop after this many jobs")
+	maxTime := flags.Int("max-time", 0, "stop after this many seconds")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if a.Queue == nil {
+		return errors.New("the queue is not booted")
+	}
+	if strings.EqualFold(a.settings().Queue.Type, "memory") {
+		return errors.New("QUEUE_TYPE=memory keeps jobs inside the web process; set QUEUE_TYPE to redis or database to run a worker")
+	}
+
+	var names []string
+	for _, name := range strings.Split(*queues, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	w := a.NewQueueWorker(names...)
+	if *concurrency > 0 {
+		w.Concurrency = *concurrency
+	}
+	w.MaxJobs = *maxJobs
+	w.MaxTime = time.Duration(*maxTime) * time.Second
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if a.Log != nil {
+		a.Log.Infof("Processing jobs from the [%s] queues with %d workers", strings.Join(w.Queues, ", "), w.Concurrency)
+	}
+
+	if err := w.Run(ctx); err != nil {
+		return err
+	}
+
+	if a.Log != nil {
+		a.Log.Infof("Worker stopped after %d jobs", w.Processed())
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(a.settings().HTTP.ShutdownGracePeriod)*time.Second)
+	defer cancel()
+
+	if err := a.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+
+	return nil
+}
+
+// Print every scheduled task with its cron spec, options, next run time and the
+// result of its last recorded run.
+func (a *Adele) listSchedule(out io.Writer) error {
+	if a.Schedule == nil {
+		return errors.New("the scheduler is not booted")
+	}
+
+	tasks := a.Schedule.Tasks()
+	if len(tasks) == 0 {
+		fmt.Fprintln(out, "No scheduled tasks.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSCHEDULE\tNEXT RUN\tLAST RUN\tDURATION\tRESULT\tOPTIONS")
+	for _, t := range tasks {
+		lastRun, duration, result := "-", "-", "-"
+		if run, ok := t.LastRun(); ok {
+			lastRun = run.StartedAt.Local().Format("2006-01-02 15:04:05")
+			duration = run.Duration.Round(time.Millisecond).String()
+			result = string(run.Status)
+			if run.Error != "" {
+				result += ": " + run.Error
+			}
+		}
+
+		next := "-"
+		if n := t.Next(); !n.IsZero() {
+			next = n.Local().Format("2006-01-02 15:04:05")
+		}
+
+		options := strings.Join(t.Options(), ", ")
+		if options == "" {
+			options = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", t.Name, t.Spec, next, lastRun, duration, result, options)
+	}
+
+	return w.Flush()
+}
+
+// Print the routes the application serves with their full pattern, name,
+// scopes and middleware, optionally only those of one method or under a path
+// prefix, as a table or as JSON.
+func (a *Adele) listRoutes(out io.Writer, args []string) error {
+	flags := flag.NewFlagSet("route:list", flag.ContinueOnError)
+	method := flags.String("method", "", "only list routes of this HTTP method")
+	prefix := flags.String("prefix", "", "only list routes whose pattern starts with this path")
+	asJSON := flags.Bool("json", false, "print the routes as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if a.Routes == nil {
+		return errors.New("the router is not booted")
+	}
+
+	all, err := a.Routes.ListRoutes()
+	if err != nil {
+		return err
+	}
+
+	routes := all[:0]
+	for _, route := range all {
+		if *method != "" && !strings.EqualFold(route.Method, *method) {
+			continue
+		}
+		if !strings.HasPrefix(route.Pattern, *prefix) {
+			continue
+		}
+		routes = append(routes, route)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(routes)
+	}
+
+	if len(routes) == 0 {
+		fmt.Fprintln(out, "No routes.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METHOD\tPATTERN\tNAME\tSCOPES\tMIDDLEWARE")
+	for _, route := range routes {
+		name, scopes, middleware := route.Name, strings.Join(route.Scopes, " "), strings.Join(route.Middleware, ", ")
+		if name == "" {
+			name = "-"
+		}
+		if scopes == "" {
+			scopes = "-"
+		}
+		if middleware == "" {
+			middleware = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", route.Method, route.Pattern, name, scopes, middleware)
+	}
+
+	return w.Flush()
+}
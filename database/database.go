@@ -82,6 +82,37 @@ func OpenDB(dbType string, config *DataSourceName) (*sql.DB, error) {
 	return db, nil
 }
 
+// Dialect converts a database type, the value of DATABASE_TYPE, to the SQL
+// dialect queries are written in for it: "mysql" for MySQL and MariaDB, and
+// "postgres" for anything else.
+func Dialect(dbType string) string {
+	if getDBDriver(dbType) == "mysql" {
+		return "mysql"
+	}
+	return "postgres"
+}
+
+// Rebind rewrites the ? placeholders in a query to the $n form postgres
+// expects. Queries in the mysql dialect are returned as they are.
+func Rebind(dialect, query string) string {
+	if dialect == "mysql" {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
 // Convert database type to driver name
 func getDBDriver(dbType string) string {
 	switch strings.ToLower(strings.TrimSpace(dbType)) {
@@ -208,3 +208,21 @@ func TestNewSessionSql(t *testing.T) {
 		t.Fatalf("nil returned when session was expected")
 	}
 }
+
+func TestDialectAndRebind(t *testing.T) {
+	for dbType, want := range map[string]string{
+		"mysql": "mysql", " MariaDB ": "mysql", "postgres": "postgres", "pgx": "postgres", "": "postgres",
+	} {
+		if got := Dialect(dbType); got != want {
+			t.Errorf("Dialect(%q) = %q, want %q", dbType, got, want)
+		}
+	}
+
+	query := "SELECT id FROM users WHERE email = ? AND active = ?"
+	if got := Rebind("mysql", query); got != query {
+		t.Errorf("expected a mysql query to be left alone, got %q", got)
+	}
+	if got := Rebind("postgres", query); got != "SELECT id FROM users WHERE email = $1 AND active = $2" {
+		t.Errorf("unexpected postgres query %q", got)
+	}
+}
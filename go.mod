@@ -8,8 +8,10 @@ replace google.golang.org/genproto => google.golang.org/genproto/googleapis/api
 
 require (
 	github.com/CloudyKit/jet/v6 v6.3.1
+	github.com/DATA-DOG/go-sqlmock v1.5.2
 	github.com/ainsleyclark/go-mail v1.0.3
 	github.com/alexedwards/scs/v2 v2.9.0
+	github.com/alicebob/miniredis/v2 v2.37.0
 	github.com/asaskevich/govalidator v0.0.0-20230301143203-a9d515a09cc2
 	github.com/aws/aws-sdk-go v1.55.8
 	github.com/bwmarrin/go-alone v0.0.0-20190806015146-742bb55d1631
@@ -32,6 +34,7 @@ require (
 	github.com/ory/dockertest/v3 v3.12.0
 	github.com/petaki/inertia-go v1.5.0
 	github.com/pkg/sftp v1.13.9
+	github.com/pquerna/otp v1.5.0
 	github.com/robfig/cron/v3 v3.0.1
 	github.com/sirupsen/logrus v1.9.3
 	github.com/studio-b12/gowebdav v0.10.0
@@ -59,6 +62,7 @@ require (
 	github.com/SparkPost/gosparkpost v0.2.0 // indirect
 	github.com/andybalholm/cascadia v1.3.3 // indirect
 	github.com/aymerick/douceur v0.2.0 // indirect
+	github.com/boombuler/barcode v1.0.1-0.20190219062509-6c824513bacc // indirect
 	github.com/cenkalti/backoff/v4 v4.3.0 // indirect
 	github.com/cespare/xxhash v1.1.0 // indirect
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
@@ -181,6 +185,7 @@ require (
 	github.com/xeipuuv/gojsonpointer v0.0.0-20190905194746-02993c407bfb // indirect
 	github.com/xeipuuv/gojsonreference v0.0.0-20180127040603-bd5ef7bd5415 // indirect
 	github.com/xeipuuv/gojsonschema v1.2.0 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
 	github.com/yusufpapurcu/wmi v1.2.4 // indirect
 	github.com/zeebo/xxh3 v1.0.2 // indirect
 	go.opencensus.io v0.24.0 // indirect
@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net"
+
+	"github.com/cidekar/adele-framework/cache"
+	"github.com/cidekar/adele-framework/filesystem"
+)
+
+// The prefix of the keys written and removed by the cache round-trip check.
+// Each probe adds a random suffix so replicas sharing a cache cannot overwrite
+// or remove each other's key mid-check.
+const cacheCheckKey = "adele:health:check:"
+
+// Check the database pool by pinging it.
+func DatabaseCheck(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// Check the cache with a write, read and delete round-trip.
+func CacheCheck(c cache.Cache) CheckFunc {
+	return func(ctx context.Context) error {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		want := hex.EncodeToString(b)
+		key := cacheCheckKey + want
+
+		if err := c.Set(key, want, 60); err != nil {
+			return fmt.Errorf("set: %w", err)
+		}
+
+		got, err := c.Get(key)
+		if err != nil {
+			return fmt.Errorf("get: %w", err)
+		}
+		if fmt.Sprint(got) != want {
+			return fmt.Errorf("get: read back %v, want %s", got, want)
+		}
+
+		if err := c.Forget(key); err != nil {
+			return fmt.Errorf("forget: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// Check a remote filesystem by listing a prefix that is not expected to exist,
+// which proves the credentials and the connection without transferring files.
+func FileSystemCheck(fs filesystem.FS) CheckFunc {
+	return func(ctx context.Context) error {
+		_, err := fs.List(".adele-health")
+		return err
+	}
+}
+
+// Check that a TCP address, such as the SMTP server, accepts connections.
+func DialCheck(addr string) CheckFunc {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
@@ -0,0 +1,155 @@
+// Package health reports the liveness and readiness of an Adele application.
+//
+// A Checker runs named checks concurrently, each bounded by a timeout, and serves
+// the results as JSON with a per-check status and latency. Built-in checks cover
+// the database pool, the cache, remote filesystems and the mail transport, and
+// applications register their own with Register and RegisterLiveness.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The default time allowed for a single check.
+const DefaultTimeout = 5 * time.Second
+
+// Create a new checker with no checks registered. A timeout of zero or less uses
+// DefaultTimeout.
+func New(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Checker{Timeout: timeout}
+}
+
+// Register a readiness check. Readiness checks cover the dependencies the
+// application needs to serve traffic; a failing check takes the instance out of
+// rotation without restarting it.
+func (c *Checker) Register(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readiness = append(c.readiness, check{name: name, fn: fn})
+}
+
+// Register a liveness check. Liveness checks should only fail when the process
+// cannot recover on its own and needs to be restarted.
+func (c *Checker) RegisterLiveness(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.liveness = append(c.liveness, check{name: name, fn: fn})
+}
+
+// Run the liveness checks. With no liveness checks registered the report is ok,
+// which means the process is up and serving requests.
+func (c *Checker) Live(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := append([]check(nil), c.liveness...)
+	c.mu.RUnlock()
+
+	return c.run(ctx, checks)
+}
+
+// Run the readiness checks.
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := append([]check(nil), c.readiness...)
+	c.mu.RUnlock()
+
+	return c.run(ctx, checks)
+}
+
+// Serve the liveness report as JSON.
+func (c *Checker) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, c.Live(r.Context()))
+}
+
+// Serve the readiness report as JSON.
+func (c *Checker) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, c.Ready(r.Context()))
+}
+
+// Mount the liveness and readiness handlers on a router. An empty path skips
+// that endpoint.
+func (c *Checker) Mount(r Router, livenessPath, readinessPath string) {
+	if livenessPath != "" {
+		r.Get(livenessPath, c.LivenessHandler)
+	}
+	if readinessPath != "" {
+		r.Get(readinessPath, c.ReadinessHandler)
+	}
+}
+
+// Run checks concurrently and collect their results in registration order.
+func (c *Checker) run(ctx context.Context, checks []check) Report {
+	report := Report{Status: StatusOK, Checks: make([]Result, len(checks))}
+
+	var wg sync.WaitGroup
+	for i, chk := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = c.runCheck(ctx, chk)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+// Run one check within the checker's timeout, recovering from a panicking check.
+func (c *Checker) runCheck(ctx context.Context, chk check) (result Result) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	result = Result{Name: chk.name, Status: StatusOK}
+	start := time.Now()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				done <- errors.New("check panicked")
+			}
+		}()
+		done <- chk.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// Write a report with 200 when every check passed and 503 otherwise.
+func writeReport(w http.ResponseWriter, report Report) {
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,208 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cidekar/adele-framework/mux"
+)
+
+// memoryCache is a minimal cache.Cache used to exercise the round-trip check.
+type memoryCache struct {
+	items map[string]interface{}
+	err   error
+}
+
+func (m *memoryCache) Has(key string) (bool, error) { _, ok := m.items[key]; return ok, nil }
+func (m *memoryCache) Get(key string) (interface{}, error) {
+	return m.items[key], m.err
+}
+func (m *memoryCache) Set(key string, value interface{}, expires ...int) error {
+	m.items[key] = value
+	return nil
+}
+func (m *memoryCache) Forget(key string) error   { delete(m.items, key); return nil }
+func (m *memoryCache) EmptyByMatch(string) error { return nil }
+func (m *memoryCache) Empty() error              { return nil }
+
+func TestReady_AllChecksPass(t *testing.T) {
+	c := New(time.Second)
+	c.Register("one", func(ctx context.Context) error { return nil })
+	c.Register("two", func(ctx context.Context) error { return nil })
+
+	report := c.Ready(context.Background())
+	if report.Status != StatusOK {
+		t.Errorf("expected ok, got %s", report.Status)
+	}
+	if len(report.Checks) != 2 || report.Checks[0].Name != "one" || report.Checks[1].Name != "two" {
+		t.Errorf("expected checks in registration order, got %+v", report.Checks)
+	}
+}
+
+func TestReady_FailingCheck(t *testing.T) {
+	c := New(time.Second)
+	c.Register("ok", func(ctx context.Context) error { return nil })
+	c.Register("broken", func(ctx context.Context) error { return errors.New("down") })
+
+	report := c.Ready(context.Background())
+	if report.Status != StatusFail {
+		t.Errorf("expected fail, got %s", report.Status)
+	}
+	if report.Checks[1].Status != StatusFail || report.Checks[1].Error != "down" {
+		t.Errorf("unexpected result: %+v", report.Checks[1])
+	}
+}
+
+func TestReady_Timeout(t *testing.T) {
+	c := New(20 * time.Millisecond)
+	c.Register("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	report := c.Ready(context.Background())
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("check should have been abandoned at the timeout")
+	}
+	if report.Checks[0].Status != StatusFail {
+		t.Errorf("expected slow check to fail, got %+v", report.Checks[0])
+	}
+}
+
+func TestReady_PanickingCheck(t *testing.T) {
+	c := New(time.Second)
+	c.Register("panics", func(ctx context.Context) error { panic("boom") })
+
+	if report := c.Ready(context.Background()); report.Status != StatusFail {
+		t.Errorf("expected fail, got %s", report.Status)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	c := New(time.Second)
+	c.Register("broken", func(ctx context.Context) error { return errors.New("down") })
+
+	r := mux.NewRouter()
+	c.Mount(r, "/healthz", "/readyz")
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("expected liveness 200, got %d", res.StatusCode)
+	}
+
+	res, err = http.Get(ts.URL + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected readiness 503, got %d", res.StatusCode)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected JSON content type, got %q", ct)
+	}
+
+	var report Report
+	if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if report.Status != StatusFail || len(report.Checks) != 1 || report.Checks[0].Name != "broken" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestDatabaseCheck(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectPing()
+	if err := DatabaseCheck(db)(context.Background()); err != nil {
+		t.Errorf("expected ping to pass, got %v", err)
+	}
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	if err := DatabaseCheck(db)(context.Background()); err == nil {
+		t.Error("expected ping failure")
+	}
+}
+
+func TestCacheCheck(t *testing.T) {
+	c := &memoryCache{items: map[string]interface{}{}}
+	if err := CacheCheck(c)(context.Background()); err != nil {
+		t.Errorf("expected round-trip to pass, got %v", err)
+	}
+	if len(c.items) != 0 {
+		t.Error("expected the check key to be removed")
+	}
+
+	c.err = errors.New("unavailable")
+	if err := CacheCheck(c)(context.Background()); err == nil {
+		t.Error("expected round-trip failure")
+	}
+}
+
+// A cache shared by replicas, recording the keys each probe writes.
+type sharedCache struct {
+	memoryCache
+	keys []string
+}
+
+func (s *sharedCache) Set(key string, value interface{}, expires ...int) error {
+	s.keys = append(s.keys, key)
+	return s.memoryCache.Set(key, value, expires...)
+}
+
+func TestCacheCheck_KeyPerProbe(t *testing.T) {
+	c := &sharedCache{memoryCache: memoryCache{items: map[string]interface{}{}}}
+	first, second := CacheCheck(c), CacheCheck(c)
+
+	for _, check := range []CheckFunc{first, second, first} {
+		if err := check(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, key := range c.keys {
+		if !strings.HasPrefix(key, cacheCheckKey) || seen[key] {
+			t.Errorf("expected a unique check key per probe, got %q in %q", key, c.keys)
+		}
+		seen[key] = true
+	}
+}
+
+func TestDialCheck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+
+	if err := DialCheck(addr)(context.Background()); err != nil {
+		t.Errorf("expected dial to pass, got %v", err)
+	}
+
+	ln.Close()
+	if err := DialCheck(addr)(context.Background()); err == nil {
+		t.Error("expected dial to a closed listener to fail")
+	}
+}
@@ -0,0 +1,54 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Status is the outcome of a single check or of a whole report.
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusFail Status = "fail"
+)
+
+// CheckFunc reports the health of one dependency by returning a non-nil error
+// when it is unavailable. It should return promptly once ctx is done.
+type CheckFunc func(ctx context.Context) error
+
+// Checker holds the registered liveness and readiness checks and serves them as
+// JSON over HTTP.
+type Checker struct {
+	Timeout time.Duration
+
+	mu        sync.RWMutex
+	liveness  []check
+	readiness []check
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name      string  `json:"name"`
+	Status    Status  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the JSON body served by the liveness and readiness endpoints.
+type Report struct {
+	Status Status   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Router is the part of a router the checker needs to mount its endpoints.
+type Router interface {
+	Get(pattern string, handler http.HandlerFunc)
+}
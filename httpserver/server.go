@@ -1,13 +1,19 @@
 // Package httpserver constructs and starts the HTTP server for an Adele application.
 //
 // It builds a net/http.Server configured from the application's environment,
-// routes, and logger, then listens for and serves incoming connections.
+// routes, and logger, then listens for and serves incoming connections until the
+// process is signalled to stop, at which point it shuts the application down.
 package httpserver
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/cidekar/adele-framework"
@@ -17,7 +23,7 @@ import (
 // Create a new http server for use with the adele skeleton application.
 func NewServer(adele *adele.Adele) *http.Server {
 	return &http.Server{
-		Addr:         fmt.Sprintf(":%s", adele.Helpers.Getenv("HTTP_PORT", "4000")),
+		Addr:         fmt.Sprintf(":%s", port(adele)),
 		ErrorLog:     log.New(adele.Log.WriterLevel(logrus.ErrorLevel), "", 0),
 		Handler:      adele.Routes,
 		IdleTimeout:  30 * time.Second,
@@ -29,7 +35,72 @@ func NewServer(adele *adele.Adele) *http.Server {
 // Creates a new http server, listens on the TCP network address srv.Addr and then calls
 // server to handle requests on incoming connections. Accepted connections are configured
 // to enable TCP keep-alives.
+//
+// Start blocks until the process receives SIGINT or SIGTERM. The server then stops
+// accepting new connections and waits up to SHUTDOWN_GRACE_PERIOD seconds (default 30)
+// for in-flight requests to finish before the application's subsystems are shut down.
 func Start(adele *adele.Adele) error {
-	server := NewServer(adele)
-	return server.ListenAndServe()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	return run(ctx, adele, NewServer(adele))
+}
+
+// Start the scheduler and serve requests until the context is cancelled, then drain the server and shut the
+// application down within the configured grace period.
+func run(ctx context.Context, app *adele.Adele, server *http.Server) error {
+	if app.Scheduler != nil {
+		app.Scheduler.Start()
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	app.Log.Info("shutting down http server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), gracePeriod(app))
+	defer cancel()
+
+	var errs []error
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("http server: %w", err))
+	}
+	if err := <-serverErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, err)
+	}
+	if err := app.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Read the port to listen on from the application configuration, falling back to
+// the environment.
+func port(app *adele.Adele) string {
+	if app.Config != nil {
+		return app.Config.HTTP.Port
+	}
+	return app.Helpers.Getenv("HTTP_PORT", "4000")
+}
+
+// Read the shutdown grace period, in seconds, from the application configuration,
+// falling back to the environment.
+func gracePeriod(app *adele.Adele) time.Duration {
+	if app.Config != nil {
+		return time.Duration(app.Config.HTTP.ShutdownGracePeriod) * time.Second
+	}
+	seconds, err := strconv.Atoi(app.Helpers.Getenv("SHUTDOWN_GRACE_PERIOD", "30"))
+	if err != nil || seconds < 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(seconds) * time.Second
 }
@@ -1,6 +1,7 @@
 package httpserver
 
 import (
+	"context"
 	"os"
 	"strings"
 	"testing"
@@ -150,3 +151,63 @@ func TestStart_InvalidPort(t *testing.T) {
 		t.Errorf("Expected error about invalid port/address, got: %v", err)
 	}
 }
+
+func TestRun_ShutdownOnCancel(t *testing.T) {
+	os.Setenv("SHUTDOWN_GRACE_PERIOD", "5")
+	defer os.Unsetenv("SHUTDOWN_GRACE_PERIOD")
+
+	app := &adele.Adele{
+		Routes: mux.NewRouter(),
+		Log:    logrus.New(),
+	}
+
+	var hookCalled bool
+	app.RegisterShutdownHook(func(ctx context.Context) error {
+		hookCalled = true
+		return nil
+	})
+
+	server := NewServer(app)
+	server.Addr = "127.0.0.1:0"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- run(ctx, app, server)
+	}()
+
+	cancel()
+
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Fatalf("Expected clean shutdown, got: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Test timed out - server should have shut down")
+	}
+
+	if !hookCalled {
+		t.Error("Expected shutdown hook to be called")
+	}
+}
+
+func TestGracePeriod(t *testing.T) {
+	app := &adele.Adele{}
+
+	os.Unsetenv("SHUTDOWN_GRACE_PERIOD")
+	if got := gracePeriod(app); got != 30*time.Second {
+		t.Errorf("Expected default grace period 30s, got %v", got)
+	}
+
+	os.Setenv("SHUTDOWN_GRACE_PERIOD", "7")
+	defer os.Unsetenv("SHUTDOWN_GRACE_PERIOD")
+	if got := gracePeriod(app); got != 7*time.Second {
+		t.Errorf("Expected grace period 7s, got %v", got)
+	}
+
+	os.Setenv("SHUTDOWN_GRACE_PERIOD", "invalid")
+	if got := gracePeriod(app); got != 30*time.Second {
+		t.Errorf("Expected fallback grace period 30s, got %v", got)
+	}
+}
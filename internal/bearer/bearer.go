@@ -0,0 +1,49 @@
+// Package bearer holds the bearer token handling shared by the auth and oauth
+// packages: reading the token of a request, hashing tokens for storage and
+// matching the scopes they grant.
+package bearer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Token extracts the token from an Authorization: Bearer header.
+func Token(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// Hash hashes a token, code or secret for storage and lookup. These are
+// generated randomly rather than chosen by users, so a fast unsalted hash is
+// enough.
+func Hash(plainText string) string {
+	sum := sha256.Sum256([]byte(plainText))
+	return hex.EncodeToString(sum[:])
+}
+
+// Grants reports whether granted covers every one of wanted; "*" grants every
+// scope.
+func Grants(granted, wanted []string) bool {
+	for _, want := range wanted {
+		ok := false
+		for _, have := range granted {
+			if have == "*" || have == want {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
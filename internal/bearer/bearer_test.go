@@ -0,0 +1,64 @@
+package bearer
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestToken(t *testing.T) {
+	tests := map[string]struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		"bearer":       {"Bearer abc", "abc", true},
+		"lower case":   {"bearer abc", "abc", true},
+		"padded":       {"Bearer  abc ", "abc", true},
+		"missing":      {"", "", false},
+		"empty token":  {"Bearer ", "", false},
+		"other scheme": {"Basic abc", "", false},
+		"no scheme":    {"abc", "", false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+
+			got, ok := Token(r)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Token(%q) = %q, %v; want %q, %v", tt.header, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	if Hash("abc") != Hash("abc") || Hash("abc") == Hash("abd") {
+		t.Error("expected the hash to depend on the token only")
+	}
+	if len(Hash("abc")) != 64 {
+		t.Errorf("expected a hex encoded SHA-256 hash, got %q", Hash("abc"))
+	}
+}
+
+func TestGrants(t *testing.T) {
+	tests := []struct {
+		granted, wanted []string
+		want            bool
+	}{
+		{[]string{"photos:read", "photos:write"}, []string{"photos:read"}, true},
+		{[]string{"photos:read"}, []string{"photos:read", "photos:write"}, false},
+		{[]string{"*"}, []string{"photos:delete"}, true},
+		{nil, nil, true},
+		{nil, []string{"photos:read"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := Grants(tt.granted, tt.wanted); got != tt.want {
+			t.Errorf("Grants(%q, %q) = %v, want %v", tt.granted, tt.wanted, got, tt.want)
+		}
+	}
+}
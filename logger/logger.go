@@ -1,31 +1,47 @@
 // Package logger configures application logging for Adele built on logrus.
 //
-// It creates loggers whose format and level are driven by environment variables and
-// provides structured HTTP request logging middleware for incoming requests.
+// It creates loggers whose format and level are driven by the application's log
+// configuration or environment variables, and provides structured HTTP request
+// logging middleware for incoming requests.
 package logger
 
 import (
-	"os"
 	"strings"
 
+	"github.com/cidekar/adele-framework/config"
 	"github.com/sirupsen/logrus"
 )
 
-// Configure and initialize a new Logrus logger instance based on environment variables.
+// Configure and initialize a new Logrus logger instance from the log settings of
+// the .env file in the working directory and the environment. A setting that
+// cannot be parsed is left at its zero value and logged as a warning.
 func CreateLogger() *logrus.Logger {
+	var c config.Log
+	err := config.Load(".", &c)
+
+	log := NewLogger(c)
+	if err != nil {
+		log.Warn(err)
+	}
+	return log
+}
+
+// Configure and initialize a new Logrus logger instance from the application's
+// log configuration.
+func NewLogger(c config.Log) *logrus.Logger {
 
 	log := logrus.New()
 
-	if os.Getenv("LOG_FORMAT") == "JSON" {
+	if strings.EqualFold(c.Format, "JSON") {
 		log.SetFormatter(&logrus.JSONFormatter{})
 	} else {
 		log.SetFormatter(&logrus.TextFormatter{})
 	}
 
-	if os.Getenv("DEBUG") == "true" {
+	if c.Debug {
 		log.SetLevel(logrus.DebugLevel)
-	} else if os.Getenv("LOG_LEVEL") != "" {
-		log.SetLevel(GetLogLevel(os.Getenv("LOG_LEVEL")))
+	} else if c.Level != "" {
+		log.SetLevel(GetLogLevel(c.Level))
 	}
 
 	return log
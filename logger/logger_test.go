@@ -4,6 +4,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/cidekar/adele-framework/config"
 	"github.com/sirupsen/logrus"
 )
 
@@ -133,3 +134,19 @@ func TestGetLogLevel_EdgeCases(t *testing.T) {
 		t.Errorf("GetLogLevel(\"\") should return InfoLevel, got %v", result)
 	}
 }
+
+func TestNewLogger(t *testing.T) {
+	log := NewLogger(config.Log{Format: "json", Level: "warn"})
+
+	if _, ok := log.Formatter.(*logrus.JSONFormatter); !ok {
+		t.Errorf("expected JSON formatter, got %T", log.Formatter)
+	}
+	if log.Level != logrus.WarnLevel {
+		t.Errorf("expected warn level, got %v", log.Level)
+	}
+
+	log = NewLogger(config.Log{Level: "error", Debug: true})
+	if log.Level != logrus.DebugLevel {
+		t.Errorf("expected debug to override level, got %v", log.Level)
+	}
+}
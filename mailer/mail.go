@@ -7,11 +7,13 @@ package mailer
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"text/template"
 	"time"
 
@@ -23,20 +25,64 @@ import (
 
 // Listen on the mail channel and send when a payload is received.
 // The method will run continually in the background and send error
-// or success messages on the results channel.
+// or success messages on the results channel. Once the Done channel is
+// closed the listener sends the messages still queued on Jobs, closes
+// Stopped and returns; Stop waits for that.
 func (m *Mail) ListenForMail() {
-	for {
-		msg := <-m.Jobs // listen for jobs
-		err := m.Send(msg)
+	atomic.StoreInt32(&m.listening, 1)
+	if m.Stopped != nil {
+		defer close(m.Stopped)
+	}
 
-		if err != nil {
-			m.Results <- Result{false, err}
-		} else {
-			m.Results <- Result{true, nil}
+	for {
+		select {
+		case msg := <-m.Jobs: // listen for jobs
+			m.deliver(msg)
+		case <-m.Done:
+			for {
+				select {
+				case msg := <-m.Jobs:
+					m.deliver(msg)
+				default:
+					return
+				}
+			}
 		}
 	}
 }
 
+// Close the Done channel and wait for a running listener to drain the Jobs
+// channel and return, or for ctx to end. Without a listener there is nothing
+// to wait for.
+func (m *Mail) Stop(ctx context.Context) error {
+	if m.Done != nil {
+		close(m.Done)
+	}
+
+	if atomic.LoadInt32(&m.listening) == 0 || m.Stopped == nil {
+		return nil
+	}
+
+	select {
+	case <-m.Stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Send a message taken off the Jobs channel and report the outcome on the
+// Results channel.
+func (m *Mail) deliver(msg Message) {
+	err := m.Send(msg)
+
+	if err != nil {
+		m.Results <- Result{false, err}
+	} else {
+		m.Results <- Result{true, nil}
+	}
+}
+
 // Determines the appropriate method for sending an email message.
 // If a third-party API is configured (API name is set and not "smtp", and both APIKey and APIUrl are provided),
 // it delegates the sending to chooseAPI. Otherwise, it defaults to sending the message via SMTP.
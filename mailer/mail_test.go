@@ -1,8 +1,11 @@
 package mailer
 
 import (
+	"context"
 	"errors"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestMail_SendSMTPMessage(t *testing.T) {
@@ -150,3 +153,64 @@ func TestMail_chooseAPI(t *testing.T) {
 	}
 
 }
+
+func TestMail_ListenForMailDrainsJobs(t *testing.T) {
+	m := &Mail{
+		API:     "unknown",
+		APIKey:  "1234",
+		APIUrl:  "https://www.fakemail.com",
+		Jobs:    make(chan Message, 5),
+		Results: make(chan Result, 5),
+		Done:    make(chan struct{}),
+		Stopped: make(chan struct{}),
+	}
+
+	for i := 0; i < 3; i++ {
+		m.Jobs <- Message{To: "you@there.com", Subject: "test"}
+	}
+
+	// With Done already closed the listener only sends the queued messages
+	// while draining Jobs before it returns.
+	close(m.Done)
+	m.ListenForMail()
+
+	if len(m.Jobs) != 0 || len(m.Results) != 3 {
+		t.Errorf("expected the 3 queued messages to be sent, got %d left and %d results", len(m.Jobs), len(m.Results))
+	}
+
+	select {
+	case <-m.Stopped:
+	default:
+		t.Error("expected Stopped to be closed once the listener returned")
+	}
+}
+
+func TestMail_StopWaitsForListener(t *testing.T) {
+	m := &Mail{
+		Jobs:    make(chan Message),
+		Results: make(chan Result),
+		Done:    make(chan struct{}),
+		Stopped: make(chan struct{}),
+	}
+
+	if err := (&Mail{Done: make(chan struct{})}).Stop(context.Background()); err != nil {
+		t.Errorf("expected a mailer without a listener to stop at once, got %v", err)
+	}
+
+	go m.ListenForMail()
+	for atomic.LoadInt32(&m.listening) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error: %v", err)
+	}
+
+	select {
+	case <-m.Stopped:
+	default:
+		t.Error("expected Stop to return after the listener")
+	}
+}
@@ -12,9 +12,13 @@ type Mail struct {
 	FromName    string
 	Jobs        chan Message
 	Results     chan Result
+	Done        chan struct{}
+	Stopped     chan struct{}
 	API         string
 	APIKey      string
 	APIUrl      string
+
+	listening int32
 }
 
 // Message is the type for an email message
@@ -3,21 +3,23 @@ package middleware
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 )
 
 func (a *Middleware) CheckForMaintenanceMode(next http.Handler) http.Handler {
+	// urls accessible while application is in maintenance mode e.g., health check url.
+	urls := a.MaintenanceURLs
+	if urls == nil {
+		urls = a.httpConfig().MaintenanceURLs
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		if a.MaintenanceMode {
-			// urls accessible while application is in maintenance mode e.g., health check url.
-			urls := strings.Split(os.Getenv("MAINTENANCE_URL"), ",")
-			if len(urls) > 1 {
-				for _, url := range urls {
-					if strings.Contains(r.URL.Path, url) {
-						return
-					}
+			for _, url := range urls {
+				if url != "" && strings.Contains(r.URL.Path, url) {
+					next.ServeHTTP(w, r)
+					return
 				}
 			}
 
@@ -42,6 +42,7 @@ func Test_CheckForMaintenanceModeMiddlewareHealthChecks(t *testing.T) {
 	r.Use(m.CheckForMaintenanceMode)
 
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
 
 	ts := httptest.NewServer(r)
 	defer ts.Close()
@@ -53,3 +54,36 @@ func Test_CheckForMaintenanceModeMiddlewareHealthChecks(t *testing.T) {
 	}
 
 }
+
+func Test_CheckForMaintenanceModeMiddlewareConfiguredURLs(t *testing.T) {
+
+	r := mux.NewRouter()
+
+	m := Middleware{
+		MaintenanceMode: true,
+		MaintenanceURLs: []string{"/healthz"},
+	}
+
+	r.Use(m.CheckForMaintenanceMode)
+
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, body := testRequest(t, ts, "GET", "/healthz", nil)
+	if body != "ok" {
+		t.Errorf("expected the exempt route's handler to run, got body %q", body)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Error("configured maintenance url returned wrong status code:", res.StatusCode)
+	}
+
+	res, _ = testRequest(t, ts, "GET", "/", nil)
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Error("check for maintenance mode middleware returned wrong status code:", res.StatusCode)
+	}
+}
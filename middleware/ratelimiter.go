@@ -2,44 +2,48 @@ package middleware
 
 import (
 	"net/http"
-	"os"
-	"strconv"
 	"time"
 
+	"github.com/cidekar/adele-framework/config"
 	"github.com/go-chi/httprate"
 )
 
+// Limit requests per client IP. The limit and window come from the Rate and
+// Duration fields when set, otherwise from HTTP_RATE_LIMIT and HTTP_RATE_DURATION
+// (in minutes) of the HTTP configuration.
 func (a *Middleware) RateLimiter() func(next http.Handler) http.Handler {
-	var rate int
-	var duration int
+	if a.Rate > 0 && a.Duration > 0 {
+		return httprate.LimitByIP(a.Rate, a.Duration)
+	}
+
+	c := a.httpConfig()
 
 	// Default to 100 requests per minute
-	rateDefault := 100
-	durationDefault := 1
-
-	_, ok := os.LookupEnv("HTTP_RATE_LIMIT")
-	if ok {
-		r, err := strconv.Atoi(os.Getenv("HTTP_RATE_LIMIT"))
-		if err != nil {
-			rate = rateDefault
-		} else {
-			rate = r
-		}
-	} else {
-		rate = rateDefault
+	rate := 100
+	if c.RateLimit > 0 {
+		rate = c.RateLimit
 	}
 
-	_, ok = os.LookupEnv("HTTP_RATE_DURATION")
-	if ok {
-		r, err := strconv.Atoi(os.Getenv("HTTP_RATE_DURATION"))
-		if err != nil {
-			duration = durationDefault
-		} else {
-			duration = r
-		}
-	} else {
-		duration = durationDefault
+	duration := 1
+	if c.RateDuration > 0 {
+		duration = c.RateDuration
 	}
 
 	return httprate.LimitByIP(rate, time.Duration(duration)*time.Minute)
 }
+
+// Load the HTTP configuration for a Middleware that was not given its settings
+// by the application. A value that cannot be parsed is left at zero, so the
+// middleware falls back to its default, and logged.
+func (a *Middleware) httpConfig() config.HTTP {
+	rootPath := a.RootPath
+	if rootPath == "" {
+		rootPath = "."
+	}
+
+	var c config.HTTP
+	if err := config.Load(rootPath, &c); err != nil && a.Log != nil {
+		a.Log.Warn(err)
+	}
+	return c
+}
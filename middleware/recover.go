@@ -33,7 +33,7 @@ func (m *Middleware) RecovererWithDebug(next http.Handler) http.Handler {
 
 				trace := FrameworkTrace{
 					AdeleVersion: m.FrameworkVersion,
-					AppName:      os.Getenv("APP_NAME"),
+					AppName:      m.appName(),
 					RootPath:     m.RootPath,
 					StackRaw:     buf[:n],
 					FrameCount:   0,
@@ -149,6 +149,14 @@ func (m *Middleware) RecovererWithDebug(next http.Handler) http.Handler {
 
 }
 
+// The application name shown on the debug page, falling back to APP_NAME.
+func (m *Middleware) appName() string {
+	if m.AppName != "" {
+		return m.AppName
+	}
+	return os.Getenv("APP_NAME")
+}
+
 func getRecoverHTML() string {
 
 	return `<html>
@@ -48,9 +48,16 @@ import (
 //   - Only include your actual reverse-proxy IPs (LB subnets, ingress CIDRs).
 //   - Headers from untrusted peers are completely ignored.
 func TrustedProxy() func(h http.Handler) http.Handler {
+	return TrustedProxyWithConfig(os.Getenv("TRUSTED_PROXIES"), os.Getenv("TRUST_PROXY_HEADERS"))
+}
+
+// TrustedProxyWithConfig is TrustedProxy with the proxy list and trusted headers
+// passed in rather than read from the environment. BootstrapMux uses it with the
+// values from the application configuration.
+func TrustedProxyWithConfig(proxies, headers string) func(h http.Handler) http.Handler {
 	// Parse trusted proxy configuration once at construction.
-	trustedProxies := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
-	trustedHeaders := parseTrustedHeaders(os.Getenv("TRUST_PROXY_HEADERS"))
+	trustedProxies := parseTrustedProxies(proxies)
+	trustedHeaders := parseTrustedHeaders(headers)
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
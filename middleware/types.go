@@ -50,6 +50,7 @@ type Middleware struct {
 	RootPath         string
 	Log              *logrus.Logger
 	MaintenanceMode  bool
+	MaintenanceURLs  []string
 	Session          *scs.SessionManager
 	Rate             int
 	Duration         time.Duration
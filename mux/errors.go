@@ -0,0 +1,9 @@
+package mux
+
+import "errors"
+
+var RouteNotFoundError = errors.New("no route has the name")
+
+var RouteParamsError = errors.New("the params do not fit the route")
+
+var UnresolvedRouteError = errors.New("the route has no record in the mux tree")
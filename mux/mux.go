@@ -1,15 +1,21 @@
 // Package mux wraps the chi router to expose all HTTP verbs while adding support
-// for per-route scope annotations.
+// for per-route scope annotations, named routes and RESTful resource routes.
 //
 // Routes may carry a "[scope:...]" annotation in their pattern, which mux strips
 // before registration and records in a route tree so scopes can be looked up
-// during a request and enforced by middleware.
+// during a request and enforced by middleware. A "[name:...]" annotation names
+// the route so its path can be built with URL instead of being hardcoded.
+// Resource registers the named routes of a controller's CRUD actions at once.
 package mux
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
 	"reflect"
 	"regexp"
+	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
@@ -28,7 +34,7 @@ import (
 //
 // a.use()
 func NewRouter() *Mux {
-	return &Mux{chi.NewRouter()}
+	return &Mux{Mux: chi.NewRouter()}
 }
 
 func (r *Mux) URLParam(rq *http.Request, key string) string {
@@ -36,24 +42,204 @@ func (r *Mux) URLParam(rq *http.Request, key string) string {
 }
 
 // Mux route tree traversal to locate a route and return the scopes attached
-// to the pattern.
+// to the pattern. The path may be a route pattern or a request path; a request
+// path such as /users/7 matches the pattern /users/{id}. An exact match wins
+// over a match through URL parameters.
 func (r *Mux) GetScopes(path string) MuxRouteScope {
 	var scope MuxRouteScope
-	for _, router := range MuxRouterTree {
-		if router.Base+router.Route == path {
-			if strings.TrimSpace(router.Scope) != "" {
-				scope.Scope = strings.Split(router.Scope, " ")
-			}
+
+	match := -1
+	for i, router := range MuxRouterTree {
+		if router.pattern() == path {
+			match = i
 			break
 		}
+		if match < 0 && matchMuxPattern(router.pattern(), path) {
+			match = i
+		}
+	}
+
+	if match >= 0 && strings.TrimSpace(MuxRouterTree[match].Scope) != "" {
+		scope.Scope = strings.Split(MuxRouterTree[match].Scope, " ")
 	}
 	return scope
 }
 
-// With adds inline middlewares for an endpoint handler.
+// Resolve the route chi routes a request to, e.g. /api/users/{id} for
+// /api/users/7, and return the scopes annotated on it. The request is matched
+// against the router at the top of its routing context, so a middleware on a
+// subrouter, or on a router whose subrouters gain routes after they are
+// mounted, sees the same route chi serves. A request no route matches has no
+// scopes. A route chi matches that the tree has no record of, such as one
+// added to the chi router underneath, returns UnresolvedRouteError so that
+// middleware can refuse it rather than let it through unchecked.
+func (r *Mux) RouteScopes(rq *http.Request) (MuxRouteScope, error) {
+	var scope MuxRouteScope
+
+	top := r.root().Mux
+	if rctx := chi.RouteContext(rq.Context()); rctx != nil {
+		if routes, ok := rctx.Routes.(*chi.Mux); ok {
+			top = routes
+		}
+	}
+
+	path := rq.URL.RawPath
+	if path == "" {
+		path = rq.URL.Path
+	}
+
+	pattern := top.Find(chi.NewRouteContext(), rq.Method, path)
+	if pattern == "" {
+		return scope, nil
+	}
+
+	info, ok := resolveMuxRoute(top, rq.Method, pattern)
+	if !ok {
+		return scope, fmt.Errorf("%w: %s %s", UnresolvedRouteError, rq.Method, pattern)
+	}
+
+	scope.Scope = strings.Fields(info.Scope)
+	return scope, nil
+}
+
+// Find the route registered on top, or on a router mounted under it, that chi
+// serves for a method and the full pattern chi reports for it. When a route
+// is registered twice the last registration wins, as it does in chi.
+func resolveMuxRoute(top *chi.Mux, method, pattern string) (MuxRouteInfo, bool) {
+	var (
+		match MuxRouteInfo
+		found bool
+	)
+
+	for _, info := range MuxRouterTree {
+		if info.Method != "" && info.Method != method {
+			continue
+		}
+
+		full, ok := info.patternFrom(top)
+		if !ok {
+			continue
+		}
+
+		if full == pattern || (info.mount && (pattern == full+"/" || pattern == full+"/*")) {
+			match, found = info, true
+		}
+	}
+
+	return match, found
+}
+
+// Build the path of the route annotated with [name:...], e.g.
+// URL("users.show", 7) is /users/7 for /users/{id}[name:users.show]. The
+// params fill the {param} placeholders in order, and a trailing * takes one
+// more. The base of the router the route is mounted on is included. When
+// several routes share a name the last one registered wins.
+func (r *Mux) URL(name string, params ...interface{}) (string, error) {
+	route := -1
+	for i := len(MuxRouterTree) - 1; i >= 0; i-- {
+		if MuxRouterTree[i].Name == name {
+			route = i
+			break
+		}
+	}
+	if route < 0 {
+		return "", fmt.Errorf("%w: %s", RouteNotFoundError, name)
+	}
+
+	segments := strings.Split(MuxRouterTree[route].Route, "/")
+	for i, segment := range segments {
+		wildcard := segment == "*"
+		if !wildcard && !(strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")) {
+			continue
+		}
+
+		if len(params) == 0 {
+			if wildcard {
+				segments[i] = ""
+				continue
+			}
+			return "", fmt.Errorf("%w: %s is missing %s", RouteParamsError, name, segment)
+		}
+
+		value := fmt.Sprint(params[0])
+		params = params[1:]
+
+		if wildcard {
+			parts := strings.Split(value, "/")
+			for j, part := range parts {
+				parts[j] = url.PathEscape(part)
+			}
+			segments[i] = strings.Join(parts, "/")
+			continue
+		}
+
+		if value == "" {
+			return "", fmt.Errorf("%w: %s has an empty %s", RouteParamsError, name, segment)
+		}
+		if _, expr, ok := strings.Cut(segment[1:len(segment)-1], ":"); ok {
+			if re, err := regexp.Compile("^(?:" + expr + ")$"); err == nil && !re.MatchString(value) {
+				return "", fmt.Errorf("%w: %q does not match %s of %s", RouteParamsError, value, segment, name)
+			}
+		}
+		segments[i] = url.PathEscape(value)
+	}
+
+	if len(params) > 0 {
+		return "", fmt.Errorf("%w: %s takes fewer params", RouteParamsError, name)
+	}
+
+	path := strings.Join(segments, "/")
+	base := MuxRouterTree[route].base()
+	if base != "" && path == "/" {
+		return base, nil
+	}
+	return base + path, nil
+}
+
+// List the routes the router serves, including those of mounted subrouters,
+// with the full pattern, the name and scopes annotated on them and the names
+// of the middleware they run through, outermost first. Routes are sorted by
+// pattern and method.
+func (r *Mux) ListRoutes() ([]MuxRouteListing, error) {
+	routes := []MuxRouteListing{}
+	err := chi.Walk(r.Mux, func(method, pattern string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		route := MuxRouteListing{
+			Method:     method,
+			Pattern:    pattern,
+			Scopes:     []string{},
+			Middleware: []string{},
+		}
+
+		if info, ok := resolveMuxRoute(r.Mux, method, pattern); ok {
+			route.Name = info.Name
+			route.Scopes = append(route.Scopes, strings.Fields(info.Scope)...)
+		}
+
+		for _, middleware := range middlewares {
+			route.Middleware = append(route.Middleware, muxFuncName(middleware))
+		}
+
+		routes = append(routes, route)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes, nil
+}
+
+// With adds inline middlewares for an endpoint handler. The routes added to
+// the returned router carry their annotations like those of r.
 func (r *Mux) With(middlewares ...func(http.Handler) http.Handler) chi.Router {
 	mx := r.Mux.With(middlewares...).(*chi.Mux)
-	return mx
+	return &Mux{Mux: mx, parent: r}
 }
 
 // Use appends a middleware handler to the Mux middleware stack.
@@ -68,13 +254,13 @@ func (r *Mux) Use(middlewares ...func(http.Handler) http.Handler) {
 // Handle adds the route `pattern` that matches any http method to execute the
 // `handler` http.Handler.
 func (r *Mux) Handle(pattern string, handler http.Handler) {
-	r.Mux.Handle(cleanMuxScopeAnnotation(pattern), handler)
+	r.Mux.Handle(cleanMuxScopeAnnotation(r, "", pattern), handler)
 }
 
 // HandleFunc adds the route `pattern` that matches any http method to execute the
 // `handlerFn` http.HandlerFunc.
 func (r *Mux) HandleFunc(pattern string, handler http.HandlerFunc) {
-	r.Mux.HandleFunc(cleanMuxScopeAnnotation(pattern), handler)
+	r.Mux.HandleFunc(cleanMuxScopeAnnotation(r, "", pattern), handler)
 }
 
 // Match searches the routing tree for a handler that matches the method/path. It's
@@ -87,19 +273,19 @@ func (r *Mux) Match(rctx *chi.Context, method string, path string) bool {
 
 // Method and MethodFunc adds routes for `pattern` that matches the `method` HTTP method.
 func (r *Mux) Method(method, pattern string, handler http.Handler) {
-	r.Mux.With().Method(method, cleanMuxScopeAnnotation(pattern), handler)
+	r.Mux.With().Method(method, cleanMuxScopeAnnotation(r, strings.ToUpper(method), pattern), handler)
 }
 
 // Method and MethodFunc adds routes for `pattern` that matches
 // the `method` HTTP method.
 func (r *Mux) MethodFunc(method, pattern string, handler http.HandlerFunc) {
-	r.Mux.With().MethodFunc(method, cleanMuxScopeAnnotation(pattern), handler)
+	r.Mux.With().MethodFunc(method, cleanMuxScopeAnnotation(r, strings.ToUpper(method), pattern), handler)
 }
 
 // Connect adds the route `pattern` that matches a CONNECT http method to execute
 // the `handlerFn` http.HandlerFunc.
 func (r *Mux) Connect(pattern string, handler http.HandlerFunc) {
-	r.Mux.Connect(cleanMuxScopeAnnotation(pattern), handler)
+	r.Mux.Connect(cleanMuxScopeAnnotation(r, http.MethodConnect, pattern), handler)
 }
 
 // Find searches the routing tree for the pattern that matches
@@ -111,49 +297,49 @@ func (r *Mux) Find(rctx *chi.Context, method, path string) string {
 // Head adds the route `pattern` that matches a HEAD http method to execute the
 // `handlerFn` http.HandlerFunc.
 func (r *Mux) Head(pattern string, handler http.HandlerFunc) {
-	r.Mux.Head(cleanMuxScopeAnnotation(pattern), handler)
+	r.Mux.Head(cleanMuxScopeAnnotation(r, http.MethodHead, pattern), handler)
 }
 
 // Get adds the route `pattern` that matches a GET http method to execute the
 // `handlerFn` http.HandlerFunc.
 func (r *Mux) Get(pattern string, handler http.HandlerFunc) {
-	r.Mux.Get(cleanMuxScopeAnnotation(pattern), handler)
+	r.Mux.Get(cleanMuxScopeAnnotation(r, http.MethodGet, pattern), handler)
 }
 
 // Post adds the route `pattern` that matches a POST http method to execute the
 // `handlerFn` http.HandlerFunc.
 func (r *Mux) Post(pattern string, handler http.HandlerFunc) {
-	r.Mux.Post(cleanMuxScopeAnnotation(pattern), handler)
+	r.Mux.Post(cleanMuxScopeAnnotation(r, http.MethodPost, pattern), handler)
 }
 
 // Put adds the route `pattern` that matches a PUT http method to execute the
 // `handlerFn` http.HandlerFunc.
 func (r *Mux) Put(pattern string, handler http.HandlerFunc) {
-	r.Mux.Put(cleanMuxScopeAnnotation(pattern), handler)
+	r.Mux.Put(cleanMuxScopeAnnotation(r, http.MethodPut, pattern), handler)
 }
 
 // Patch adds the route `pattern` that matches a PATCH http method to execute the
 // `handlerFn` http.HandlerFunc.
 func (r *Mux) Patch(pattern string, handler http.HandlerFunc) {
-	r.Mux.Patch(cleanMuxScopeAnnotation(pattern), handler)
+	r.Mux.Patch(cleanMuxScopeAnnotation(r, http.MethodPatch, pattern), handler)
 }
 
 // Delete adds the route `pattern` that matches a DELETE http method to execute
 // the `handlerFn` http.HandlerFunc.
 func (r *Mux) Delete(pattern string, handler http.HandlerFunc) {
-	r.Mux.Delete(cleanMuxScopeAnnotation(pattern), handler)
+	r.Mux.Delete(cleanMuxScopeAnnotation(r, http.MethodDelete, pattern), handler)
 }
 
 // Trace adds the route `pattern` that matches a TRACE http method to execute the
 // `handlerFn` http.HandlerFunc.
 func (r *Mux) Trace(pattern string, handler http.HandlerFunc) {
-	r.Mux.Trace(cleanMuxScopeAnnotation(pattern), handler)
+	r.Mux.Trace(cleanMuxScopeAnnotation(r, http.MethodTrace, pattern), handler)
 }
 
 // Options adds the route `pattern` that matches an OPTIONS http method to execute
 // the `handlerFn` http.HandlerFunc.
 func (r *Mux) Options(pattern string, handler http.HandlerFunc) {
-	r.Mux.Options(cleanMuxScopeAnnotation(pattern), handler)
+	r.Mux.Options(cleanMuxScopeAnnotation(r, http.MethodOptions, pattern), handler)
 }
 
 // NotFound sets a custom http.HandlerFunc for routing paths that could not
@@ -188,13 +374,24 @@ func (r *Mux) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 //
 // set of middlewares.
 func (r *Mux) Group(fn func(r chi.Router)) chi.Router {
-	return r.Mux.Group(fn)
+	im := r.With()
+	if fn != nil {
+		fn(im)
+	}
+	return im
 }
 
 // Route creates a new Mux and mounts it along the `pattern` as a subrouter.
 // Effectively, this is a short-hand call to Mount.
 func (r *Mux) Route(pattern string, fn func(r chi.Router)) chi.Router {
-	return r.Mux.Route(pattern, fn)
+	if fn == nil {
+		panic("adele: attempting to Route() a nil subrouter on '" + pattern + "'")
+	}
+
+	sub := NewRouter()
+	fn(sub)
+	r.Mount(pattern, sub)
+	return sub
 }
 
 // Mount attaches another http.Handler or chi Router as a subrouter along a routing
@@ -204,32 +401,80 @@ func (r *Mux) Mount(pattern string, handler http.Handler) {
 
 	r.Mux.Mount(pattern, handler)
 
-	var extractRoute func([]chi.Route, string)
-	processRouter := func(r chi.Router, basePattern string) {
-		extractRoute(r.Routes(), basePattern)
+	switch sub := handler.(type) {
+	case *Mux:
+		sub.parent, sub.prefix = r, pattern
+	case chi.Routes:
+		// The routes of a chi router are not in the tree, and middleware
+		// checking scopes refuses them.
+	default:
+		MuxRouterTree = append(MuxRouterTree, MuxRouteInfo{
+			Route: muxPrefix(pattern),
+			owner: r,
+			mount: true,
+		})
 	}
+}
 
-	extractRoute = func(routes []chi.Route, basePattern string) {
-		for _, route := range routes {
-
-			// range the mux tree for the matching pattern
-			for i, muxRoute := range MuxRouterTree {
-				if muxRoute.Route == route.Pattern {
-					muxRoute.Base = basePattern
-					muxRoute.Scope = extractScopeFromMuxPattern(muxRoute.Annotation)
-					MuxRouterTree[i] = muxRoute
-					break
-				}
-			}
+// The router at the top of the routers r is mounted on.
+func (r *Mux) root() *Mux {
+	for r.parent != nil {
+		r = r.parent
+	}
+	return r
+}
 
-			// check if the handler is another chi router aka subrouter
-			if reflect.TypeOf(route.SubRoutes) != nil {
-				extractRoute(route.SubRoutes.Routes(), basePattern)
-			}
+// The pattern a router adds to the routes mounted along it. A router mounted
+// on / adds none, as chi routes /login there rather than //login.
+func muxPrefix(pattern string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(pattern, "*"), "/")
+}
+
+// The patterns of the routers a route is mounted under, or the Base of a
+// route added to the tree by hand.
+func (info MuxRouteInfo) base() string {
+	if info.owner == nil {
+		return muxPrefix(info.Base)
+	}
+
+	base := ""
+	for m := info.owner; m.parent != nil; m = m.parent {
+		base = muxPrefix(m.prefix) + base
+	}
+	return base
+}
+
+// The full pattern of a route, with the base of the routers it is mounted on.
+func (info MuxRouteInfo) pattern() string {
+	return info.base() + info.Route
+}
+
+// The pattern of a route as top routes it, when the route is registered on
+// top or on a router mounted under top.
+func (info MuxRouteInfo) patternFrom(top *chi.Mux) (string, bool) {
+	base := ""
+	for m := info.owner; m != nil; m = m.parent {
+		if m.Mux == top {
+			return base + info.Route, true
 		}
+		base = muxPrefix(m.prefix) + base
+	}
+	return "", false
+}
+
+// The name of a middleware function without its package path, e.g.
+// auth.(*Auth).TrackSessions.
+func muxFuncName(fn interface{}) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "?"
 	}
 
-	processRouter(r, pattern)
+	name := strings.TrimSuffix(f.Name(), "-fm")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
 }
 
 // Middlewares returns a slice of middleware handler functions.
@@ -244,33 +489,70 @@ func (r *Mux) Routes() []chi.Route {
 }
 
 // Clean the mux pattern, capture the values in the mux node tree and
-// return the pattern used for HTTP routing. The scope annotation i.e.,
-// string pattern is enclosed in square brackets.
-func cleanMuxScopeAnnotation(pattern string) string {
+// return the pattern used for HTTP routing. Annotations are enclosed in square
+// brackets at the end of the pattern, e.g. /users/{id}[scope:users:read] or
+// /users/{id}[scope:users:read][name:users.show].
+func cleanMuxScopeAnnotation(r *Mux, method, pattern string) string {
+	route, annotations := splitMuxAnnotations(pattern)
+
+	info := MuxRouteInfo{
+		Method: method,
+		Route:  route,
+		owner:  r,
+	}
+	if len(annotations) > 0 {
+		info.Annotation = pattern
+		info.Scope = extractScopeFromMuxPattern(pattern)
+		info.Name = extractNameFromMuxPattern(pattern)
+	}
 
-	re := regexp.MustCompile(`(?:\[|\])`)
-	hasAnnotation := re.MatchString(pattern)
+	MuxRouterTree = append(MuxRouterTree, info)
+	return route
+}
 
-	// nothing to do here if the pattern has no annotation
-	if !hasAnnotation {
-		MuxRouterTree = append(MuxRouterTree, MuxRouteInfo{
-			Route: pattern,
-		})
-		return pattern
-	}
+var muxAnnotationPattern = regexp.MustCompile(`\[([^\[\]]*)\]$`)
 
-	re = regexp.MustCompile(`(.*)(?:\[(.*)\])`)
-	annotation := re.FindStringSubmatch(pattern)
-	if len(annotation) != 3 {
-		panic("adele: detected malformed annotation in pattern; " + pattern)
+// Split the trailing annotations off a pattern. Brackets inside the pattern,
+// such as in the regexp of /users/{id:[0-9]+}, are left alone.
+func splitMuxAnnotations(pattern string) (string, []string) {
+	var annotations []string
+	for strings.HasSuffix(pattern, "]") {
+		match := muxAnnotationPattern.FindStringSubmatchIndex(pattern)
+		if match == nil {
+			panic("adele: detected malformed annotation in pattern; " + pattern)
+		}
+		annotations = append([]string{pattern[match[2]:match[3]]}, annotations...)
+		pattern = pattern[:match[0]]
 	}
+	return pattern, annotations
+}
 
-	MuxRouterTree = append(MuxRouterTree, MuxRouteInfo{
-		Annotation: pattern,
-		Route:      annotation[1],
-	})
+// Report whether a request path matches a route pattern. A {param} segment,
+// with or without a regexp, matches any one non-empty path segment and a
+// trailing * matches the rest of the path.
+func matchMuxPattern(pattern, path string) bool {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, segment := range patternSegments {
+		if segment == "*" {
+			return true
+		}
+		if i >= len(pathSegments) {
+			return false
+		}
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if pathSegments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segment != pathSegments[i] {
+			return false
+		}
+	}
 
-	return annotation[1]
+	return len(patternSegments) == len(pathSegments)
 }
 
 // Extract a "scope" value from a given string pattern, where the scope
@@ -278,29 +560,38 @@ func cleanMuxScopeAnnotation(pattern string) string {
 // (e.g., [scope:value] or [scopes:value]). The extracted scopes, scopes
 // assigned to the route, are returned.
 func extractScopeFromMuxPattern(pattern string) string {
-	re := regexp.MustCompile(`(?:\[|\])`)
-	hasAnnotation := re.MatchString(pattern)
-	if !hasAnnotation {
-		return ""
-	}
+	return extractMuxAnnotation(pattern, "scope", "scopes")
+}
 
-	re = regexp.MustCompile(`(.*)(?:\[(.*)\])`)
-	annotation := re.FindStringSubmatch(pattern)
-	if len(annotation) != 3 {
-		panic("adele: detected malformed annotation in pattern; " + pattern)
-	}
+// Extract the route name from a [name:value] annotation, e.g. users.show for
+// /users/{id}[name:users.show].
+func extractNameFromMuxPattern(pattern string) string {
+	return extractMuxAnnotation(pattern, "name")
+}
 
-	typ, val, has := strings.Cut(annotation[2], ":")
-	if !has {
-		panic("adele: detected malformed annotation type in pattern: " + annotation[2])
-	}
+// Extract the value of the first annotation of one of the given types. Every
+// annotation must be a known type:value pair.
+func extractMuxAnnotation(pattern string, types ...string) string {
+	_, annotations := splitMuxAnnotations(pattern)
 
-	typ = strings.TrimSpace(typ)
+	value, found := "", false
+	for _, annotation := range annotations {
+		typ, val, has := strings.Cut(annotation, ":")
+		if !has {
+			panic("adele: detected malformed annotation type in pattern: " + annotation)
+		}
 
-	if typ == "scopes" || typ == "scope" {
-		return strings.TrimSpace(val)
-	}
+		typ = strings.TrimSpace(typ)
+		if typ != "scope" && typ != "scopes" && typ != "name" {
+			panic("adele: detected unknown annotation type in pattern: " + typ)
+		}
 
-	panic("adele: detected unknown annotation type in pattern: " + typ)
+		for _, t := range types {
+			if typ == t && !found {
+				value, found = strings.TrimSpace(val), true
+			}
+		}
+	}
 
+	return value
 }
@@ -1,6 +1,7 @@
 package mux
 
 import (
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -57,8 +58,8 @@ func TestMux_With(t *testing.T) {
 
 	r := mux.With(mf)
 
-	if reflect.TypeOf(r).String() != "*chi.Mux" {
-		t.Error("mux with did not return pointer to internal router")
+	if inline, ok := r.(*Mux); !ok || inline.Mux == nil || inline.Mux == mux.Mux {
+		t.Error("mux with did not return an inline router wrapping the internal router")
 	}
 
 }
@@ -576,3 +577,465 @@ func TestMux_CheckAnnotation(t *testing.T) {
 	t.Log(w.Result().StatusCode)
 
 }
+
+func TestMux_RouteScopes(t *testing.T) {
+	mux := NewRouter()
+	mux.Get("/route-scopes/{id}[scopes:photos:read photos:list]", func(w http.ResponseWriter, r *http.Request) {})
+
+	api := NewRouter()
+	api.Delete("/route-scopes-api/{id}[scope:photos:delete]", func(w http.ResponseWriter, r *http.Request) {})
+	mux.Mount("/api", api)
+
+	r := httptest.NewRequest(http.MethodGet, "/route-scopes/7", nil)
+	if got, err := mux.RouteScopes(r); err != nil || strings.Join(got.Scope, " ") != "photos:read photos:list" {
+		t.Errorf("unexpected scopes %v, %v for a parameterized route", got.Scope, err)
+	}
+
+	r = httptest.NewRequest(http.MethodDelete, "/api/route-scopes-api/7", nil)
+	if got, err := mux.RouteScopes(r); err != nil || strings.Join(got.Scope, " ") != "photos:delete" {
+		t.Errorf("unexpected scopes %v, %v for a mounted route", got.Scope, err)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/route-scopes-missing", nil)
+	if got, err := mux.RouteScopes(r); err != nil || len(got.Scope) != 0 {
+		t.Errorf("expected no scopes for an unknown route, got %v, %v", got.Scope, err)
+	}
+}
+
+// A middleware recording the scopes RouteScopes resolves on a router for the
+// last request it served.
+type scopeRecorder struct {
+	scopes string
+	err    error
+}
+
+func (rec *scopeRecorder) middleware(m *Mux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope, err := m.RouteScopes(r)
+			rec.scopes, rec.err = strings.Join(scope.Scope, " "), err
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestMux_RouteScopes_Subrouter(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+
+	rec := &scopeRecorder{}
+	root := NewRouter()
+	api := NewRouter()
+	api.Use(rec.middleware(api))
+	api.Get("/photos[scope:photos:read]", h)
+	root.Mount("/sub-api", api)
+
+	testHandler(t, root, http.MethodGet, "/sub-api/photos", nil)
+	if rec.err != nil || rec.scopes != "photos:read" {
+		t.Errorf("expected the scopes of the route from a middleware on the subrouter, got %q, %v", rec.scopes, rec.err)
+	}
+}
+
+func TestMux_RouteScopes_MountedFirst(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+
+	rec := &scopeRecorder{}
+	root := NewRouter()
+	api := NewRouter()
+	admin := NewRouter()
+	admin.Use(rec.middleware(admin))
+	root.Mount("/first-api", api)
+	api.Mount("/admin", admin)
+
+	// Routes registered after the routers are mounted.
+	api.Get("/photos[scope:photos:read]", h)
+	admin.Delete("/photos/{id}[scope:photos:delete]", h)
+	api.Group(func(r chi.Router) {
+		r.Post("/photos[scope:photos:write]", h)
+	})
+	api.With(func(next http.Handler) http.Handler { return next }).Put("/photos/{id}[scope:photos:update]", h)
+	api.Route("/albums", func(r chi.Router) {
+		r.Get("/{id}[scope:albums:read]", h)
+	})
+
+	tests := []struct {
+		method, path, want string
+	}{
+		{http.MethodGet, "/first-api/photos", "photos:read"},
+		{http.MethodDelete, "/first-api/admin/photos/7", "photos:delete"},
+		{http.MethodPost, "/first-api/photos", "photos:write"},
+		{http.MethodPut, "/first-api/photos/7", "photos:update"},
+		{http.MethodGet, "/first-api/albums/7", "albums:read"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if got, err := root.RouteScopes(r); err != nil || strings.Join(got.Scope, " ") != tt.want {
+			t.Errorf("%s %s: expected %q, got %v, %v", tt.method, tt.path, tt.want, got.Scope, err)
+		}
+	}
+
+	testHandler(t, root, http.MethodDelete, "/first-api/admin/photos/7", nil)
+	if rec.err != nil || rec.scopes != "photos:delete" {
+		t.Errorf("expected the scopes of a route registered after mounting, got %q, %v", rec.scopes, rec.err)
+	}
+}
+
+func TestMux_RouteScopes_Unresolved(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+
+	root := NewRouter()
+	root.Mux.Get("/unresolved-raw", h)
+
+	raw := chi.NewRouter()
+	raw.Get("/photos", h)
+	root.Mount("/unresolved-chi", raw)
+
+	root.Mount("/unresolved-files", http.FileServer(http.Dir(".")))
+
+	for _, path := range []string{"/unresolved-raw", "/unresolved-chi/photos"} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if _, err := root.RouteScopes(r); !errors.Is(err, UnresolvedRouteError) {
+			t.Errorf("GET %s: expected %v, got %v", path, UnresolvedRouteError, err)
+		}
+	}
+
+	for _, path := range []string{"/unresolved-files", "/unresolved-files/mux.go", "/unresolved-missing"} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if got, err := root.RouteScopes(r); err != nil || len(got.Scope) != 0 {
+			t.Errorf("GET %s: expected no scopes, got %v, %v", path, got.Scope, err)
+		}
+	}
+}
+
+func TestMux_RouteScopes_ByMethod(t *testing.T) {
+	mux := NewRouter()
+	mux.Get("/route-scopes-method[scopes:notes:read]", func(w http.ResponseWriter, r *http.Request) {})
+	mux.Post("/route-scopes-method[scopes:notes:write]", func(w http.ResponseWriter, r *http.Request) {})
+
+	r := httptest.NewRequest(http.MethodPost, "/route-scopes-method", nil)
+	if got, err := mux.RouteScopes(r); err != nil || strings.Join(got.Scope, " ") != "notes:write" {
+		t.Errorf("expected the scopes of the POST route, got %v, %v", got.Scope, err)
+	}
+}
+
+func TestMux_GetScopes_Params(t *testing.T) {
+	MuxRouterTree = append(MuxRouterTree,
+		MuxRouteInfo{Route: "/scoped-users/{id}", Scope: "users:read"},
+		MuxRouteInfo{Route: "/scoped-users/{id}/posts/{slug:[a-z-]+}", Scope: "posts:read"},
+		MuxRouteInfo{Route: "/scoped-users/me", Scope: "profile"},
+		MuxRouteInfo{Base: "/files", Route: "/*", Scope: "files:read"},
+	)
+
+	mux := NewRouter()
+
+	tests := map[string]string{
+		"/scoped-users/7":               "users:read",
+		"/scoped-users/{id}":            "users:read",
+		"/scoped-users/me":              "profile",
+		"/scoped-users/7/posts/my-post": "posts:read",
+		"/files/a/b.txt":                "files:read",
+		"/scoped-users/7/posts":         "",
+		"/scoped-users":                 "",
+	}
+	for path, want := range tests {
+		if got := strings.Join(mux.GetScopes(path).Scope, " "); got != want {
+			t.Errorf("GetScopes(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestMux_URL(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+
+	mux := NewRouter()
+	mux.Get("/url-home/[name:url.home]", h)
+	mux.Get("/url-users/{id}[scope:users:read][name:url.users.show]", h)
+	mux.Get("/url-users/{id}/posts/{slug:[a-z-]+}[name:url.posts.show]", h)
+	mux.Get("/url-files/*[name:url.files]", h)
+
+	admin := NewRouter()
+	admin.Get("/[name:url.admin]", h)
+	admin.Get("/url-reports/{year:[0-9]+}[name:url.admin.reports]", h)
+	api := NewRouter()
+	api.Mount("/admin", admin)
+	mux.Mount("/api", api)
+
+	tests := []struct {
+		name   string
+		params []interface{}
+		want   string
+	}{
+		{"url.home", nil, "/url-home/"},
+		{"url.users.show", []interface{}{7}, "/url-users/7"},
+		{"url.users.show", []interface{}{"a b/c"}, "/url-users/a%20b%2Fc"},
+		{"url.posts.show", []interface{}{7, "my-post"}, "/url-users/7/posts/my-post"},
+		{"url.files", []interface{}{"a/b c.txt"}, "/url-files/a/b%20c.txt"},
+		{"url.files", nil, "/url-files/"},
+		{"url.admin", nil, "/api/admin"},
+		{"url.admin.reports", []interface{}{2024}, "/api/admin/url-reports/2024"},
+	}
+	for _, tt := range tests {
+		if got, err := mux.URL(tt.name, tt.params...); err != nil || got != tt.want {
+			t.Errorf("URL(%q, %v) = %q, %v, want %q", tt.name, tt.params, got, err, tt.want)
+		}
+	}
+
+	if got := strings.Join(mux.GetScopes("/url-users/7").Scope, " "); got != "users:read" {
+		t.Errorf("expected the scope next to the name to be kept, got %q", got)
+	}
+
+	refused := []struct {
+		name   string
+		params []interface{}
+		want   error
+	}{
+		{"url.missing", nil, RouteNotFoundError},
+		{"url.users.show", nil, RouteParamsError},
+		{"url.users.show", []interface{}{7, 8}, RouteParamsError},
+		{"url.users.show", []interface{}{""}, RouteParamsError},
+		{"url.posts.show", []interface{}{7, "Not A Slug"}, RouteParamsError},
+	}
+	for _, tt := range refused {
+		if _, err := mux.URL(tt.name, tt.params...); !errors.Is(err, tt.want) {
+			t.Errorf("URL(%q, %v): expected %v, got %v", tt.name, tt.params, tt.want, err)
+		}
+	}
+}
+
+func TestMux_Annotations(t *testing.T) {
+	mux := NewRouter()
+	mux.Get("/annotated/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {})
+
+	r := httptest.NewRequest(http.MethodGet, "/annotated/7", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected brackets in a parameter regexp to be left alone, got %d", w.Code)
+	}
+
+	for _, pattern := range []string{"/annotated[name]", "/annotated[title:home]", "/annotated]"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected %s to panic", pattern)
+				}
+			}()
+			mux.Get(pattern, func(w http.ResponseWriter, r *http.Request) {})
+		}()
+	}
+}
+
+func listMiddleware(next http.Handler) http.Handler { return next }
+
+func adminMiddleware(next http.Handler) http.Handler { return next }
+
+func TestMux_ListRoutes(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+
+	web := NewRouter()
+	web.Use(listMiddleware)
+	web.Get("/list-login[name:list.login]", h)
+	web.Post("/list-login", h)
+
+	admin := NewRouter()
+	admin.Use(adminMiddleware)
+	admin.Delete("/list-users/{id}[scope:users:delete][name:list.users.destroy]", h)
+	web.Mount("/list-admin", admin)
+
+	root := NewRouter()
+	root.Mount("/", web)
+
+	routes, err := root.ListRoutes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []MuxRouteListing{
+		{Method: "DELETE", Pattern: "/list-admin/list-users/{id}", Name: "list.users.destroy", Scopes: []string{"users:delete"},
+			Middleware: []string{"mux.listMiddleware", "mux.adminMiddleware"}},
+		{Method: "GET", Pattern: "/list-login", Name: "list.login", Scopes: []string{}, Middleware: []string{"mux.listMiddleware"}},
+		{Method: "POST", Pattern: "/list-login", Scopes: []string{}, Middleware: []string{"mux.listMiddleware"}},
+	}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("unexpected routes:\n got %+v\nwant %+v", routes, want)
+	}
+
+	r := httptest.NewRequest(http.MethodDelete, "/list-admin/list-users/7", nil)
+	if got, err := root.RouteScopes(r); err != nil || strings.Join(got.Scope, " ") != "users:delete" {
+		t.Errorf("expected the scopes of a route under a router mounted on /, got %v, %v", got.Scope, err)
+	}
+}
+
+func TestMux_ListRoutes_MountedFirst(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+
+	// The subrouters are mounted before their routes are registered, and one
+	// is nested through Route and Group.
+	root := NewRouter()
+	api := NewRouter()
+	root.Mount("/first-api", api)
+	api.Get("/photos[scope:photos:read][name:first.photos.index]", h)
+	api.Route("/admin", func(admin chi.Router) {
+		admin.Group(func(g chi.Router) {
+			g.Delete("/photos/{id}[scope:photos:delete]", h)
+		})
+	})
+
+	routes, err := root.ListRoutes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []MuxRouteListing{
+		{Method: "DELETE", Pattern: "/first-api/admin/photos/{id}", Scopes: []string{"photos:delete"}, Middleware: []string{}},
+		{Method: "GET", Pattern: "/first-api/photos", Name: "first.photos.index", Scopes: []string{"photos:read"}, Middleware: []string{}},
+	}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("unexpected routes:\n got %+v\nwant %+v", routes, want)
+	}
+}
+
+// A resource controller recording the action and item it served.
+type photoController struct {
+	served string
+}
+
+func (c *photoController) Index(w http.ResponseWriter, r *http.Request)  { c.served = "index" }
+func (c *photoController) Create(w http.ResponseWriter, r *http.Request) { c.served = "create" }
+func (c *photoController) Store(w http.ResponseWriter, r *http.Request)  { c.served = "store" }
+func (c *photoController) Show(w http.ResponseWriter, r *http.Request) {
+	c.served = "show " + chi.URLParam(r, "photo")
+}
+func (c *photoController) Edit(w http.ResponseWriter, r *http.Request)    { c.served = "edit" }
+func (c *photoController) Update(w http.ResponseWriter, r *http.Request)  { c.served = "update" }
+func (c *photoController) Destroy(w http.ResponseWriter, r *http.Request) { c.served = "destroy" }
+
+type listingController struct{}
+
+func (listingController) Index(w http.ResponseWriter, r *http.Request) {}
+
+func resourceRoutes(t *testing.T, mux *Mux) []string {
+	routes, err := mux.ListRoutes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, route := range routes {
+		got = append(got, strings.TrimSpace(route.Method+" "+route.Pattern+" "+route.Name+" "+strings.Join(route.Scopes, " ")))
+	}
+	return got
+}
+
+func TestMux_Resource(t *testing.T) {
+	c := &photoController{}
+	mux := NewRouter()
+	mux.Resource("/res-photos", c)
+
+	want := []string{
+		"GET /res-photos res-photos.index",
+		"POST /res-photos res-photos.store",
+		"GET /res-photos/create res-photos.create",
+		"DELETE /res-photos/{res_photo} res-photos.destroy",
+		"GET /res-photos/{res_photo} res-photos.show",
+		"PATCH /res-photos/{res_photo}",
+		"PUT /res-photos/{res_photo} res-photos.update",
+		"GET /res-photos/{res_photo}/edit res-photos.edit",
+	}
+	if got := resourceRoutes(t, mux); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected routes:\n got %q\nwant %q", got, want)
+	}
+
+	photos := NewRouter()
+	photos.Resource("/photos", c, ResourceName("gallery"))
+	for path, served := range map[string]string{"/photos/7": "show 7", "/photos/create": "create"} {
+		testHandler(t, photos, http.MethodGet, path, nil)
+		if c.served != served {
+			t.Errorf("GET %s: expected %q, got %q", path, served, c.served)
+		}
+	}
+	if got, err := photos.URL("gallery.show", 7); err != nil || got != "/photos/7" {
+		t.Errorf("expected a named show route, got %q %v", got, err)
+	}
+}
+
+func TestMux_Resource_Options(t *testing.T) {
+	mux := NewRouter()
+	mux.APIResource("/res-posts/{post}/comments", &photoController{},
+		Except("destroy"), ActionScope("store", "comments:write"), ResourceParam("id"))
+	mux.Resource("/res-tags", &photoController{}, Only("index", "show"))
+	mux.Resource("/res-listings", listingController{})
+
+	want := []string{
+		"GET /res-listings res-listings.index",
+		"GET /res-posts/{post}/comments res-posts.comments.index",
+		"POST /res-posts/{post}/comments res-posts.comments.store comments:write",
+		"GET /res-posts/{post}/comments/{id} res-posts.comments.show",
+		"PATCH /res-posts/{post}/comments/{id}",
+		"PUT /res-posts/{post}/comments/{id} res-posts.comments.update",
+		"GET /res-tags res-tags.index",
+		"GET /res-tags/{res_tag} res-tags.show",
+	}
+	if got := resourceRoutes(t, mux); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected routes:\n got %q\nwant %q", got, want)
+	}
+
+	for name, register := range map[string]func(){
+		"unknown action": func() { mux.Resource("/res-bad", &photoController{}, Only("list")) },
+		"no actions":     func() { mux.Resource("/res-empty", struct{}{}) },
+		"no name":        func() { mux.Resource("/{id}", &photoController{}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic", name)
+				}
+			}()
+			register()
+		}()
+	}
+}
+
+func TestMux_Resource_ActionScopeOnSubrouter(t *testing.T) {
+	c := &photoController{}
+	var scopes []string
+
+	root := NewRouter()
+	api := NewRouter()
+	root.Mount("/scoped-api", api)
+	api.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, err := api.RouteScopes(r)
+			if err != nil {
+				t.Errorf("RouteScopes: %v", err)
+			}
+			scopes = route.Scope
+			next.ServeHTTP(w, r)
+		})
+	})
+	api.Route("/v1", func(v1 chi.Router) {
+		v1.(*Mux).APIResource("/photos", c, Only("index", "store"), ActionScope("store", "photos:write"))
+	})
+
+	testHandler(t, root, http.MethodPost, "/scoped-api/v1/photos", nil)
+	if c.served != "store" || strings.Join(scopes, " ") != "photos:write" {
+		t.Errorf("expected the store scope on the mounted resource, got %q %v", c.served, scopes)
+	}
+
+	testHandler(t, root, http.MethodGet, "/scoped-api/v1/photos", nil)
+	if c.served != "index" || len(scopes) != 0 {
+		t.Errorf("expected the index action to carry no scopes, got %q %v", c.served, scopes)
+	}
+}
+
+func TestSingularResourceParam(t *testing.T) {
+	for plural, want := range map[string]string{
+		"photos": "photo", "categories": "category", "boxes": "box", "matches": "match",
+		"addresses": "address", "status": "status", "data": "data", "user-roles": "user_role",
+		"buses": "bus", "statuses": "status", "campuses": "campus", "houses": "house", "causes": "cause",
+		"uses": "use", "courses": "course", "cases": "case",
+	} {
+		if got := singularResourceParam(plural); got != want {
+			t.Errorf("singularResourceParam(%q) = %q, want %q", plural, got, want)
+		}
+	}
+}
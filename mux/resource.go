@@ -0,0 +1,241 @@
+package mux
+
+import (
+	"net/http"
+	"strings"
+)
+
+// The actions of a resource in the order they are registered, so the static
+// /create segment is registered before the {param} segment next to it.
+var resourceActions = []string{"index", "create", "store", "show", "edit", "update", "destroy"}
+
+// A resource registered by Resource or APIResource.
+type resource struct {
+	only   map[string]bool
+	except map[string]bool
+	param  string
+	name   string
+	scopes map[string]string
+}
+
+// Register only the given actions of a resource, e.g. Only("index", "show").
+func Only(actions ...string) ResourceOption {
+	set := resourceActionSet(actions)
+	return func(res *resource) {
+		res.only = set
+	}
+}
+
+// Register every action of a resource but the given ones.
+func Except(actions ...string) ResourceOption {
+	set := resourceActionSet(actions)
+	return func(res *resource) {
+		for action := range set {
+			res.except[action] = true
+		}
+	}
+}
+
+// Name the URL parameter of a single item, {id} instead of the singular of
+// the last path segment such as {photo} for /photos.
+func ResourceParam(name string) ResourceOption {
+	return func(res *resource) {
+		res.param = name
+	}
+}
+
+// Prefix the route names with name instead of the static path segments, e.g.
+// ResourceName("gallery") names gallery.index rather than photos.index.
+func ResourceName(name string) ResourceOption {
+	return func(res *resource) {
+		res.name = name
+	}
+}
+
+// Annotate the route of an action with scopes, e.g.
+// ActionScope("store", "photos:write") is like [scope:photos:write].
+func ActionScope(action, scopes string) ResourceOption {
+	resourceActionSet([]string{action})
+	return func(res *resource) {
+		res.scopes[action] = scopes
+	}
+}
+
+// Resource registers the RESTful routes of a controller for each action
+// interface it implements:
+//
+//	GET       /photos               Index     photos.index
+//	GET       /photos/create        Create    photos.create
+//	POST      /photos               Store     photos.store
+//	GET       /photos/{photo}       Show      photos.show
+//	GET       /photos/{photo}/edit  Edit      photos.edit
+//	PUT|PATCH /photos/{photo}       Update    photos.update
+//	DELETE    /photos/{photo}       Destroy   photos.destroy
+//
+// A nested resource such as /posts/{post}/comments is named
+// posts.comments.index and so on, and its handlers read both parameters with
+// URLParam. The options limit the actions, rename the parameter and the route
+// names, and annotate actions with scopes.
+func (r *Mux) Resource(pattern string, controller interface{}, options ...ResourceOption) {
+	r.resource(pattern, controller, false, options)
+}
+
+// APIResource registers a resource like Resource without the create and edit
+// actions, which serve HTML forms.
+func (r *Mux) APIResource(pattern string, controller interface{}, options ...ResourceOption) {
+	r.resource(pattern, controller, true, options)
+}
+
+func (r *Mux) resource(pattern string, controller interface{}, api bool, options []ResourceOption) {
+	pattern = "/" + strings.Trim(pattern, "/")
+
+	res := &resource{
+		except: map[string]bool{},
+		scopes: map[string]string{},
+	}
+	if api {
+		res.except["create"] = true
+		res.except["edit"] = true
+	}
+	for _, option := range options {
+		option(res)
+	}
+
+	var statics []string
+	for _, segment := range strings.Split(strings.Trim(pattern, "/"), "/") {
+		if segment != "" && !strings.HasPrefix(segment, "{") {
+			statics = append(statics, segment)
+		}
+	}
+	if len(statics) == 0 {
+		panic("adele: resource pattern has no path segment to name it by: " + pattern)
+	}
+	if res.param == "" {
+		res.param = singularResourceParam(statics[len(statics)-1])
+	}
+	if res.name == "" {
+		res.name = strings.Join(statics, ".")
+	}
+
+	item := pattern + "/{" + res.param + "}"
+	registered := 0
+	for _, action := range resourceActions {
+		if (res.only != nil && !res.only[action]) || res.except[action] {
+			continue
+		}
+
+		handler, ok := resourceHandler(controller, action)
+		if !ok {
+			continue
+		}
+
+		name := "[name:" + res.name + "." + action + "]"
+		scope := ""
+		if scopes := strings.TrimSpace(res.scopes[action]); scopes != "" {
+			scope = "[scope:" + scopes + "]"
+		}
+
+		switch action {
+		case "index":
+			r.Get(pattern+scope+name, handler)
+		case "create":
+			r.Get(pattern+"/create"+scope+name, handler)
+		case "store":
+			r.Post(pattern+scope+name, handler)
+		case "show":
+			r.Get(item+scope+name, handler)
+		case "edit":
+			r.Get(item+"/edit"+scope+name, handler)
+		case "update":
+			// A name belongs to one route, and both share the path.
+			r.Put(item+scope+name, handler)
+			r.Patch(item+scope, handler)
+		case "destroy":
+			r.Delete(item+scope+name, handler)
+		}
+		registered++
+	}
+
+	if registered == 0 {
+		panic("adele: resource controller implements none of the registered actions: " + pattern)
+	}
+}
+
+// The handler of a controller for an action, if the controller implements it.
+func resourceHandler(controller interface{}, action string) (http.HandlerFunc, bool) {
+	switch action {
+	case "index":
+		if c, ok := controller.(ResourceIndexer); ok {
+			return c.Index, true
+		}
+	case "create":
+		if c, ok := controller.(ResourceCreator); ok {
+			return c.Create, true
+		}
+	case "store":
+		if c, ok := controller.(ResourceStorer); ok {
+			return c.Store, true
+		}
+	case "show":
+		if c, ok := controller.(ResourceShower); ok {
+			return c.Show, true
+		}
+	case "edit":
+		if c, ok := controller.(ResourceEditor); ok {
+			return c.Edit, true
+		}
+	case "update":
+		if c, ok := controller.(ResourceUpdater); ok {
+			return c.Update, true
+		}
+	case "destroy":
+		if c, ok := controller.(ResourceDestroyer); ok {
+			return c.Destroy, true
+		}
+	}
+	return nil, false
+}
+
+// Collect actions into a set, panicking on a name that is not an action.
+func resourceActionSet(actions []string) map[string]bool {
+	set := map[string]bool{}
+	for _, action := range actions {
+		known := false
+		for _, a := range resourceActions {
+			known = known || a == action
+		}
+		if !known {
+			panic("adele: detected unknown resource action: " + action)
+		}
+		set[action] = true
+	}
+	return set
+}
+
+// The parameter name for an item of a resource, the singular of its path
+// segment: photos is photo, categories is category, boxes is box and buses is
+// bus, while houses is house and status is kept. Other plurals are named with
+// ResourceParam.
+func singularResourceParam(segment string) string {
+	segment = strings.ReplaceAll(segment, "-", "_")
+	switch {
+	case strings.HasSuffix(segment, "ies") && len(segment) > 3:
+		return strings.TrimSuffix(segment, "ies") + "y"
+	case strings.HasSuffix(segment, "xes"), strings.HasSuffix(segment, "ches"), strings.HasSuffix(segment, "shes"),
+		strings.HasSuffix(segment, "sses"), strings.HasSuffix(segment, "uses") && consonantBefore(segment, "uses"):
+		return strings.TrimSuffix(segment, "es")
+	case strings.HasSuffix(segment, "s") && !strings.HasSuffix(segment, "ss") && !strings.HasSuffix(segment, "us"):
+		return strings.TrimSuffix(segment, "s")
+	}
+	return segment
+}
+
+// Report whether the letter before suffix is a consonant, as in the plural of
+// an -us word such as statuses, unlike causes.
+func consonantBefore(segment, suffix string) bool {
+	stem := strings.TrimSuffix(segment, suffix)
+	if stem == "" {
+		return false
+	}
+	return !strings.ContainsRune("aeiou_", rune(stem[len(stem)-1]))
+}
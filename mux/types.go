@@ -46,6 +46,12 @@ type Cors struct {
 
 type Mux struct {
 	Mux *chi.Mux
+
+	// The router this one is mounted on, or made inline from by With, and
+	// the pattern it is mounted along, from which the full patterns of its
+	// routes are built whenever they are looked up.
+	parent *Mux
+	prefix string
 }
 
 var Router = &Mux{}
@@ -56,10 +62,69 @@ type MuxRouteInfo struct {
 	Annotation string
 	Method     string
 	Route      string
-	Base       string
 	Scope      string
+
+	// The base of a route added to the tree by hand. A route registered on a
+	// router takes the patterns of the routers it is mounted under instead,
+	// including mounts made after it was registered.
+	Base string
+
+	// The name given with a [name:...] annotation, which URL builds the path
+	// of the route from.
+	Name string
+
+	// The router the route was registered on, which ties the route to the
+	// routers it is mounted under.
+	owner *Mux
+
+	// Whether the route is a handler other than a router mounted along
+	// Route, which serves every path below it.
+	mount bool
+}
+
+// A MuxRouteListing describes a route served by a router, as printed by
+// `adele route:list`.
+type MuxRouteListing struct {
+	Method     string   `json:"method"`
+	Pattern    string   `json:"pattern"`
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	Middleware []string `json:"middleware"`
 }
 
 type MuxRouteScope struct {
 	Scope []string
 }
+
+// A ResourceOption changes the routes Resource registers.
+type ResourceOption func(*resource)
+
+// The actions of a resource controller. Resource registers a route for each
+// one the controller implements.
+type ResourceIndexer interface {
+	Index(w http.ResponseWriter, r *http.Request)
+}
+
+type ResourceCreator interface {
+	Create(w http.ResponseWriter, r *http.Request)
+}
+
+type ResourceStorer interface {
+	Store(w http.ResponseWriter, r *http.Request)
+}
+
+type ResourceShower interface {
+	Show(w http.ResponseWriter, r *http.Request)
+}
+
+type ResourceEditor interface {
+	Edit(w http.ResponseWriter, r *http.Request)
+}
+
+type ResourceUpdater interface {
+	Update(w http.ResponseWriter, r *http.Request)
+}
+
+type ResourceDestroyer interface {
+	Destroy(w http.ResponseWriter, r *http.Request)
+}
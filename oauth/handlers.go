@@ -0,0 +1,444 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cidekar/adele-framework/internal/bearer"
+	"github.com/cidekar/adele-framework/mux"
+)
+
+const (
+	// Session key holding the authorization request awaiting the user's
+	// decision.
+	consentSessionKey = "oauth.consent"
+
+	// How long the consent page may be left open.
+	consentTTL = 30 * time.Minute
+)
+
+// Routes returns a router serving the authorize, token and revoke endpoints,
+// to be mounted by the application, for example:
+//
+//	a.App.Routes.Mount("/oauth", a.App.OAuth.Routes())
+//
+// The token and revoke endpoints are called by clients rather than browsers,
+// so they must not sit behind CSRF protection. The authorize endpoint must sit
+// behind the application's session middleware, as it holds the authorization
+// request in the session between the consent page and its form.
+func (s *Server) Routes() *mux.Mux {
+	m := mux.NewRouter()
+	m.Get("/authorize", s.Authorize)
+	m.Post("/authorize", s.Authorize)
+	m.Post("/token", s.Token)
+	m.Post("/revoke", s.Revoke)
+	return m
+}
+
+// Authorize serves the authorization endpoint of the authorization code grant.
+// A GET validates the request, holds it in the session of the logged in user
+// and renders the consent page; the consent form POSTs back to the same URL
+// with the user's decision and the nonce of the held request, and the user is
+// redirected to the client with a code or an access_denied error. The code is
+// issued for the held request only, so a form posted from anywhere but the
+// rendered consent page is refused. Public clients must send an S256 PKCE code
+// challenge.
+func (s *Server) Authorize(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		s.decide(w, r)
+		return
+	}
+
+	q := r.URL.Query()
+
+	client, err := s.Store.Client(r.Context(), q.Get("client_id"))
+	if errors.Is(err, ErrClientNotFound) {
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.serverError(w, err)
+		return
+	}
+
+	// Errors are only sent back to a redirect URI registered for the client;
+	// anything else would make the server an open redirector.
+	redirectURI := q.Get("redirect_uri")
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !client.AllowsRedirect(redirectURI) {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	state := q.Get("state")
+
+	if q.Get("response_type") != "code" {
+		redirectError(w, r, redirectURI, state, &Error{Code: "unsupported_response_type"})
+		return
+	}
+
+	scopes := strings.Fields(q.Get("scope"))
+	if !client.AllowsScopes(scopes) {
+		redirectError(w, r, redirectURI, state, &Error{Code: "invalid_scope", Description: "the client may not request these scopes"})
+		return
+	}
+
+	challenge, method := q.Get("code_challenge"), q.Get("code_challenge_method")
+	if challenge == "" && !client.Confidential() {
+		redirectError(w, r, redirectURI, state, &Error{Code: "invalid_request", Description: "public clients must send a PKCE code_challenge"})
+		return
+	}
+	if challenge != "" && method != "S256" {
+		redirectError(w, r, redirectURI, state, &Error{Code: "invalid_request", Description: "code_challenge_method must be S256"})
+		return
+	}
+
+	userID, ok := s.user(r)
+	if !ok {
+		http.Redirect(w, r, s.LoginURL+"?redirect="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+		return
+	}
+
+	if s.Consent == nil || s.Session == nil {
+		s.serverError(w, errors.New("oauth: the consent page needs Consent and Session"))
+		return
+	}
+
+	pending := pendingAuthorization{
+		Nonce:               randomToken(32),
+		ClientID:            client.ID,
+		UserID:              userID,
+		Scopes:              scopes,
+		RedirectURI:         redirectURI,
+		RequestedRedirect:   q.Get("redirect_uri"),
+		State:               state,
+		CodeChallenge:       challenge,
+		CodeChallengeMethod: method,
+		ExpiresAt:           time.Now().Add(consentTTL),
+	}
+	held, err := json.Marshal(pending)
+	if err != nil {
+		s.serverError(w, err)
+		return
+	}
+	s.Session.Put(r.Context(), consentSessionKey, string(held))
+
+	consent := &Consent{Client: client, Scopes: scopes, Action: r.URL.RequestURI(), Nonce: pending.Nonce}
+	if err := s.Consent(w, r, consent); err != nil {
+		s.serverError(w, err)
+	}
+}
+
+// Take the authorization request the consent page was rendered for and issue a
+// code for it, or deny it, as the user decided.
+func (s *Server) decide(w http.ResponseWriter, r *http.Request) {
+	if s.Session == nil {
+		s.serverError(w, errors.New("oauth: the consent page needs Consent and Session"))
+		return
+	}
+
+	var pending pendingAuthorization
+	held := s.Session.PopString(r.Context(), consentSessionKey)
+	nonce := r.PostFormValue("consent")
+	userID, ok := s.user(r)
+	if held == "" || json.Unmarshal([]byte(held), &pending) != nil || nonce == "" ||
+		subtle.ConstantTimeCompare([]byte(nonce), []byte(pending.Nonce)) != 1 ||
+		!ok || userID != pending.UserID || !time.Now().Before(pending.ExpiresAt) {
+		http.Error(w, "the authorization request has expired, please start again", http.StatusBadRequest)
+		return
+	}
+
+	if r.PostFormValue("approve") != "yes" {
+		redirectError(w, r, pending.RedirectURI, pending.State, &Error{Code: "access_denied", Description: "the user denied the request"})
+		return
+	}
+
+	plainText := randomToken(32)
+	code := &AuthCode{
+		ID:                  bearer.Hash(plainText),
+		ClientID:            pending.ClientID,
+		UserID:              pending.UserID,
+		Scopes:              pending.Scopes,
+		RedirectURI:         pending.RequestedRedirect,
+		CodeChallenge:       pending.CodeChallenge,
+		CodeChallengeMethod: pending.CodeChallengeMethod,
+		ExpiresAt:           time.Now().UTC().Add(s.AuthCodeTTL),
+	}
+	if err := s.Store.CreateAuthCode(r.Context(), code); err != nil {
+		s.serverError(w, err)
+		return
+	}
+
+	redirect(w, r, pending.RedirectURI, url.Values{"code": {plainText}, "state": {pending.State}})
+}
+
+// Token serves the token endpoint for the authorization_code,
+// client_credentials and refresh_token grants. Clients authenticate with HTTP
+// Basic auth or the client_id and client_secret form fields.
+func (s *Server) Token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, &Error{Code: "invalid_request", Description: err.Error(), Status: http.StatusBadRequest})
+		return
+	}
+
+	client, oauthErr := s.authenticateClient(r)
+	if oauthErr != nil {
+		writeError(w, oauthErr)
+		return
+	}
+
+	var (
+		res *tokenResponse
+		err error
+	)
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		res, err = s.exchangeAuthCode(r, client)
+	case "client_credentials":
+		res, err = s.clientCredentials(r, client)
+	case "refresh_token":
+		res, err = s.refresh(r, client)
+	default:
+		err = &Error{Code: "unsupported_grant_type"}
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		writeError(w, e)
+		return
+	}
+	if err != nil {
+		s.serverError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	writeJSON(w, http.StatusOK, res)
+}
+
+// Revoke serves the token revocation endpoint of RFC 7009. The token may be an
+// access or a refresh token; unknown tokens are not an error.
+func (s *Server) Revoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, &Error{Code: "invalid_request", Description: err.Error(), Status: http.StatusBadRequest})
+		return
+	}
+
+	client, oauthErr := s.authenticateClient(r)
+	if oauthErr != nil {
+		writeError(w, oauthErr)
+		return
+	}
+
+	id := bearer.Hash(r.PostForm.Get("token"))
+
+	if token, err := s.Store.AccessToken(r.Context(), id); err == nil && token.ClientID == client.ID {
+		if err := s.Store.RevokeAccessToken(r.Context(), id); err != nil {
+			s.serverError(w, err)
+			return
+		}
+	}
+
+	if refresh, err := s.Store.RefreshToken(r.Context(), id); err == nil && refresh.ClientID == client.ID {
+		err := s.Store.RevokeRefreshToken(r.Context(), id)
+		if err == nil {
+			err = s.Store.RevokeAccessToken(r.Context(), refresh.AccessTokenID)
+		}
+		if err != nil && !errors.Is(err, ErrTokenNotFound) {
+			s.serverError(w, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Exchange an authorization code, checking the redirect URI and PKCE verifier
+// it was issued with. A redirect_uri sent in the authorization request must be
+// sent again, unchanged (RFC 6749 section 4.1.3).
+func (s *Server) exchangeAuthCode(r *http.Request, client *Client) (*tokenResponse, error) {
+	code, err := s.Store.TakeAuthCode(r.Context(), bearer.Hash(r.PostForm.Get("code")))
+	if errors.Is(err, ErrCodeNotFound) {
+		return nil, &Error{Code: "invalid_grant", Description: "the authorization code is invalid"}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case code.ClientID != client.ID:
+		return nil, &Error{Code: "invalid_grant", Description: "the authorization code was issued to another client"}
+	case !time.Now().Before(code.ExpiresAt):
+		return nil, &Error{Code: "invalid_grant", Description: "the authorization code has expired"}
+	case code.RedirectURI != "" && r.PostForm.Get("redirect_uri") != code.RedirectURI:
+		return nil, &Error{Code: "invalid_grant", Description: "redirect_uri does not match the authorization request"}
+	case code.RedirectURI == "" && r.PostForm.Get("redirect_uri") != "" && !client.AllowsRedirect(r.PostForm.Get("redirect_uri")):
+		return nil, &Error{Code: "invalid_grant", Description: "redirect_uri is not registered for the client"}
+	case code.CodeChallenge != "" && !verifyChallenge(code.CodeChallenge, r.PostForm.Get("code_verifier")):
+		return nil, &Error{Code: "invalid_grant", Description: "the code_verifier does not match the code_challenge"}
+	}
+
+	return s.issue(r.Context(), client.ID, code.UserID, code.Scopes, true)
+}
+
+// Issue a token to a confidential client acting on its own behalf.
+func (s *Server) clientCredentials(r *http.Request, client *Client) (*tokenResponse, error) {
+	if !client.Confidential() {
+		return nil, &Error{Code: "unauthorized_client", Description: "public clients may not use the client_credentials grant"}
+	}
+
+	scopes := strings.Fields(r.PostForm.Get("scope"))
+	if !client.AllowsScopes(scopes) {
+		return nil, &Error{Code: "invalid_scope", Description: "the client may not request these scopes"}
+	}
+
+	return s.issue(r.Context(), client.ID, 0, scopes, false)
+}
+
+// Exchange a refresh token for a new token pair. The refresh token and the
+// access token issued with it are revoked, but only once the token is found to
+// belong to the client and to be unexpired, so presenting another client's
+// token does not destroy it. A narrower scope may be requested.
+func (s *Server) refresh(r *http.Request, client *Client) (*tokenResponse, error) {
+	id := bearer.Hash(r.PostForm.Get("refresh_token"))
+	refresh, err := s.Store.RefreshToken(r.Context(), id)
+	if errors.Is(err, ErrTokenNotFound) {
+		return nil, &Error{Code: "invalid_grant", Description: "the refresh token is invalid"}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if refresh.ClientID != client.ID {
+		return nil, &Error{Code: "invalid_grant", Description: "the refresh token was issued to another client"}
+	}
+	if !time.Now().Before(refresh.ExpiresAt) {
+		return nil, &Error{Code: "invalid_grant", Description: "the refresh token has expired"}
+	}
+
+	scopes := refresh.Scopes
+	if requested := strings.Fields(r.PostForm.Get("scope")); len(requested) > 0 {
+		if !bearer.Grants(refresh.Scopes, requested) {
+			return nil, &Error{Code: "invalid_scope", Description: "the requested scope exceeds the original grant"}
+		}
+		scopes = requested
+	}
+
+	// Of two requests refreshing with the same token, only the one whose
+	// revocation removed it gets a new token pair.
+	if err := s.Store.RevokeRefreshToken(r.Context(), id); errors.Is(err, ErrTokenNotFound) {
+		return nil, &Error{Code: "invalid_grant", Description: "the refresh token is invalid"}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := s.Store.RevokeAccessToken(r.Context(), refresh.AccessTokenID); err != nil && !errors.Is(err, ErrTokenNotFound) {
+		return nil, err
+	}
+
+	return s.issue(r.Context(), client.ID, refresh.UserID, scopes, true)
+}
+
+// Identify the client making a token or revoke request. Confidential clients
+// must present their secret.
+func (s *Server) authenticateClient(r *http.Request) (*Client, *Error) {
+	id, secret, basic := r.BasicAuth()
+	if !basic {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	invalid := &Error{Code: "invalid_client", Description: "client authentication failed", Status: http.StatusUnauthorized}
+
+	client, err := s.Store.Client(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, ErrClientNotFound) && s.ErrorLog != nil {
+			s.ErrorLog.Println("oauth: find client:", err)
+		}
+		return nil, invalid
+	}
+
+	if client.Confidential() && !client.checkSecret(secret) {
+		return nil, invalid
+	}
+
+	return client, nil
+}
+
+// Resolve the logged in user.
+func (s *Server) user(r *http.Request) (int, bool) {
+	if s.User == nil {
+		return 0, false
+	}
+	return s.User(r)
+}
+
+func (s *Server) serverError(w http.ResponseWriter, err error) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Println(err)
+	}
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// Check a PKCE code verifier against an S256 code challenge.
+func verifyChallenge(challenge, verifier string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return verifier != "" && subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// Redirect the user agent to the client with the given query parameters.
+func redirect(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	q := u.Query()
+	for key, values := range params {
+		if len(values) > 0 && values[0] != "" {
+			q.Set(key, values[0])
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// Redirect the user agent to the client with an error, see RFC 6749 section
+// 4.1.2.1.
+func redirectError(w http.ResponseWriter, r *http.Request, redirectURI, state string, e *Error) {
+	redirect(w, r, redirectURI, url.Values{
+		"error":             {e.Code},
+		"error_description": {e.Description},
+		"state":             {state},
+	})
+}
+
+func writeError(w http.ResponseWriter, e *Error) {
+	status := e.Status
+	if status == 0 {
+		status = http.StatusBadRequest
+	}
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, e)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,129 @@
+package oauth
+
+import "context"
+
+// Create an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		clients:       make(map[string]Client),
+		codes:         make(map[string]AuthCode),
+		accessTokens:  make(map[string]AccessToken),
+		refreshTokens: make(map[string]RefreshToken),
+	}
+}
+
+func (m *MemoryStore) CreateClient(ctx context.Context, client *Client) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clients[client.ID] = *client
+	return nil
+}
+
+func (m *MemoryStore) Client(ctx context.Context, id string) (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	client, ok := m.clients[id]
+	if !ok {
+		return nil, ErrClientNotFound
+	}
+	return &client, nil
+}
+
+// DeleteClient removes the client with its codes and tokens.
+func (m *MemoryStore) DeleteClient(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.clients[id]; !ok {
+		return ErrClientNotFound
+	}
+	delete(m.clients, id)
+
+	for key, code := range m.codes {
+		if code.ClientID == id {
+			delete(m.codes, key)
+		}
+	}
+	for key, token := range m.accessTokens {
+		if token.ClientID == id {
+			delete(m.accessTokens, key)
+		}
+	}
+	for key, token := range m.refreshTokens {
+		if token.ClientID == id {
+			delete(m.refreshTokens, key)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) CreateAuthCode(ctx context.Context, code *AuthCode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.codes[code.ID] = *code
+	return nil
+}
+
+func (m *MemoryStore) TakeAuthCode(ctx context.Context, id string) (*AuthCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	code, ok := m.codes[id]
+	if !ok {
+		return nil, ErrCodeNotFound
+	}
+	delete(m.codes, id)
+	return &code, nil
+}
+
+func (m *MemoryStore) CreateAccessToken(ctx context.Context, token *AccessToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.accessTokens[token.ID] = *token
+	return nil
+}
+
+func (m *MemoryStore) AccessToken(ctx context.Context, id string) (*AccessToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.accessTokens[id]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return &token, nil
+}
+
+func (m *MemoryStore) RevokeAccessToken(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.accessTokens[id]; !ok {
+		return ErrTokenNotFound
+	}
+	delete(m.accessTokens, id)
+	return nil
+}
+
+func (m *MemoryStore) CreateRefreshToken(ctx context.Context, token *RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refreshTokens[token.ID] = *token
+	return nil
+}
+
+func (m *MemoryStore) RefreshToken(ctx context.Context, id string) (*RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.refreshTokens[id]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return &token, nil
+}
+
+func (m *MemoryStore) RevokeRefreshToken(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.refreshTokens[id]; !ok {
+		return ErrTokenNotFound
+	}
+	delete(m.refreshTokens, id)
+	return nil
+}
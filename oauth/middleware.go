@@ -0,0 +1,70 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cidekar/adele-framework/internal/bearer"
+	"github.com/cidekar/adele-framework/mux"
+)
+
+type contextKey string
+
+const tokenContextKey contextKey = "oauth.token"
+
+// Middleware protects a resource server's routes with the access tokens the
+// server issues. A request without a valid, unexpired bearer token is answered
+// with 401; a token lacking any of the scopes annotated on the route chi
+// serves the request with, e.g. m.Get("/api/photos[scopes:photos:read]",
+// handler), with 403, as is a request for a route the mux tree has no record
+// of. The token is stored in the request context for TokenFromContext.
+func (s *Server) Middleware(m *mux.Mux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			plainText, ok := bearer.Token(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			token, err := s.Store.AccessToken(r.Context(), bearer.Hash(plainText))
+			if err != nil && !errors.Is(err, ErrTokenNotFound) {
+				s.serverError(w, err)
+				return
+			}
+			if err != nil || token.Expired() {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			route, err := m.RouteScopes(r)
+			if err != nil {
+				if s.ErrorLog != nil {
+					s.ErrorLog.Println("oauth: resolve route scopes:", err)
+				}
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			scopes := route.Scope
+			if !token.HasScopes(scopes...) {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(scopes, " ")))
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tokenContextKey, token)))
+		})
+	}
+}
+
+// Get the access token stored in a context by Middleware, or nil.
+func TokenFromContext(ctx context.Context) *AccessToken {
+	token, _ := ctx.Value(tokenContextKey).(*AccessToken)
+	return token
+}
@@ -0,0 +1,167 @@
+// Package oauth provides an OAuth 2.0 authorization server for Adele
+// applications.
+//
+// It serves the authorization code grant with PKCE, the client credentials
+// grant and refresh tokens, asks users to approve clients on a consent page
+// rendered by the application, and stores clients and hashed codes and tokens
+// in memory or in the database. Its middleware authenticates API requests with
+// the access tokens it issues and enforces the scopes annotated on mux routes,
+// e.g. "/api/photos[scopes:photos:read]".
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cidekar/adele-framework/internal/bearer"
+)
+
+// Create an authorization server keeping its clients and tokens in store.
+// Access tokens last an hour, refresh tokens 30 days and authorization codes
+// ten minutes.
+func New(store Store) *Server {
+	return &Server{
+		Store:           store,
+		AccessTokenTTL:  time.Hour,
+		RefreshTokenTTL: 30 * 24 * time.Hour,
+		AuthCodeTTL:     10 * time.Minute,
+		LoginURL:        "/login",
+	}
+}
+
+// Register a client allowed to redirect to redirectURIs and to request scopes.
+// A confidential client is given a secret, returned here once; only its hash
+// is stored. Public clients get an empty secret and must use PKCE.
+func (s *Server) CreateClient(ctx context.Context, name string, redirectURIs, scopes []string, confidential bool) (*Client, string, error) {
+	client := &Client{
+		ID:           randomToken(16),
+		Name:         name,
+		RedirectURIs: redirectURIs,
+		Scopes:       scopes,
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	var secret string
+	if confidential {
+		secret = randomToken(32)
+		client.Secret = bearer.Hash(secret)
+	}
+
+	if err := s.Store.CreateClient(ctx, client); err != nil {
+		return nil, "", err
+	}
+
+	return client, secret, nil
+}
+
+// Delete a client. Tokens already issued to it stop working when the store
+// cascades the delete, as the tables of the SQL store do.
+func (s *Server) DeleteClient(ctx context.Context, id string) error {
+	return s.Store.DeleteClient(ctx, id)
+}
+
+// Report whether the client authenticates with a secret.
+func (c *Client) Confidential() bool {
+	return c.Secret != ""
+}
+
+// Report whether the client may redirect to uri. Redirect URIs must match one
+// registered for the client exactly.
+func (c *Client) AllowsRedirect(uri string) bool {
+	for _, registered := range c.RedirectURIs {
+		if registered == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// Report whether the client may request every one of scopes.
+func (c *Client) AllowsScopes(scopes []string) bool {
+	return bearer.Grants(c.Scopes, scopes)
+}
+
+// Check a client secret against the stored hash.
+func (c *Client) checkSecret(secret string) bool {
+	return c.Confidential() && subtle.ConstantTimeCompare([]byte(c.Secret), []byte(bearer.Hash(secret))) == 1
+}
+
+// Report whether the token grants every one of the given scopes.
+func (t *AccessToken) HasScopes(scopes ...string) bool {
+	return bearer.Grants(t.Scopes, scopes)
+}
+
+// Report whether the token has expired.
+func (t *AccessToken) Expired() bool {
+	return !time.Now().Before(t.ExpiresAt)
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return "oauth: " + e.Code
+	}
+	return fmt.Sprintf("oauth: %s: %s", e.Code, e.Description)
+}
+
+// The response body of the token endpoint, see RFC 6749 section 5.1.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// Issue an access token, and a refresh token when withRefresh is set.
+func (s *Server) issue(ctx context.Context, clientID string, userID int, scopes []string, withRefresh bool) (*tokenResponse, error) {
+	now := time.Now().UTC()
+	plainText := randomToken(32)
+	access := &AccessToken{
+		ID:        bearer.Hash(plainText),
+		ClientID:  clientID,
+		UserID:    userID,
+		Scopes:    scopes,
+		ExpiresAt: now.Add(s.AccessTokenTTL),
+		CreatedAt: now,
+	}
+	if err := s.Store.CreateAccessToken(ctx, access); err != nil {
+		return nil, err
+	}
+
+	res := &tokenResponse{
+		AccessToken: plainText,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(s.AccessTokenTTL.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}
+
+	if withRefresh {
+		plainText := randomToken(32)
+		refresh := &RefreshToken{
+			ID:            bearer.Hash(plainText),
+			AccessTokenID: access.ID,
+			ClientID:      clientID,
+			UserID:        userID,
+			Scopes:        scopes,
+			ExpiresAt:     now.Add(s.RefreshTokenTTL),
+		}
+		if err := s.Store.CreateRefreshToken(ctx, refresh); err != nil {
+			return nil, err
+		}
+		res.RefreshToken = plainText
+	}
+
+	return res, nil
+}
+
+// Generate a random hex encoded token of n bytes.
+func randomToken(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,420 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/cidekar/adele-framework/internal/bearer"
+	"github.com/cidekar/adele-framework/mux"
+)
+
+const verifier = "dBjftJeZ4CVP-mJ92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+func challenge() string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// A server whose logged in user is read from the X-User header.
+func newTestServer(t *testing.T) (*Server, *Consent) {
+	s := New(NewMemoryStore())
+	s.Session = scs.New()
+	seen := &Consent{}
+	s.User = func(r *http.Request) (int, bool) {
+		return 7, r.Header.Get("X-User") == "7"
+	}
+	s.Consent = func(w http.ResponseWriter, r *http.Request, c *Consent) error {
+		*seen = *c
+		_, err := w.Write([]byte(c.Nonce))
+		return err
+	}
+	return s, seen
+}
+
+// Serve an authorize request as user 7 in the session carried by cookie and
+// return the response with the session cookie.
+func authorize(s *Server, method, target string, form url.Values, cookie *http.Cookie) (*httptest.ResponseRecorder, *http.Cookie) {
+	r := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	if form != nil {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	r.Header.Set("X-User", "7")
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+
+	w := serve(s.Session.LoadAndSave(http.HandlerFunc(s.Authorize)).ServeHTTP, r)
+	for _, c := range w.Result().Cookies() {
+		if c.Name == s.Session.Cookie.Name {
+			cookie = c
+		}
+	}
+	return w, cookie
+}
+
+func authorizeURL(client *Client, extra url.Values) string {
+	q := url.Values{
+		"client_id":             {client.ID},
+		"redirect_uri":          {"https://app.test/callback"},
+		"response_type":         {"code"},
+		"scope":                 {"photos:read"},
+		"state":                 {"xyz"},
+		"code_challenge":        {challenge()},
+		"code_challenge_method": {"S256"},
+	}
+	for k, v := range extra {
+		q[k] = v
+	}
+	return "/oauth/authorize?" + q.Encode()
+}
+
+func serve(h http.HandlerFunc, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w
+}
+
+func postForm(h http.HandlerFunc, target string, form url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return serve(h, r)
+}
+
+func decodeToken(t *testing.T, w *httptest.ResponseRecorder) tokenResponse {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 from the token endpoint, got %d: %s", w.Code, w.Body)
+	}
+	var res tokenResponse
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// Approve the client on the consent page and return the issued code.
+func approve(t *testing.T, s *Server, client *Client) string {
+	t.Helper()
+	return approveWith(t, s, client, nil)
+}
+
+// Approve the client with extra authorization request parameters.
+func approveWith(t *testing.T, s *Server, client *Client, extra url.Values) string {
+	t.Helper()
+	page, cookie := authorize(s, http.MethodGet, authorizeURL(client, extra), nil, nil)
+	form := url.Values{"consent": {page.Body.String()}, "approve": {"yes"}}
+	w, _ := authorize(s, http.MethodPost, authorizeURL(client, extra), form, cookie)
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected a redirect, got %d", w.Code)
+	}
+
+	location, _ := url.Parse(w.Header().Get("Location"))
+	if location.Query().Get("state") != "xyz" || location.Query().Get("code") == "" {
+		t.Fatalf("unexpected redirect %s", location)
+	}
+	return location.Query().Get("code")
+}
+
+func TestAuthorize_ConsentAndDeny(t *testing.T) {
+	s, seen := newTestServer(t)
+	client, _, _ := s.CreateClient(context.Background(), "Mobile", []string{"https://app.test/callback"}, []string{"photos:read", "photos:write"}, false)
+
+	// Not logged in: off to the login page first.
+	w := serve(s.Authorize, httptest.NewRequest(http.MethodGet, authorizeURL(client, nil), nil))
+	if w.Code != http.StatusFound || !strings.HasPrefix(w.Header().Get("Location"), "/login?redirect=") {
+		t.Errorf("expected a redirect to the login page, got %d %s", w.Code, w.Header().Get("Location"))
+	}
+
+	_, cookie := authorize(s, http.MethodGet, authorizeURL(client, nil), nil, nil)
+	if seen.Client == nil || seen.Client.ID != client.ID || strings.Join(seen.Scopes, " ") != "photos:read" || seen.Action == "" || seen.Nonce == "" {
+		t.Errorf("unexpected consent %+v", seen)
+	}
+
+	w, cookie = authorize(s, http.MethodPost, authorizeURL(client, nil), url.Values{"consent": {seen.Nonce}, "approve": {"no"}}, cookie)
+	if location, _ := url.Parse(w.Header().Get("Location")); location.Query().Get("error") != "access_denied" {
+		t.Errorf("expected access_denied, got %s", location)
+	}
+
+	// The decision used the held request up.
+	w, _ = authorize(s, http.MethodPost, authorizeURL(client, nil), url.Values{"consent": {seen.Nonce}, "approve": {"yes"}}, cookie)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected a second decision to be refused, got %d", w.Code)
+	}
+}
+
+func TestAuthorize_RequiresRenderedConsent(t *testing.T) {
+	s, seen := newTestServer(t)
+	client, _, _ := s.CreateClient(context.Background(), "Mobile", []string{"https://app.test/callback"}, []string{"photos:read", "photos:write"}, false)
+
+	// A form posted from another site carries no request held in the session.
+	if w, _ := authorize(s, http.MethodPost, authorizeURL(client, nil), url.Values{"approve": {"yes"}}, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("expected an approval without a consent page to be refused, got %d", w.Code)
+	}
+
+	_, cookie := authorize(s, http.MethodGet, authorizeURL(client, nil), nil, nil)
+	if w, _ := authorize(s, http.MethodPost, authorizeURL(client, nil), url.Values{"consent": {"guess"}, "approve": {"yes"}}, cookie); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a wrong nonce to be refused, got %d", w.Code)
+	}
+
+	// The code is issued for the request the user saw, whatever the form's
+	// query string asks for.
+	_, cookie = authorize(s, http.MethodGet, authorizeURL(client, nil), nil, nil)
+	w, _ := authorize(s, http.MethodPost, authorizeURL(client, url.Values{"scope": {"photos:write"}}), url.Values{"consent": {seen.Nonce}, "approve": {"yes"}}, cookie)
+	location, _ := url.Parse(w.Header().Get("Location"))
+	code, err := s.Store.TakeAuthCode(context.Background(), bearer.Hash(location.Query().Get("code")))
+	if err != nil || strings.Join(code.Scopes, " ") != "photos:read" {
+		t.Errorf("expected a code for the consented scopes, got %+v, %v", code, err)
+	}
+}
+
+func TestAuthorize_RejectsBadRequests(t *testing.T) {
+	s, _ := newTestServer(t)
+	client, _, _ := s.CreateClient(context.Background(), "Mobile", []string{"https://app.test/callback"}, []string{"photos:read"}, false)
+
+	w := serve(s.Authorize, httptest.NewRequest(http.MethodGet, authorizeURL(client, url.Values{"redirect_uri": {"https://evil.test/"}}), nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected an unregistered redirect to be refused without redirecting, got %d", w.Code)
+	}
+
+	tests := map[string]url.Values{
+		"invalid_scope":   {"scope": {"photos:delete"}},
+		"invalid_request": {"code_challenge": {""}},
+	}
+	for want, extra := range tests {
+		w := serve(s.Authorize, httptest.NewRequest(http.MethodGet, authorizeURL(client, extra), nil))
+		if location, _ := url.Parse(w.Header().Get("Location")); location.Query().Get("error") != want {
+			t.Errorf("expected %s, got %s", want, location)
+		}
+	}
+}
+
+func TestToken_AuthorizationCodeAndRefresh(t *testing.T) {
+	s, _ := newTestServer(t)
+	client, _, _ := s.CreateClient(context.Background(), "Mobile", []string{"https://app.test/callback"}, []string{"photos:read"}, false)
+
+	code := approve(t, s, client)
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {client.ID},
+		"code":          {code},
+		"redirect_uri":  {"https://app.test/callback"},
+		"code_verifier": {"wrong"},
+	}
+	w := postForm(s.Token, "/oauth/token", form)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_grant") {
+		t.Fatalf("expected a wrong verifier to be refused, got %d %s", w.Code, w.Body)
+	}
+
+	// The failed attempt used the code up.
+	form.Set("code_verifier", verifier)
+	if w := postForm(s.Token, "/oauth/token", form); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a used code to be refused, got %d", w.Code)
+	}
+
+	form.Set("code", approve(t, s, client))
+	first := decodeToken(t, postForm(s.Token, "/oauth/token", form))
+	if first.AccessToken == "" || first.RefreshToken == "" || first.TokenType != "Bearer" || first.Scope != "photos:read" {
+		t.Fatalf("unexpected token response %+v", first)
+	}
+
+	refresh := url.Values{"grant_type": {"refresh_token"}, "client_id": {client.ID}, "refresh_token": {first.RefreshToken}}
+	second := decodeToken(t, postForm(s.Token, "/oauth/token", refresh))
+	if second.AccessToken == first.AccessToken || second.RefreshToken == first.RefreshToken {
+		t.Error("expected a new token pair")
+	}
+
+	if w := postForm(s.Token, "/oauth/token", refresh); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a used refresh token to be refused, got %d", w.Code)
+	}
+	if _, err := s.Store.AccessToken(context.Background(), bearer.Hash(first.AccessToken)); err == nil {
+		t.Error("expected the refreshed access token to be revoked")
+	}
+}
+
+func TestToken_RefreshTokenOfAnotherClient(t *testing.T) {
+	s, _ := newTestServer(t)
+	client, _, _ := s.CreateClient(context.Background(), "Mobile", []string{"https://app.test/callback"}, []string{"photos:read"}, false)
+	other, secret, _ := s.CreateClient(context.Background(), "Billing", nil, []string{"photos:read"}, true)
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {client.ID},
+		"code":          {approve(t, s, client)},
+		"redirect_uri":  {"https://app.test/callback"},
+		"code_verifier": {verifier},
+	}
+	issued := decodeToken(t, postForm(s.Token, "/oauth/token", form))
+
+	// Another client presenting the leaked token is refused without using it
+	// up, whether it tries to refresh or to revoke it.
+	stolen := url.Values{"grant_type": {"refresh_token"}, "client_id": {other.ID}, "client_secret": {secret}, "refresh_token": {issued.RefreshToken}}
+	if w := postForm(s.Token, "/oauth/token", stolen); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_grant") {
+		t.Errorf("expected another client's refresh token to be refused, got %d %s", w.Code, w.Body)
+	}
+	revoke := url.Values{"client_id": {other.ID}, "client_secret": {secret}, "token": {issued.RefreshToken}}
+	if w := postForm(s.Revoke, "/oauth/revoke", revoke); w.Code != http.StatusOK {
+		t.Errorf("expected the revocation to be answered with 200, got %d", w.Code)
+	}
+
+	refresh := url.Values{"grant_type": {"refresh_token"}, "client_id": {client.ID}, "refresh_token": {issued.RefreshToken}}
+	decodeToken(t, postForm(s.Token, "/oauth/token", refresh))
+}
+
+func TestToken_RedirectURIMustMatch(t *testing.T) {
+	s, _ := newTestServer(t)
+	client, _, _ := s.CreateClient(context.Background(), "Mobile", []string{"https://app.test/callback"}, []string{"photos:read"}, false)
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {client.ID},
+		"code_verifier": {verifier},
+	}
+
+	// Sent in the authorization request, it must be sent again unchanged.
+	for _, redirectURI := range []string{"", "https://app.test/other"} {
+		form.Set("code", approve(t, s, client))
+		form.Set("redirect_uri", redirectURI)
+		w := postForm(s.Token, "/oauth/token", form)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_grant") {
+			t.Errorf("expected redirect_uri %q to be refused, got %d %s", redirectURI, w.Code, w.Body)
+		}
+	}
+
+	// Left out of the authorization request, it may be left out here too.
+	form.Set("code", approveWith(t, s, client, url.Values{"redirect_uri": {""}}))
+	form.Del("redirect_uri")
+	decodeToken(t, postForm(s.Token, "/oauth/token", form))
+
+	form.Set("code", approveWith(t, s, client, url.Values{"redirect_uri": {""}}))
+	form.Set("redirect_uri", "https://evil.test/")
+	if w := postForm(s.Token, "/oauth/token", form); w.Code != http.StatusBadRequest {
+		t.Errorf("expected an unregistered redirect_uri to be refused, got %d", w.Code)
+	}
+}
+
+func TestToken_ClientCredentials(t *testing.T) {
+	s, _ := newTestServer(t)
+	client, secret, _ := s.CreateClient(context.Background(), "Billing", nil, []string{"invoices:read"}, true)
+	public, _, _ := s.CreateClient(context.Background(), "Mobile", nil, []string{"invoices:read"}, false)
+
+	form := url.Values{"grant_type": {"client_credentials"}, "scope": {"invoices:read"}}
+
+	r := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.SetBasicAuth(client.ID, "wrong")
+	if w := serve(s.Token, r); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected a wrong secret to be refused, got %d", w.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.SetBasicAuth(client.ID, secret)
+	res := decodeToken(t, serve(s.Token, r))
+	if res.RefreshToken != "" {
+		t.Error("expected no refresh token for client credentials")
+	}
+
+	form.Set("client_id", public.ID)
+	if w := postForm(s.Token, "/oauth/token", form); !strings.Contains(w.Body.String(), "unauthorized_client") {
+		t.Errorf("expected a public client to be refused, got %s", w.Body)
+	}
+}
+
+func TestMiddleware_RouteScopes(t *testing.T) {
+	s, _ := newTestServer(t)
+	client, secret, _ := s.CreateClient(context.Background(), "Billing", nil, []string{"invoices:read", "invoices:write"}, true)
+
+	token := func(scope string) string {
+		form := url.Values{"grant_type": {"client_credentials"}, "client_id": {client.ID}, "client_secret": {secret}, "scope": {scope}}
+		return decodeToken(t, postForm(s.Token, "/oauth/token", form)).AccessToken
+	}
+	readOnly, readWrite := token("invoices:read"), token("invoices:read invoices:write")
+
+	m := mux.NewRouter()
+	m.Use(s.Middleware(m))
+	m.Post("/oauth-invoices/{id}[scopes:invoices:write]", func(w http.ResponseWriter, r *http.Request) {
+		if TokenFromContext(r.Context()) == nil {
+			t.Error("expected the token in the request context")
+		}
+	})
+
+	tests := []struct {
+		token string
+		want  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"unknown", http.StatusUnauthorized},
+		{readOnly, http.StatusForbidden},
+		{readWrite, http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/oauth-invoices/3", nil)
+		if tt.token != "" {
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("expected %d, got %d", tt.want, w.Code)
+		}
+	}
+
+	form := url.Values{"client_id": {client.ID}, "client_secret": {secret}, "token": {readWrite}}
+	if w := postForm(s.Revoke, "/oauth/revoke", form); w.Code != http.StatusOK {
+		t.Fatalf("expected the token to be revoked, got %d", w.Code)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/oauth-invoices/3", nil)
+	r.Header.Set("Authorization", "Bearer "+readWrite)
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected a revoked token to be refused, got %d", w.Code)
+	}
+}
+
+func TestMiddleware_MountedSubrouter(t *testing.T) {
+	s, _ := newTestServer(t)
+	client, secret, _ := s.CreateClient(context.Background(), "Billing", nil, []string{"invoices:read", "invoices:write"}, true)
+
+	form := url.Values{"grant_type": {"client_credentials"}, "client_id": {client.ID}, "client_secret": {secret}, "scope": {"invoices:read"}}
+	readOnly := decodeToken(t, postForm(s.Token, "/oauth/token", form)).AccessToken
+
+	h := func(w http.ResponseWriter, r *http.Request) {}
+
+	// The middleware on the subrouter, with routes added before and after it
+	// is mounted.
+	root := mux.NewRouter()
+	api := mux.NewRouter()
+	api.Use(s.Middleware(api))
+	api.Get("/invoices[scopes:invoices:read]", h)
+	root.Mount("/oauth-api", api)
+	api.Post("/invoices[scopes:invoices:write]", h)
+	api.Mux.Delete("/invoices", h)
+
+	tests := []struct {
+		method string
+		want   int
+	}{
+		{http.MethodGet, http.StatusOK},
+		{http.MethodPost, http.StatusForbidden},
+		{http.MethodDelete, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/oauth-api/invoices", nil)
+		r.Header.Set("Authorization", "Bearer "+readOnly)
+		w := httptest.NewRecorder()
+		root.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s /oauth-api/invoices: expected %d, got %d", tt.method, tt.want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/cidekar/adele-framework/database"
+)
+
+// Create a SQL store for the given pool. The database type is the value of
+// DATABASE_TYPE and is normalized to either "postgres" or "mysql".
+func NewSQLStore(db *sql.DB, databaseType string) *SQLStore {
+	return &SQLStore{
+		DB:      db,
+		Dialect: database.Dialect(databaseType),
+	}
+}
+
+func (s *SQLStore) CreateClient(ctx context.Context, client *Client) error {
+	_, err := s.DB.ExecContext(ctx, database.Rebind(s.Dialect, `INSERT INTO oauth_clients (id, name, secret, redirect_uris, scopes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`),
+		client.ID, client.Name, client.Secret, strings.Join(client.RedirectURIs, " "), strings.Join(client.Scopes, " "), client.CreatedAt.UTC())
+	return err
+}
+
+func (s *SQLStore) Client(ctx context.Context, id string) (*Client, error) {
+	var (
+		client               Client
+		redirectURIs, scopes string
+	)
+
+	err := s.DB.QueryRowContext(ctx, database.Rebind(s.Dialect, "SELECT id, name, secret, redirect_uris, scopes, created_at FROM oauth_clients WHERE id = ?"), id).
+		Scan(&client.ID, &client.Name, &client.Secret, &redirectURIs, &scopes, &client.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	client.RedirectURIs = strings.Fields(redirectURIs)
+	client.Scopes = strings.Fields(scopes)
+	return &client, nil
+}
+
+// DeleteClient removes the client; the foreign keys of the token tables remove
+// its codes and tokens.
+func (s *SQLStore) DeleteClient(ctx context.Context, id string) error {
+	return s.delete(ctx, "DELETE FROM oauth_clients WHERE id = ?", id, ErrClientNotFound)
+}
+
+func (s *SQLStore) CreateAuthCode(ctx context.Context, code *AuthCode) error {
+	_, err := s.DB.ExecContext(ctx, database.Rebind(s.Dialect, `INSERT INTO oauth_auth_codes (id, client_id, user_id, scopes, redirect_uri, code_challenge, code_challenge_method, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+		code.ID, code.ClientID, code.UserID, strings.Join(code.Scopes, " "), code.RedirectURI, code.CodeChallenge, code.CodeChallengeMethod, code.ExpiresAt.UTC())
+	return err
+}
+
+// TakeAuthCode reads and deletes the code. Only the caller whose delete removed
+// the row gets the code, so concurrent exchanges cannot both succeed.
+func (s *SQLStore) TakeAuthCode(ctx context.Context, id string) (*AuthCode, error) {
+	var (
+		code   AuthCode
+		scopes string
+	)
+
+	err := s.DB.QueryRowContext(ctx, database.Rebind(s.Dialect, `SELECT id, client_id, user_id, scopes, redirect_uri, code_challenge, code_challenge_method, expires_at
+		FROM oauth_auth_codes WHERE id = ?`), id).
+		Scan(&code.ID, &code.ClientID, &code.UserID, &scopes, &code.RedirectURI, &code.CodeChallenge, &code.CodeChallengeMethod, &code.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCodeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.delete(ctx, "DELETE FROM oauth_auth_codes WHERE id = ?", id, ErrCodeNotFound); err != nil {
+		return nil, err
+	}
+
+	code.Scopes = strings.Fields(scopes)
+	return &code, nil
+}
+
+func (s *SQLStore) CreateAccessToken(ctx context.Context, token *AccessToken) error {
+	_, err := s.DB.ExecContext(ctx, database.Rebind(s.Dialect, `INSERT INTO oauth_access_tokens (id, client_id, user_id, scopes, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`),
+		token.ID, token.ClientID, nullUserID(token.UserID), strings.Join(token.Scopes, " "), token.ExpiresAt.UTC(), token.CreatedAt.UTC())
+	return err
+}
+
+func (s *SQLStore) AccessToken(ctx context.Context, id string) (*AccessToken, error) {
+	var (
+		token  AccessToken
+		userID sql.NullInt64
+		scopes string
+	)
+
+	err := s.DB.QueryRowContext(ctx, database.Rebind(s.Dialect, "SELECT id, client_id, user_id, scopes, expires_at, created_at FROM oauth_access_tokens WHERE id = ?"), id).
+		Scan(&token.ID, &token.ClientID, &userID, &scopes, &token.ExpiresAt, &token.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	token.UserID = int(userID.Int64)
+	token.Scopes = strings.Fields(scopes)
+	return &token, nil
+}
+
+func (s *SQLStore) RevokeAccessToken(ctx context.Context, id string) error {
+	return s.delete(ctx, "DELETE FROM oauth_access_tokens WHERE id = ?", id, ErrTokenNotFound)
+}
+
+func (s *SQLStore) CreateRefreshToken(ctx context.Context, token *RefreshToken) error {
+	_, err := s.DB.ExecContext(ctx, database.Rebind(s.Dialect, `INSERT INTO oauth_refresh_tokens (id, access_token_id, client_id, user_id, scopes, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)`),
+		token.ID, token.AccessTokenID, token.ClientID, nullUserID(token.UserID), strings.Join(token.Scopes, " "), token.ExpiresAt.UTC())
+	return err
+}
+
+func (s *SQLStore) RefreshToken(ctx context.Context, id string) (*RefreshToken, error) {
+	var (
+		token  RefreshToken
+		userID sql.NullInt64
+		scopes string
+	)
+
+	err := s.DB.QueryRowContext(ctx, database.Rebind(s.Dialect, "SELECT id, access_token_id, client_id, user_id, scopes, expires_at FROM oauth_refresh_tokens WHERE id = ?"), id).
+		Scan(&token.ID, &token.AccessTokenID, &token.ClientID, &userID, &scopes, &token.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	token.UserID = int(userID.Int64)
+	token.Scopes = strings.Fields(scopes)
+	return &token, nil
+}
+
+// RevokeRefreshToken deletes the refresh token. Only the caller whose delete
+// removed the row succeeds, so a refresh token cannot be used twice.
+func (s *SQLStore) RevokeRefreshToken(ctx context.Context, id string) error {
+	return s.delete(ctx, "DELETE FROM oauth_refresh_tokens WHERE id = ?", id, ErrTokenNotFound)
+}
+
+// Run a delete by id, returning notFound when no row was removed.
+func (s *SQLStore) delete(ctx context.Context, query, id string, notFound error) error {
+	res, err := s.DB.ExecContext(ctx, database.Rebind(s.Dialect, query), id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+
+	return nil
+}
+
+// Client credentials tokens belong to no user and are stored with a NULL user.
+func nullUserID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
@@ -0,0 +1,88 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSQLStore_TakeAuthCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	s := NewSQLStore(db, "postgres")
+	expires := time.Now().Add(time.Minute)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM oauth_auth_codes WHERE id = $1")).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "client_id", "user_id", "scopes", "redirect_uri", "code_challenge", "code_challenge_method", "expires_at"}).
+			AddRow("hash", "client", 7, "photos:read photos:write", "https://app.test/callback", "challenge", "S256", expires))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM oauth_auth_codes WHERE id = $1")).
+		WithArgs("hash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	code, err := s.TakeAuthCode(context.Background(), "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code.UserID != 7 || len(code.Scopes) != 2 {
+		t.Errorf("unexpected code %+v", code)
+	}
+
+	// Another exchange deleted the row first.
+	mock.ExpectQuery("FROM oauth_auth_codes").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "client_id", "user_id", "scopes", "redirect_uri", "code_challenge", "code_challenge_method", "expires_at"}).
+			AddRow("hash", "client", 7, "", "", "", "", expires))
+	mock.ExpectExec("DELETE FROM oauth_auth_codes").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err := s.TakeAuthCode(context.Background(), "hash"); !errors.Is(err, ErrCodeNotFound) {
+		t.Errorf("expected ErrCodeNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSQLStore_ClientCredentialsToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	s := NewSQLStore(db, "mysql")
+	now := time.Now()
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO oauth_access_tokens (id, client_id, user_id, scopes, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?)")).
+		WithArgs("hash", "client", nil, "invoices:read", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM oauth_access_tokens WHERE id = ?")).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "client_id", "user_id", "scopes", "expires_at", "created_at"}).
+			AddRow("hash", "client", nil, "invoices:read", now, now))
+
+	token := &AccessToken{ID: "hash", ClientID: "client", Scopes: []string{"invoices:read"}, ExpiresAt: now, CreatedAt: now}
+	if err := s.CreateAccessToken(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.AccessToken(context.Background(), "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UserID != 0 || !got.HasScopes("invoices:read") {
+		t.Errorf("unexpected token %+v", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,165 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// A Client is an application allowed to request tokens. Confidential clients,
+// such as a server side app, authenticate with a secret of which only the
+// SHA-256 hash is stored; public clients, such as a mobile or single page app,
+// have no secret and must use PKCE.
+type Client struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Secret       string    `json:"-"`
+	RedirectURIs []string  `json:"redirectUris"`
+	Scopes       []string  `json:"scopes"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// An AuthCode is issued when a user approves a client on the consent page and
+// is exchanged once for an access token. ID is the hash of the code.
+// RedirectURI is the redirect_uri sent in the authorization request, empty when
+// the request left it out and the client's only registered URI was used.
+type AuthCode struct {
+	ID                  string
+	ClientID            string
+	UserID              int
+	Scopes              []string
+	RedirectURI         string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	ExpiresAt           time.Time
+}
+
+// An AccessToken authenticates API requests made by a client, on behalf of a
+// user or, for the client credentials grant, on its own when UserID is zero.
+// ID is the hash of the bearer token.
+type AccessToken struct {
+	ID        string    `json:"-"`
+	ClientID  string    `json:"clientId"`
+	UserID    int       `json:"userId"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// A RefreshToken is exchanged once for a new access and refresh token pair.
+// ID is the hash of the token.
+type RefreshToken struct {
+	ID            string
+	AccessTokenID string
+	ClientID      string
+	UserID        int
+	Scopes        []string
+	ExpiresAt     time.Time
+}
+
+// Store persists clients, authorization codes and tokens. TakeAuthCode removes
+// the code it returns so it can only be used once. Revoke methods report
+// ErrTokenNotFound unless their delete removed the token, so of two requests
+// consuming the same refresh token only one succeeds.
+type Store interface {
+	CreateClient(ctx context.Context, client *Client) error
+	Client(ctx context.Context, id string) (*Client, error)
+	DeleteClient(ctx context.Context, id string) error
+	CreateAuthCode(ctx context.Context, code *AuthCode) error
+	TakeAuthCode(ctx context.Context, id string) (*AuthCode, error)
+	CreateAccessToken(ctx context.Context, token *AccessToken) error
+	AccessToken(ctx context.Context, id string) (*AccessToken, error)
+	RevokeAccessToken(ctx context.Context, id string) error
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+	RefreshToken(ctx context.Context, id string) (*RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, id string) error
+}
+
+// Server is an OAuth 2.0 authorization server for the authorization code grant
+// with PKCE, the client credentials grant and refresh tokens.
+type Server struct {
+	Store Store
+
+	// Lifetimes of the codes and tokens the server issues.
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	AuthCodeTTL     time.Duration
+
+	// Where a user who is not logged in is sent before the consent page. The
+	// authorize URL is passed in the redirect query parameter.
+	LoginURL string
+
+	// Resolve the ID of the user logged in to the application.
+	User func(r *http.Request) (int, bool)
+
+	// Render the consent page, on which the user approves or denies the client.
+	Consent func(w http.ResponseWriter, r *http.Request, consent *Consent) error
+
+	// Hold the authorization request the consent page was rendered for until
+	// the user decides. Required by Authorize.
+	Session *scs.SessionManager
+
+	ErrorLog *log.Logger
+}
+
+// Consent describes an authorization request awaiting the user's decision. The
+// consent page posts its form to Action with a consent field set to Nonce and
+// an approve field set to "yes" to approve the client; any other value denies
+// it.
+type Consent struct {
+	Client *Client
+	Scopes []string
+	Action string
+	Nonce  string
+}
+
+// An authorization request held in the session while the consent page is
+// shown. RedirectURI is where the user is sent back to, RequestedRedirect the
+// redirect_uri of the request, see AuthCode.
+type pendingAuthorization struct {
+	Nonce               string    `json:"nonce"`
+	ClientID            string    `json:"client_id"`
+	UserID              int       `json:"user_id"`
+	Scopes              []string  `json:"scopes"`
+	RedirectURI         string    `json:"redirect_uri"`
+	RequestedRedirect   string    `json:"requested_redirect"`
+	State               string    `json:"state"`
+	CodeChallenge       string    `json:"code_challenge"`
+	CodeChallengeMethod string    `json:"code_challenge_method"`
+	ExpiresAt           time.Time `json:"expires_at"`
+}
+
+// Error is an OAuth 2.0 error response, see RFC 6749 section 5.2.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	Status      int    `json:"-"`
+}
+
+// MemoryStore keeps clients and tokens in memory, for tests and development.
+type MemoryStore struct {
+	mu            sync.Mutex
+	clients       map[string]Client
+	codes         map[string]AuthCode
+	accessTokens  map[string]AccessToken
+	refreshTokens map[string]RefreshToken
+}
+
+// SQLStore keeps clients and tokens in the oauth_clients, oauth_auth_codes,
+// oauth_access_tokens and oauth_refresh_tokens tables.
+type SQLStore struct {
+	DB      *sql.DB
+	Dialect string
+}
+
+var (
+	ErrClientNotFound = errors.New("oauth: client not found")
+	ErrCodeNotFound   = errors.New("oauth: authorization code not found")
+	ErrTokenNotFound  = errors.New("oauth: token not found")
+)
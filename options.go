@@ -0,0 +1,96 @@
+package adele
+
+import "github.com/cidekar/adele-framework/config"
+
+// Option configures how Adele.New bootstraps the application. By default every
+// subsystem is booted; options switch individual subsystems off (or back on) so
+// tests, CLI tools and small services only pay for what they use.
+type Option func(*bootOptions)
+
+// Boot every subsystem unless an option says otherwise.
+func defaultBootOptions() bootOptions {
+	return bootOptions{
+		cache:      true,
+		database:   true,
+		filesystem: true,
+		mail:       true,
+		providers:  true,
+		queue:      true,
+		render:     true,
+	}
+}
+
+// Turn off every optional subsystem. Combine with the With* options to opt back
+// in to only the subsystems an application needs, for example:
+//
+//	a.New(path, adele.WithoutSubsystems(), adele.WithDatabase(true))
+func WithoutSubsystems() Option {
+	return func(o *bootOptions) {
+		o.cache = false
+		o.database = false
+		o.filesystem = false
+		o.mail = false
+		o.providers = false
+		o.queue = false
+		o.render = false
+	}
+}
+
+// Choose whether the cache (redis or badger, per CACHE) is booted.
+func WithCache(enabled bool) Option {
+	return func(o *bootOptions) {
+		o.cache = enabled
+	}
+}
+
+// Choose whether a database connection is opened. Database backed session stores
+// require the database to be booted.
+func WithDatabase(enabled bool) Option {
+	return func(o *bootOptions) {
+		o.database = enabled
+	}
+}
+
+// Choose whether the S3, Minio, SFTP and WebDAV filesystems are configured.
+func WithFilesystem(enabled bool) Option {
+	return func(o *bootOptions) {
+		o.filesystem = enabled
+	}
+}
+
+// Choose whether the mailer is configured.
+func WithMail(enabled bool) Option {
+	return func(o *bootOptions) {
+		o.mail = enabled
+	}
+}
+
+// Choose whether registered service providers are loaded.
+func WithProviders(enabled bool) Option {
+	return func(o *bootOptions) {
+		o.providers = enabled
+	}
+}
+
+// Choose whether the background job queue (memory, redis or database, per
+// QUEUE_TYPE) is booted.
+func WithQueue(enabled bool) Option {
+	return func(o *bootOptions) {
+		o.queue = enabled
+	}
+}
+
+// Choose whether the Jet engine and the page renderer are configured.
+func WithRender(enabled bool) Option {
+	return func(o *bootOptions) {
+		o.render = enabled
+	}
+}
+
+// Use an already loaded configuration instead of reading it from the application's
+// .env file, config/ directory and environment.
+func WithConfig(cfg *config.Config) Option {
+	return func(o *bootOptions) {
+		o.config = cfg
+	}
+}
@@ -1,17 +1,34 @@
 // Package provider implements a service-provider registry for extending the
 // application with pluggable, configurable components.
 //
-// Providers are registered globally, sorted by priority, then configured,
-// registered, and booted in two passes when loaded into an application.
-// Providers may opt into configuration, custom priority, or optional booting.
+// Providers are registered globally, sorted by priority and dependencies, then
+// configured, registered, and booted in two passes when loaded into an
+// application. Providers may opt into configuration, custom priority, optional
+// booting, dependencies on other providers, or deferred loading.
 package provider
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v2"
 )
 
 var globalProviders []ServiceProvider
 
+// New creates a provider manager with empty enablement and configuration maps
+func New() *Provider {
+	return &Provider{
+		EnabledProviders: make(map[string]bool),
+		ProviderConfigs:  make(map[string]map[string]interface{}),
+		loaded:           make(map[string]bool),
+	}
+}
+
 // RegisterGlobalProvider adds a provider to the global registry
 func RegisterGlobalProvider(provider ServiceProvider) {
 	for _, p := range globalProviders {
@@ -50,38 +67,132 @@ func (p *Provider) SetProviderEnabled(name string, enabled bool) {
 
 // SetProviderConfig sets configuration for a provider
 func (p *Provider) SetProviderConfig(name string, config map[string]interface{}) {
-	fmt.Println("SetProviderConfig:", name, config)
 	p.ProviderConfigs[name] = config
 }
 
-// LoadProviders discovers and loads all registered providers into the application
+// LoadConfig reads per-provider settings from a YAML file, typically
+// config/providers.yml. Each top-level key is a provider name; its optional
+// `enabled` flag enables or disables the provider and the remaining keys,
+// including an optional `priority`, are passed to ConfigurableProvider.Configure.
+// A missing file is not an error.
+//
+//	stripe:
+//	  enabled: true
+//	  priority: 20
+//	  api_key: sk_test_123
+func (p *Provider) LoadConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read provider config: %w", err)
+	}
+
+	var doc map[string]map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse provider config: %w", err)
+	}
+
+	if p.EnabledProviders == nil {
+		p.EnabledProviders = make(map[string]bool)
+	}
+	if p.ProviderConfigs == nil {
+		p.ProviderConfigs = make(map[string]map[string]interface{})
+	}
+
+	for name, settings := range doc {
+		if enabled, ok := settings["enabled"]; ok {
+			b, ok := enabled.(bool)
+			if !ok {
+				return fmt.Errorf("provider '%s': enabled must be true or false", name)
+			}
+			p.SetProviderEnabled(name, b)
+			delete(settings, "enabled")
+		}
+		if settings == nil {
+			settings = map[string]interface{}{}
+		}
+		p.SetProviderConfig(name, normalizeConfig(settings))
+	}
+
+	return nil
+}
+
+// normalizeConfig converts the nested maps produced by the YAML decoder into
+// string keyed maps so providers can type assert them predictably
+func normalizeConfig(config map[string]interface{}) map[string]interface{} {
+	for key, value := range config {
+		config[key] = normalizeValue(value)
+	}
+	return config
+}
+
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalizeValue(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeValue(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// LoadProviders discovers and loads all registered providers into the application.
+// Providers are ordered by priority and then by their dependencies, so a provider
+// always registers and boots after the providers it depends on. Deferred providers
+// are held back until Resolve is called for one of their services, unless an
+// eagerly loaded provider depends on them.
 func (p *Provider) LoadProviders(app interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.app = app
+	if p.loaded == nil {
+		p.loaded = make(map[string]bool)
+	}
+
 	providers := make([]ServiceProvider, len(globalProviders))
 	copy(providers, globalProviders)
 
 	// Sort providers by priority
 	sortedProviders := p.sortProvidersByPriority(providers)
 
-	// First pass: Register all enabled providers
-	var registeredProviders []ServiceProvider
+	var enabledProviders []ServiceProvider
 	for _, prov := range sortedProviders {
 		if !p.IsProviderEnabled(prov.Name()) {
-			fmt.Printf("Skipping disabled provider: %s\n", prov.Name())
+			p.debugf("skipping disabled provider: %s", prov.Name())
 			continue
 		}
+		enabledProviders = append(enabledProviders, prov)
+	}
 
-		// Configure provider if it supports configuration
-		if configurable, ok := prov.(ConfigurableProvider); ok {
-			if config := p.GetProviderConfig(prov.Name()); config != nil {
-				if err := configurable.Configure(config); err != nil {
-					return fmt.Errorf("failed to configure provider '%s': %w", prov.Name(), err)
-				}
-			}
+	// Order providers so dependencies come first
+	orderedProviders, err := sortProvidersByDependency(enabledProviders)
+	if err != nil {
+		return err
+	}
+
+	eager := eagerProviders(orderedProviders)
+
+	// First pass: Register all eager providers
+	var registeredProviders []ServiceProvider
+	for _, prov := range orderedProviders {
+		if !eager[prov.Name()] {
+			p.deferred = append(p.deferred, prov)
+			continue
 		}
 
-		fmt.Printf("Registering provider: %s\n", prov.Name())
-		if err := prov.Register(app); err != nil {
-			return fmt.Errorf("failed to register provider '%s': %w", prov.Name(), err)
+		if err := p.register(prov); err != nil {
+			return err
 		}
 
 		registeredProviders = append(registeredProviders, prov)
@@ -89,21 +200,218 @@ func (p *Provider) LoadProviders(app interface{}) error {
 
 	// Second pass: Boot all registered providers
 	for _, prov := range registeredProviders {
-		fmt.Printf("Booting provider: %s\n", prov.Name())
-		if err := prov.Boot(app); err != nil {
-			// Check if provider is optional
-			if optional, ok := prov.(OptionalProvider); ok && optional.IsOptional() {
-				fmt.Printf("Warning: Optional provider '%s' failed to boot: %v\n", prov.Name(), err)
+		if err := p.boot(prov); err != nil {
+			return err
+		}
+	}
+
+	p.debugf("loaded %d providers", len(registeredProviders))
+	return nil
+}
+
+// Resolve registers and boots the deferred provider with the given name, or the
+// deferred provider that lists the name in Provides, along with any deferred
+// providers it depends on. Resolving a provider that is already loaded is a no-op.
+func (p *Provider) Resolve(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.loaded[name] {
+		return nil
+	}
+
+	prov := p.findDeferred(name)
+	if prov == nil {
+		return fmt.Errorf("no provider found for '%s'", name)
+	}
+
+	return p.load(prov)
+}
+
+// IsProviderLoaded reports whether a provider has been registered and booted
+func (p *Provider) IsProviderLoaded(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.loaded[name]
+}
+
+// load registers and boots a deferred provider after its dependencies
+func (p *Provider) load(prov ServiceProvider) error {
+	if p.loaded[prov.Name()] {
+		return nil
+	}
+
+	if dependent, ok := prov.(DependentProvider); ok {
+		for _, dep := range dependent.DependsOn() {
+			if p.loaded[dep] {
 				continue
 			}
-			return fmt.Errorf("failed to boot provider '%s': %w", prov.Name(), err)
+			depProvider := p.findDeferred(dep)
+			if depProvider == nil {
+				return fmt.Errorf("provider '%s' depends on '%s' which is not loaded", prov.Name(), dep)
+			}
+			if err := p.load(depProvider); err != nil {
+				return err
+			}
 		}
 	}
 
-	fmt.Printf("Successfully loaded %d providers\n", len(registeredProviders))
+	if err := p.register(prov); err != nil {
+		return err
+	}
+
+	return p.boot(prov)
+}
+
+// register configures a provider if it supports configuration and then registers it
+func (p *Provider) register(prov ServiceProvider) error {
+	if configurable, ok := prov.(ConfigurableProvider); ok {
+		if config := p.GetProviderConfig(prov.Name()); config != nil {
+			if err := configurable.Configure(config); err != nil {
+				return fmt.Errorf("failed to configure provider '%s': %w", prov.Name(), err)
+			}
+		}
+	}
+
+	p.debugf("registering provider: %s", prov.Name())
+	if err := prov.Register(p.app); err != nil {
+		return fmt.Errorf("failed to register provider '%s': %w", prov.Name(), err)
+	}
+
+	return nil
+}
+
+// boot boots a registered provider and marks it loaded. Optional providers that
+// fail to boot are reported and skipped.
+func (p *Provider) boot(prov ServiceProvider) error {
+	p.debugf("booting provider: %s", prov.Name())
+	if err := prov.Boot(p.app); err != nil {
+		// Check if provider is optional
+		if optional, ok := prov.(OptionalProvider); ok && optional.IsOptional() {
+			if p.Log != nil {
+				p.Log.Warnf("optional provider '%s' failed to boot: %v", prov.Name(), err)
+			}
+			return nil
+		}
+		return fmt.Errorf("failed to boot provider '%s': %w", prov.Name(), err)
+	}
+
+	p.loaded[prov.Name()] = true
 	return nil
 }
 
+// findDeferred returns the pending deferred provider named name or providing the
+// service name
+func (p *Provider) findDeferred(name string) ServiceProvider {
+	for _, prov := range p.deferred {
+		if prov.Name() == name {
+			return prov
+		}
+		if deferred, ok := prov.(DeferredProvider); ok && slices.Contains(deferred.Provides(), name) {
+			return prov
+		}
+	}
+	return nil
+}
+
+// eagerProviders returns the names of the providers to load immediately: every
+// provider that is not deferred, and every deferred provider an eager provider
+// depends on
+func eagerProviders(providers []ServiceProvider) map[string]bool {
+	byName := make(map[string]ServiceProvider, len(providers))
+	for _, prov := range providers {
+		byName[prov.Name()] = prov
+	}
+
+	eager := make(map[string]bool)
+	var mark func(prov ServiceProvider)
+	mark = func(prov ServiceProvider) {
+		if eager[prov.Name()] {
+			return
+		}
+		eager[prov.Name()] = true
+		if dependent, ok := prov.(DependentProvider); ok {
+			for _, dep := range dependent.DependsOn() {
+				mark(byName[dep])
+			}
+		}
+	}
+
+	for _, prov := range providers {
+		if _, ok := prov.(DeferredProvider); !ok {
+			mark(prov)
+		}
+	}
+
+	return eager
+}
+
+// sortProvidersByDependency orders providers so every provider follows the
+// providers it depends on, keeping the incoming priority order otherwise. It
+// returns an error for a missing dependency or a dependency cycle.
+func sortProvidersByDependency(providers []ServiceProvider) ([]ServiceProvider, error) {
+	present := make(map[string]bool, len(providers))
+	for _, prov := range providers {
+		present[prov.Name()] = true
+	}
+
+	for _, prov := range providers {
+		if dependent, ok := prov.(DependentProvider); ok {
+			for _, dep := range dependent.DependsOn() {
+				if !present[dep] {
+					return nil, fmt.Errorf("provider '%s' depends on '%s' which is not registered or is disabled", prov.Name(), dep)
+				}
+			}
+		}
+	}
+
+	placed := make(map[string]bool, len(providers))
+	sorted := make([]ServiceProvider, 0, len(providers))
+	remaining := providers
+
+	for len(remaining) > 0 {
+		progress := false
+		var next []ServiceProvider
+
+		for _, prov := range remaining {
+			if dependenciesPlaced(prov, placed) && !progress {
+				sorted = append(sorted, prov)
+				placed[prov.Name()] = true
+				progress = true
+				continue
+			}
+			next = append(next, prov)
+		}
+
+		if !progress {
+			var names []string
+			for _, prov := range remaining {
+				names = append(names, prov.Name())
+			}
+			return nil, fmt.Errorf("dependency cycle between providers: %s", strings.Join(names, ", "))
+		}
+
+		remaining = next
+	}
+
+	return sorted, nil
+}
+
+// dependenciesPlaced reports whether every dependency of a provider is placed
+func dependenciesPlaced(prov ServiceProvider, placed map[string]bool) bool {
+	dependent, ok := prov.(DependentProvider)
+	if !ok {
+		return true
+	}
+	for _, dep := range dependent.DependsOn() {
+		if !placed[dep] {
+			return false
+		}
+	}
+	return true
+}
+
 // sortProvidersByPriority sorts providers by priority (lowest first)
 func (p *Provider) sortProvidersByPriority(providers []ServiceProvider) []ServiceProvider {
 	type providerWithPriority struct {
@@ -149,3 +457,11 @@ func (p *Provider) sortProvidersByPriority(providers []ServiceProvider) []Servic
 	}
 	return sorted
 }
+
+// debugf logs the progress of loading providers at debug level, when the
+// manager has a logger.
+func (p *Provider) debugf(format string, args ...interface{}) {
+	if p.Log != nil {
+		p.Log.Debugf(format, args...)
+	}
+}
@@ -1,8 +1,14 @@
 package provider
 
 import (
+	"bytes"
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/sirupsen/logrus"
 )
 
 // Mock providers for testing
@@ -321,11 +327,21 @@ func TestLoadProvidersOptionalBootError(t *testing.T) {
 		ProviderConfigs:  make(map[string]map[string]interface{}),
 	}
 
+	var out bytes.Buffer
+	p.Log = logrus.New()
+	p.Log.SetOutput(&out)
+
 	app := &struct{}{}
 	err := p.LoadProviders(app)
 	if err != nil {
 		t.Error("Optional provider failure should not stop loading")
 	}
+	if !strings.Contains(out.String(), "level=warning") || !strings.Contains(out.String(), "boot failed") {
+		t.Errorf("expected the failure to be logged as a warning, got %q", out.String())
+	}
+	if strings.Contains(out.String(), "booting provider") {
+		t.Errorf("expected progress to be logged at debug level only, got %q", out.String())
+	}
 }
 
 func TestSortProvidersByPriorityDefault(t *testing.T) {
@@ -553,3 +569,173 @@ func TestLoadProvidersExecutionOrder(t *testing.T) {
 		}
 	}
 }
+
+// dependentProvider is a tracking provider that declares dependencies and may be deferred
+type dependentProvider struct {
+	trackingProvider
+	dependsOn []string
+}
+
+func (d *dependentProvider) DependsOn() []string {
+	return d.dependsOn
+}
+
+type deferredProvider struct {
+	dependentProvider
+	provides []string
+}
+
+func (d *deferredProvider) Provides() []string {
+	return d.provides
+}
+
+func newDependent(name string, priority int, order *[]string, deps ...string) *dependentProvider {
+	return &dependentProvider{
+		trackingProvider: trackingProvider{name: name, priority: priority, executionOrder: order, orderPrefix: name},
+		dependsOn:        deps,
+	}
+}
+
+func TestLoadProvidersDependencyOrder(t *testing.T) {
+	resetGlobalProviders()
+
+	var executionOrder []string
+
+	// "cache" has the higher priority but depends on "database"
+	RegisterGlobalProvider(newDependent("cache", 10, &executionOrder, "database"))
+	RegisterGlobalProvider(newDependent("database", 50, &executionOrder))
+
+	p := New()
+	if err := p.LoadProviders(&struct{}{}); err != nil {
+		t.Fatalf("LoadProviders failed: %v", err)
+	}
+
+	expected := []string{"database-register", "cache-register", "database-boot", "cache-boot"}
+	if len(executionOrder) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, executionOrder)
+	}
+	for i := range expected {
+		if executionOrder[i] != expected[i] {
+			t.Errorf("Execution order mismatch at position %d: expected %s, got %s", i, expected[i], executionOrder[i])
+		}
+	}
+}
+
+func TestLoadProvidersMissingDependency(t *testing.T) {
+	resetGlobalProviders()
+
+	var executionOrder []string
+	RegisterGlobalProvider(newDependent("cache", 10, &executionOrder, "database"))
+
+	err := New().LoadProviders(&struct{}{})
+	if err == nil || !strings.Contains(err.Error(), "depends on 'database'") {
+		t.Errorf("Expected missing dependency error, got %v", err)
+	}
+}
+
+func TestLoadProvidersDependencyCycle(t *testing.T) {
+	resetGlobalProviders()
+
+	var executionOrder []string
+	RegisterGlobalProvider(newDependent("a", 10, &executionOrder, "b"))
+	RegisterGlobalProvider(newDependent("b", 10, &executionOrder, "a"))
+
+	err := New().LoadProviders(&struct{}{})
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected dependency cycle error, got %v", err)
+	}
+}
+
+func TestLoadProvidersDeferred(t *testing.T) {
+	resetGlobalProviders()
+
+	var executionOrder []string
+	deferred := &deferredProvider{
+		dependentProvider: *newDependent("search", 10, &executionOrder),
+		provides:          []string{"search.client"},
+	}
+	RegisterGlobalProvider(deferred)
+	RegisterGlobalProvider(newDependent("app", 20, &executionOrder))
+
+	p := New()
+	if err := p.LoadProviders(&struct{}{}); err != nil {
+		t.Fatalf("LoadProviders failed: %v", err)
+	}
+
+	if p.IsProviderLoaded("search") {
+		t.Fatal("Deferred provider should not be loaded before it is resolved")
+	}
+	if len(executionOrder) != 2 {
+		t.Fatalf("Expected only the eager provider to run, got %v", executionOrder)
+	}
+
+	if err := p.Resolve("search.client"); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if !p.IsProviderLoaded("search") {
+		t.Error("Deferred provider should be loaded after it is resolved")
+	}
+
+	// Resolving again must not register the provider twice
+	if err := p.Resolve("search"); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if len(executionOrder) != 4 {
+		t.Errorf("Expected deferred provider to register and boot once, got %v", executionOrder)
+	}
+
+	if err := p.Resolve("unknown"); err == nil {
+		t.Error("Expected error resolving an unknown provider")
+	}
+}
+
+func TestLoadProvidersDeferredDependencyLoadedEagerly(t *testing.T) {
+	resetGlobalProviders()
+
+	var executionOrder []string
+	RegisterGlobalProvider(&deferredProvider{
+		dependentProvider: *newDependent("queue", 10, &executionOrder),
+	})
+	RegisterGlobalProvider(newDependent("mailer", 20, &executionOrder, "queue"))
+
+	p := New()
+	if err := p.LoadProviders(&struct{}{}); err != nil {
+		t.Fatalf("LoadProviders failed: %v", err)
+	}
+
+	if !p.IsProviderLoaded("queue") {
+		t.Error("Deferred provider required by an eager provider should be loaded")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "providers.yml")
+	content := "stripe:\n  enabled: true\n  priority: 20\n  api_key: sk_test\n  webhooks:\n    path: /stripe\nlegacy:\n  enabled: false\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	p := &Provider{}
+	if err := p.LoadConfig(path); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if p.IsProviderEnabled("legacy") {
+		t.Error("legacy provider should be disabled")
+	}
+
+	config := p.GetProviderConfig("stripe")
+	if config["api_key"] != "sk_test" || config["priority"] != 20 {
+		t.Errorf("Unexpected stripe config: %v", config)
+	}
+	if _, ok := config["enabled"]; ok {
+		t.Error("enabled flag should not be passed to Configure")
+	}
+	if webhooks, ok := config["webhooks"].(map[string]interface{}); !ok || webhooks["path"] != "/stripe" {
+		t.Errorf("Expected nested config to be string keyed, got %#v", config["webhooks"])
+	}
+
+	if err := p.LoadConfig(filepath.Join(t.TempDir(), "missing.yml")); err != nil {
+		t.Errorf("Missing config file should not be an error, got %v", err)
+	}
+}
@@ -1,10 +1,23 @@
 package provider
 
-// Provider manages the loading and bootstrapping of service providers
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Provider manages the loading and bootstrapping of service providers. Progress
+// is logged to Log at debug level, and optional providers that fail to boot are
+// logged as warnings; a nil Log logs nothing.
 type Provider struct {
-	//App              *adele.Adele
 	EnabledProviders map[string]bool
 	ProviderConfigs  map[string]map[string]interface{}
+	Log              *logrus.Logger
+
+	app      interface{}
+	deferred []ServiceProvider
+	loaded   map[string]bool
+	mu       sync.Mutex
 }
 
 // ServiceProvider is the expected interface every provider must implement
@@ -31,3 +44,17 @@ type PriorityProvider interface {
 	ServiceProvider
 	Priority() int
 }
+
+// DependentProvider allows providers to require other providers, by name, to be
+// registered and booted before them
+type DependentProvider interface {
+	ServiceProvider
+	DependsOn() []string
+}
+
+// DeferredProvider allows providers to postpone registering and booting until one
+// of the services they provide is first resolved
+type DeferredProvider interface {
+	ServiceProvider
+	Provides() []string
+}
@@ -0,0 +1,148 @@
+package queue
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// Create an empty in-memory driver.
+func NewMemoryDriver() *MemoryDriver {
+	return &MemoryDriver{
+		reserved: make(map[string]memoryReservation),
+	}
+}
+
+// Push adds a copy of the envelope to the pending jobs.
+func (d *MemoryDriver) Push(ctx context.Context, env *Envelope) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	e := *env
+	d.pending = append(d.pending, &e)
+	return nil
+}
+
+// Pop reserves the oldest available job from the first queue that has one,
+// under a reservation token of its own. Reservations older than retryAfter are
+// returned to the pending jobs first.
+func (d *MemoryDriver) Pop(ctx context.Context, queues []string, retryAfter time.Duration) (*Envelope, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	for id, r := range d.reserved {
+		if now.After(r.expires) {
+			d.pending = append(d.pending, r.env)
+			delete(d.reserved, id)
+		}
+	}
+
+	for _, name := range queues {
+		for i, env := range d.pending {
+			if env.Queue != name || env.AvailableAt.After(now) {
+				continue
+			}
+			d.pending = slices.Delete(d.pending, i, i+1)
+			env.Attempts++
+			token := newID()
+			d.reserved[env.ID] = memoryReservation{env: env, token: token, expires: now.Add(retryAfter)}
+
+			e := *env
+			e.reservation = token
+			return &e, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Ack removes a reserved job, unless its reservation expired and the job was
+// handed out again.
+func (d *MemoryDriver) Ack(ctx context.Context, env *Envelope) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.unreserve(env)
+	return nil
+}
+
+// Release moves a reserved job back to the pending jobs, available after delay,
+// unless its reservation expired and the job was handed out again.
+func (d *MemoryDriver) Release(ctx context.Context, env *Envelope, delay time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.unreserve(env) {
+		return nil
+	}
+	e := *env
+	e.reservation = ""
+	e.AvailableAt = time.Now().UTC().Add(delay)
+	d.pending = append(d.pending, &e)
+	return nil
+}
+
+// Fail moves a reserved job to the failed jobs, unless its reservation expired
+// and the job was handed out again.
+func (d *MemoryDriver) Fail(ctx context.Context, env *Envelope, jobErr error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.unreserve(env) {
+		return nil
+	}
+	d.failed = append(d.failed, FailedJob{Envelope: *env, Error: errorString(jobErr), FailedAt: time.Now().UTC()})
+	return nil
+}
+
+// Failed lists the failed jobs in the order they failed.
+func (d *MemoryDriver) Failed(ctx context.Context) ([]FailedJob, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return slices.Clone(d.failed), nil
+}
+
+// ForgetFailed removes one failed job.
+func (d *MemoryDriver) ForgetFailed(ctx context.Context, id string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i, f := range d.failed {
+		if f.Envelope.ID == id {
+			d.failed = slices.Delete(d.failed, i, i+1)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FlushFailed removes every failed job.
+func (d *MemoryDriver) FlushFailed(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.failed = nil
+	return nil
+}
+
+// End the reservation the envelope was handed out under and report whether it
+// was still held. Callers hold d.mu.
+func (d *MemoryDriver) unreserve(env *Envelope) bool {
+	r, ok := d.reserved[env.ID]
+	if !ok || r.token != env.reservation {
+		return false
+	}
+
+	delete(d.reserved, env.ID)
+	return true
+}
+
+// The message stored for a failed job.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
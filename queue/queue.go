@@ -0,0 +1,208 @@
+// Package queue runs slow work in the background through pluggable drivers.
+//
+// Jobs implement the Job interface and are registered by name with RegisterJob.
+// A Queue encodes dispatched jobs into envelopes stored by a Driver (in memory,
+// Redis or the SQL database), and a Worker reserves them, runs them with a
+// timeout and retries failures with exponential backoff until their attempts
+// are exhausted, at which point they move to the failed jobs store.
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]reflect.Type{}
+)
+
+// RegisterJob makes a job type known to workers so dispatched jobs of that name
+// can be decoded. Register a zero value of each job type, typically from init:
+//
+//	queue.RegisterJob(&jobs.SendInvoice{})
+func RegisterJob(job Job) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[job.Name()]; exists {
+		panic(fmt.Sprintf("queue: job with name '%s' already registered", job.Name()))
+	}
+	registry[job.Name()] = reflect.TypeOf(job)
+}
+
+// Create a queue that dispatches to a driver with the default queue name, three
+// attempts and a sixty second timeout per attempt.
+func New(driver Driver) *Queue {
+	return &Queue{
+		Driver:       driver,
+		DefaultQueue: "default",
+		MaxAttempts:  3,
+		Timeout:      60 * time.Second,
+	}
+}
+
+// DispatchOption changes how a single job is dispatched.
+type DispatchOption func(*Envelope)
+
+// Dispatch the job to the named queue.
+func OnQueue(name string) DispatchOption {
+	return func(env *Envelope) {
+		env.Queue = name
+	}
+}
+
+// Delay the first attempt of the job.
+func Delay(d time.Duration) DispatchOption {
+	return func(env *Envelope) {
+		env.AvailableAt = env.AvailableAt.Add(d)
+	}
+}
+
+// Override the number of attempts for the job.
+func Attempts(n int) DispatchOption {
+	return func(env *Envelope) {
+		env.MaxAttempts = n
+	}
+}
+
+// Override how long a single attempt of the job may run.
+func Timeout(d time.Duration) DispatchOption {
+	return func(env *Envelope) {
+		env.Timeout = d
+	}
+}
+
+// Dispatch a job to run in the background and return its id. The job's own
+// queue, attempts and timeout apply unless overridden by options.
+func (q *Queue) Dispatch(ctx context.Context, job Job, opts ...DispatchOption) (string, error) {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return "", fmt.Errorf("queue: encode job '%s': %w", job.Name(), err)
+	}
+
+	now := time.Now().UTC()
+	env := &Envelope{
+		ID:          newID(),
+		Queue:       q.DefaultQueue,
+		Name:        job.Name(),
+		Payload:     payload,
+		MaxAttempts: q.MaxAttempts,
+		Timeout:     q.Timeout,
+		AvailableAt: now,
+		CreatedAt:   now,
+	}
+
+	if j, ok := job.(QueuedJob); ok && j.Queue() != "" {
+		env.Queue = j.Queue()
+	}
+	if j, ok := job.(AttemptsJob); ok && j.MaxAttempts() > 0 {
+		env.MaxAttempts = j.MaxAttempts()
+	}
+	if j, ok := job.(TimeoutJob); ok && j.Timeout() > 0 {
+		env.Timeout = j.Timeout()
+	}
+	for _, opt := range opts {
+		opt(env)
+	}
+
+	if err := q.Driver.Push(ctx, env); err != nil {
+		return "", fmt.Errorf("queue: push job '%s': %w", job.Name(), err)
+	}
+
+	return env.ID, nil
+}
+
+// List the failed jobs, oldest first.
+func (q *Queue) Failed(ctx context.Context) ([]FailedJob, error) {
+	return q.Driver.Failed(ctx)
+}
+
+// Push a failed job back onto its queue with its attempts reset.
+func (q *Queue) Retry(ctx context.Context, id string) error {
+	failed, err := q.Driver.Failed(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range failed {
+		if f.Envelope.ID == id {
+			return q.retry(ctx, f)
+		}
+	}
+
+	return ErrFailedJobNotFound
+}
+
+// Push every failed job back onto its queue and return how many were retried.
+func (q *Queue) RetryAll(ctx context.Context) (int, error) {
+	failed, err := q.Driver.Failed(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, f := range failed {
+		if err := q.retry(ctx, f); err != nil {
+			return i, err
+		}
+	}
+
+	return len(failed), nil
+}
+
+// Remove every failed job.
+func (q *Queue) Flush(ctx context.Context) error {
+	return q.Driver.FlushFailed(ctx)
+}
+
+func (q *Queue) retry(ctx context.Context, f FailedJob) error {
+	env := f.Envelope
+	env.Attempts = 0
+	env.AvailableAt = time.Now().UTC()
+	env.reservation = ""
+
+	if err := q.Driver.Push(ctx, &env); err != nil {
+		return err
+	}
+
+	_, err := q.Driver.ForgetFailed(ctx, env.ID)
+	return err
+}
+
+// Decode an envelope into a new value of its registered job type.
+func decode(env *Envelope) (Job, error) {
+	registryMu.RLock()
+	t, ok := registry[env.Name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJob, env.Name)
+	}
+
+	if t.Kind() == reflect.Pointer {
+		v := reflect.New(t.Elem())
+		if err := json.Unmarshal(env.Payload, v.Interface()); err != nil {
+			return nil, err
+		}
+		return v.Interface().(Job), nil
+	}
+
+	v := reflect.New(t)
+	if err := json.Unmarshal(env.Payload, v.Interface()); err != nil {
+		return nil, err
+	}
+	return v.Elem().Interface().(Job), nil
+}
+
+// Generate a random job id.
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,370 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A job recording how often it ran, failing until it has run FailTimes times.
+type testJob struct {
+	Key       string `json:"key"`
+	FailTimes int    `json:"fail_times"`
+	Sleep     int    `json:"sleep"`
+}
+
+var (
+	runsMu sync.Mutex
+	runs   = map[string]int{}
+	failed = map[string]error{}
+)
+
+func (j *testJob) Name() string { return "test-job" }
+
+func (j *testJob) Handle(ctx context.Context) error {
+	runsMu.Lock()
+	runs[j.Key]++
+	n := runs[j.Key]
+	runsMu.Unlock()
+
+	if j.Sleep > 0 {
+		select {
+		case <-time.After(time.Duration(j.Sleep) * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if n <= j.FailTimes {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+func (j *testJob) Failed(ctx context.Context, err error) {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+	failed[j.Key] = err
+}
+
+func runCount(key string) int {
+	runsMu.Lock()
+	defer runsMu.Unlock()
+	return runs[key]
+}
+
+func init() {
+	RegisterJob(&testJob{})
+}
+
+// Create a worker with delays short enough for tests.
+func newTestWorker(q *Queue) *Worker {
+	w := q.NewWorker()
+	w.Sleep = 5 * time.Millisecond
+	w.Backoff = time.Millisecond
+	w.MaxBackoff = 2 * time.Millisecond
+	return w
+}
+
+func TestDispatch_Options(t *testing.T) {
+	driver := NewMemoryDriver()
+	q := New(driver)
+
+	id, err := q.Dispatch(context.Background(), &testJob{Key: "options"}, OnQueue("emails"), Attempts(7), Timeout(time.Second), Delay(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := driver.pending[0]
+	if env.ID != id || env.Queue != "emails" || env.MaxAttempts != 7 || env.Timeout != time.Second {
+		t.Errorf("unexpected envelope: %+v", env)
+	}
+	if !env.AvailableAt.After(time.Now().Add(59 * time.Minute)) {
+		t.Error("expected the job to be delayed")
+	}
+
+	popped, _ := driver.Pop(context.Background(), []string{"emails"}, time.Minute)
+	if popped != nil {
+		t.Error("expected a delayed job not to be reserved")
+	}
+}
+
+func TestWorker_RetriesWithBackoff(t *testing.T) {
+	q := New(NewMemoryDriver())
+	if _, err := q.Dispatch(context.Background(), &testJob{Key: "retry", FailTimes: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := newTestWorker(q)
+	w.MaxJobs = 3
+	if err := w.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := runCount("retry"); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+	if jobs, _ := q.Failed(context.Background()); len(jobs) != 0 {
+		t.Errorf("expected no failed jobs, got %d", len(jobs))
+	}
+}
+
+func TestWorker_FailsAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	q := New(NewMemoryDriver())
+	id, err := q.Dispatch(ctx, &testJob{Key: "fail", FailTimes: 10}, Attempts(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := newTestWorker(q)
+	w.MaxJobs = 2
+	if err := w.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, _ := q.Failed(ctx)
+	if len(jobs) != 1 || jobs[0].Envelope.ID != id || jobs[0].Error != "boom" {
+		t.Fatalf("expected the job in the failed store, got %+v", jobs)
+	}
+	runsMu.Lock()
+	if failed["fail"] == nil {
+		t.Error("expected the Failed hook to run")
+	}
+	runsMu.Unlock()
+
+	if err := q.Retry(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if jobs, _ := q.Failed(ctx); len(jobs) != 0 {
+		t.Error("expected the retried job to leave the failed store")
+	}
+	if err := q.Retry(ctx, id); !errors.Is(err, ErrFailedJobNotFound) {
+		t.Errorf("expected ErrFailedJobNotFound, got %v", err)
+	}
+}
+
+func TestWorker_CountsAbandonedReservations(t *testing.T) {
+	ctx := context.Background()
+	q := New(NewMemoryDriver())
+	id, err := q.Dispatch(ctx, &testJob{Key: "abandoned", FailTimes: 10}, Attempts(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A worker reserving the job and dying before it finishes.
+	if env, _ := q.Driver.Pop(ctx, []string{"default"}, 0); env == nil || env.Attempts != 1 {
+		t.Fatalf("expected the reservation to count an attempt, got %+v", env)
+	}
+	time.Sleep(time.Millisecond)
+
+	w := newTestWorker(q)
+	w.MaxJobs = 1
+	if err := w.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, _ := q.Failed(ctx)
+	if len(jobs) != 1 || jobs[0].Envelope.ID != id || jobs[0].Envelope.Attempts != 2 {
+		t.Fatalf("expected the job to fail on its second attempt, got %+v", jobs)
+	}
+}
+
+func TestMemoryDriver_AckByReservation(t *testing.T) {
+	ctx := context.Background()
+	driver := NewMemoryDriver()
+	q := New(driver)
+
+	if _, err := q.Dispatch(ctx, &testJob{Key: "redelivered"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first worker's reservation expires and the job is handed to a
+	// second worker.
+	first, _ := driver.Pop(ctx, []string{"default"}, 0)
+	time.Sleep(time.Millisecond)
+	second, _ := driver.Pop(ctx, []string{"default"}, time.Minute)
+	if first == nil || second == nil || second.ID != first.ID {
+		t.Fatalf("expected the job to be handed out again, got %+v and %+v", first, second)
+	}
+
+	if err := driver.Ack(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.Release(ctx, first, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.Fail(ctx, first, errors.New("late")); err != nil {
+		t.Fatal(err)
+	}
+	if len(driver.reserved) != 1 || len(driver.pending) != 0 || len(driver.failed) != 0 {
+		t.Fatalf("expected the late worker to leave the second reservation alone, got %d reserved, %d pending, %d failed",
+			len(driver.reserved), len(driver.pending), len(driver.failed))
+	}
+
+	if err := driver.Ack(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	if len(driver.reserved) != 0 {
+		t.Error("expected the second worker's ack to end its reservation")
+	}
+}
+
+func TestWorker_Timeout(t *testing.T) {
+	ctx := context.Background()
+	q := New(NewMemoryDriver())
+	if _, err := q.Dispatch(ctx, &testJob{Key: "timeout", Sleep: 1000}, Attempts(1), Timeout(10*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	w := newTestWorker(q)
+	w.MaxJobs = 1
+	start := time.Now()
+	if err := w.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("expected the job to be stopped by its timeout")
+	}
+
+	jobs, _ := q.Failed(ctx)
+	if len(jobs) != 1 {
+		t.Fatalf("expected the timed out job to fail, got %d failed jobs", len(jobs))
+	}
+}
+
+// A job that overruns its timeout, noticing the cancelled context only after
+// a while, and records how many copies of it run at once.
+type overrunJob struct {
+	Key string `json:"key"`
+}
+
+var (
+	overrunMu      sync.Mutex
+	overrunRunning int
+	overrunMost    int
+)
+
+func (j *overrunJob) Name() string { return "overrun-job" }
+
+func (j *overrunJob) Handle(ctx context.Context) error {
+	overrunMu.Lock()
+	overrunRunning++
+	overrunMost = max(overrunMost, overrunRunning)
+	overrunMu.Unlock()
+
+	<-ctx.Done()
+	time.Sleep(50 * time.Millisecond)
+
+	overrunMu.Lock()
+	overrunRunning--
+	overrunMu.Unlock()
+	return ctx.Err()
+}
+
+func TestWorker_TimeoutWaitsForHandler(t *testing.T) {
+	RegisterJob(&overrunJob{})
+
+	ctx := context.Background()
+	q := New(NewMemoryDriver())
+	if _, err := q.Dispatch(ctx, &overrunJob{Key: "overrun"}, Attempts(2), Timeout(5*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	w := newTestWorker(q)
+	w.Concurrency = 2
+	w.MaxJobs = 2
+	if err := w.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	overrunMu.Lock()
+	defer overrunMu.Unlock()
+	if overrunMost != 1 {
+		t.Errorf("expected the retry to wait for the timed out attempt, %d ran at once", overrunMost)
+	}
+
+	jobs, _ := q.Failed(ctx)
+	if len(jobs) != 1 || !strings.Contains(jobs[0].Error, "timed out") {
+		t.Errorf("expected the job to fail with a timeout, got %+v", jobs)
+	}
+}
+
+func TestWorker_FinishesJobInFlightOnCancel(t *testing.T) {
+	q := New(NewMemoryDriver())
+	if _, err := q.Dispatch(context.Background(), &testJob{Key: "inflight", Sleep: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := newTestWorker(q)
+	w.Concurrency = 2
+
+	done := make(chan struct{})
+	go func() {
+		_ = w.Run(ctx)
+		close(done)
+	}()
+
+	for runCount("inflight") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if jobs, _ := q.Failed(context.Background()); len(jobs) != 0 {
+		t.Error("expected the job in flight to complete")
+	}
+	if env, _ := q.Driver.Pop(context.Background(), []string{"default"}, 0); env != nil {
+		t.Error("expected the job in flight to be acknowledged")
+	}
+}
+
+func TestWorker_MaxJobs(t *testing.T) {
+	ctx := context.Background()
+	q := New(NewMemoryDriver())
+	for i := 0; i < 5; i++ {
+		if _, err := q.Dispatch(ctx, &testJob{Key: "maxjobs"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := newTestWorker(q)
+	w.Concurrency = 3
+	w.MaxJobs = 4
+	if err := w.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := runCount("maxjobs"); n != 4 {
+		t.Errorf("expected 4 jobs to run, got %d", n)
+	}
+}
+
+func TestWorker_Backoff(t *testing.T) {
+	w := &Worker{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		if got := w.backoff(attempt); got != want {
+			t.Errorf("attempt %d: expected %s, got %s", attempt, want, got)
+		}
+	}
+}
+
+func TestWorker_UnknownJobFails(t *testing.T) {
+	ctx := context.Background()
+	driver := NewMemoryDriver()
+	_ = driver.Push(ctx, &Envelope{ID: "x", Queue: "default", Name: "missing", MaxAttempts: 3})
+
+	w := newTestWorker(New(driver))
+	w.MaxJobs = 1
+	if err := w.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, _ := driver.Failed(ctx)
+	if len(jobs) != 1 || jobs[0].Envelope.ID != "x" {
+		t.Errorf("expected the unknown job to fail, got %+v", jobs)
+	}
+}
@@ -0,0 +1,292 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Move due delayed jobs and expired reservations onto the ready list, then pop
+// the next ready job, record its reservation under a token of its own and
+// count the attempt against its id. Running as one script keeps a job from
+// being handed to two workers, and a worker that dies mid-job from leaving the
+// attempt uncounted.
+//
+// KEYS: ready list, delayed set, reserved set, reservations hash, attempts
+// hash. ARGV: now, reservation expiry, reservation token.
+var popScript = redis.NewScript(5, `
+local due = redis.call('zrangebyscore', KEYS[2], '-inf', ARGV[1])
+if #due > 0 then
+	redis.call('zremrangebyscore', KEYS[2], '-inf', ARGV[1])
+	for i = 1, #due do
+		redis.call('rpush', KEYS[1], due[i])
+	end
+end
+
+local expired = redis.call('zrangebyscore', KEYS[3], '-inf', ARGV[1])
+if #expired > 0 then
+	redis.call('zremrangebyscore', KEYS[3], '-inf', ARGV[1])
+	for i = 1, #expired do
+		local job = redis.call('hget', KEYS[4], expired[i])
+		if job then
+			redis.call('rpush', KEYS[1], job)
+		end
+		redis.call('hdel', KEYS[4], expired[i])
+	end
+end
+
+local job = redis.call('lpop', KEYS[1])
+if not job then
+	return false
+end
+redis.call('zadd', KEYS[3], ARGV[2], ARGV[3])
+redis.call('hset', KEYS[4], ARGV[3], job)
+local attempts = redis.call('hincrby', KEYS[5], cjson.decode(job)['id'], 1)
+return {job, attempts}
+`)
+
+// End a reservation by its token. A reservation that expired and was handed
+// out again belongs to another worker by now and is left alone, as is the
+// job's count of attempts.
+//
+// KEYS: reserved set, reservations hash, attempts hash. ARGV: reservation
+// token, job id.
+var ackScript = redis.NewScript(3, `
+if redis.call('zrem', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('hdel', KEYS[2], ARGV[1])
+redis.call('hdel', KEYS[3], ARGV[2])
+return 1
+`)
+
+// End a reservation by its token and delay the job for another attempt.
+//
+// KEYS: reserved set, reservations hash, delayed set. ARGV: reservation
+// token, available at, job.
+var releaseScript = redis.NewScript(3, `
+if redis.call('zrem', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('hdel', KEYS[2], ARGV[1])
+redis.call('zadd', KEYS[3], ARGV[2], ARGV[3])
+return 1
+`)
+
+// End a reservation by its token and store the job as failed.
+//
+// KEYS: reserved set, reservations hash, attempts hash, failed jobs hash.
+// ARGV: reservation token, job id, failed job.
+var failScript = redis.NewScript(4, `
+if redis.call('zrem', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('hdel', KEYS[2], ARGV[1])
+redis.call('hdel', KEYS[3], ARGV[2])
+redis.call('hset', KEYS[4], ARGV[2], ARGV[3])
+return 1
+`)
+
+// Create a Redis driver whose keys start with prefix.
+func NewRedisDriver(pool *redis.Pool, prefix string) *RedisDriver {
+	return &RedisDriver{
+		Pool:   pool,
+		Prefix: prefix,
+	}
+}
+
+// Push adds a job to its ready list, or to its delayed set when it is not yet available.
+func (d *RedisDriver) Push(ctx context.Context, env *Envelope) error {
+	raw, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+
+	conn, err := d.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if env.AvailableAt.After(time.Now()) {
+		_, err = conn.Do("ZADD", d.key(env.Queue, "delayed"), env.AvailableAt.UnixMilli(), raw)
+		return err
+	}
+
+	_, err = conn.Do("RPUSH", d.key(env.Queue), raw)
+	return err
+}
+
+// Pop reserves the next job from the first queue that has one available.
+func (d *RedisDriver) Pop(ctx context.Context, queues []string, retryAfter time.Duration) (*Envelope, error) {
+	conn, err := d.Pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	for _, name := range queues {
+		now := time.Now()
+		token := newID()
+		reply, err := redis.Values(popScript.Do(conn,
+			d.key(name), d.key(name, "delayed"), d.key(name, "reserved"), d.key(name, "reservations"), d.key(name, "attempts"),
+			now.UnixMilli(), now.Add(retryAfter).UnixMilli(), token,
+		))
+		if errors.Is(err, redis.ErrNil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			raw      string
+			attempts int
+		)
+		if _, err := redis.Scan(reply, &raw, &attempts); err != nil {
+			return nil, err
+		}
+
+		var env Envelope
+		if err := json.Unmarshal([]byte(raw), &env); err != nil {
+			return nil, err
+		}
+		env.Attempts = attempts
+		env.reservation = token
+		return &env, nil
+	}
+
+	return nil, nil
+}
+
+// Ack removes the job's reservation and its count of attempts.
+func (d *RedisDriver) Ack(ctx context.Context, env *Envelope) error {
+	conn, err := d.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = ackScript.Do(conn,
+		d.key(env.Queue, "reserved"), d.key(env.Queue, "reservations"), d.key(env.Queue, "attempts"),
+		env.reservation, env.ID,
+	)
+	return err
+}
+
+// Release removes the job's reservation and adds it to the delayed set, unless
+// the reservation expired and the job was handed out again.
+func (d *RedisDriver) Release(ctx context.Context, env *Envelope, delay time.Duration) error {
+	e := *env
+	e.AvailableAt = time.Now().UTC().Add(delay)
+	raw, err := json.Marshal(&e)
+	if err != nil {
+		return err
+	}
+
+	conn, err := d.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = releaseScript.Do(conn,
+		d.key(env.Queue, "reserved"), d.key(env.Queue, "reservations"), d.key(env.Queue, "delayed"),
+		env.reservation, e.AvailableAt.UnixMilli(), raw,
+	)
+	return err
+}
+
+// Fail removes the job's reservation and count of attempts and stores it in
+// the failed jobs hash, unless the reservation expired and the job was handed
+// out again.
+func (d *RedisDriver) Fail(ctx context.Context, env *Envelope, jobErr error) error {
+	failed := FailedJob{Envelope: *env, Error: errorString(jobErr), FailedAt: time.Now().UTC()}
+	raw, err := json.Marshal(failed)
+	if err != nil {
+		return err
+	}
+
+	conn, err := d.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = failScript.Do(conn,
+		d.key(env.Queue, "reserved"), d.key(env.Queue, "reservations"), d.key(env.Queue, "attempts"), d.failedKey(),
+		env.reservation, env.ID, raw,
+	)
+	return err
+}
+
+// Failed lists the failed jobs in the order they failed.
+func (d *RedisDriver) Failed(ctx context.Context) ([]FailedJob, error) {
+	conn, err := d.Pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	values, err := redis.ByteSlices(conn.Do("HVALS", d.failedKey()))
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]FailedJob, 0, len(values))
+	for _, v := range values {
+		var f FailedJob
+		if err := json.Unmarshal(v, &f); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, f)
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].FailedAt.Before(jobs[j].FailedAt)
+	})
+
+	return jobs, nil
+}
+
+// ForgetFailed removes one failed job.
+func (d *RedisDriver) ForgetFailed(ctx context.Context, id string) (bool, error) {
+	conn, err := d.Pool.GetContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	n, err := redis.Int(conn.Do("HDEL", d.failedKey(), id))
+	return n > 0, err
+}
+
+// FlushFailed removes every failed job.
+func (d *RedisDriver) FlushFailed(ctx context.Context) error {
+	conn, err := d.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Do("DEL", d.failedKey())
+	return err
+}
+
+// The key of a queue's ready list, or of one of its sets when a suffix is given.
+func (d *RedisDriver) key(queue string, suffix ...string) string {
+	key := d.Prefix + ":queues:" + queue
+	for _, s := range suffix {
+		key += ":" + s
+	}
+	return key
+}
+
+// The key of the failed jobs hash, kept outside the queues namespace so it
+// cannot collide with a queue's keys.
+func (d *RedisDriver) failedKey() string {
+	return d.Prefix + ":failed_jobs"
+}
@@ -0,0 +1,177 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+// Start a miniredis server standing in for a local Redis instance and return a
+// pool dialing it.
+func newTestRedisPool(t *testing.T) (*miniredis.Miniredis, *redis.Pool) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+
+	pool := &redis.Pool{
+		MaxIdle: 5,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	t.Cleanup(func() { pool.Close() })
+
+	return mr, pool
+}
+
+func TestRedisDriver_PushPopAck(t *testing.T) {
+	ctx := context.Background()
+	mr, pool := newTestRedisPool(t)
+	q := New(NewRedisDriver(pool, "adele"))
+
+	id, err := q.Dispatch(ctx, &testJob{Key: "redis"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mr.Exists("adele:queues:default") {
+		t.Fatal("expected the job on the ready list")
+	}
+
+	env, err := q.Driver.Pop(ctx, []string{"default"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env == nil || env.ID != id || env.Attempts != 1 {
+		t.Fatalf("expected to reserve job %s for its first attempt, got %+v", id, env)
+	}
+	if members, _ := mr.ZMembers("adele:queues:default:reserved"); len(members) != 1 {
+		t.Errorf("expected one reservation, got %d", len(members))
+	}
+
+	if again, _ := q.Driver.Pop(ctx, []string{"default"}, time.Minute); again != nil {
+		t.Error("expected a reserved job not to be handed out twice")
+	}
+
+	if err := q.Driver.Ack(ctx, env); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("adele:queues:default:reserved") || mr.Exists("adele:queues:default:attempts") {
+		t.Error("expected the reservation and its attempts to be removed")
+	}
+}
+
+func TestRedisDriver_ReleaseAndExpiredReservation(t *testing.T) {
+	ctx := context.Background()
+	_, pool := newTestRedisPool(t)
+	driver := NewRedisDriver(pool, "adele")
+	q := New(driver)
+
+	if _, err := q.Dispatch(ctx, &testJob{Key: "release"}); err != nil {
+		t.Fatal(err)
+	}
+
+	env, _ := driver.Pop(ctx, []string{"default"}, time.Minute)
+	if err := driver.Release(ctx, env, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	env, _ = driver.Pop(ctx, []string{"default"}, 0)
+	if env == nil || env.Attempts != 2 {
+		t.Fatalf("expected the released job for its second attempt, got %+v", env)
+	}
+
+	// A reservation that expires, as when its worker dies, still counts.
+	time.Sleep(2 * time.Millisecond)
+	expired, _ := driver.Pop(ctx, []string{"default"}, time.Minute)
+	if expired == nil || expired.ID != env.ID || expired.Attempts != 3 {
+		t.Errorf("expected an expired reservation to be handed out for a third attempt, got %+v", expired)
+	}
+}
+
+func TestRedisDriver_FailRetryFlush(t *testing.T) {
+	ctx := context.Background()
+	_, pool := newTestRedisPool(t)
+	driver := NewRedisDriver(pool, "adele")
+	q := New(driver)
+
+	for i := 0; i < 2; i++ {
+		if _, err := q.Dispatch(ctx, &testJob{Key: "redis-fail"}); err != nil {
+			t.Fatal(err)
+		}
+		env, _ := driver.Pop(ctx, []string{"default"}, time.Minute)
+		if err := driver.Fail(ctx, env, errors.New("broken")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	jobs, err := q.Failed(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || jobs[0].Error != "broken" {
+		t.Fatalf("expected two failed jobs, got %+v", jobs)
+	}
+
+	if err := q.Retry(ctx, jobs[0].Envelope.ID); err != nil {
+		t.Fatal(err)
+	}
+	if env, _ := driver.Pop(ctx, []string{"default"}, time.Minute); env == nil || env.ID != jobs[0].Envelope.ID {
+		t.Error("expected the retried job back on its queue")
+	}
+
+	if err := q.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if jobs, _ := q.Failed(ctx); len(jobs) != 0 {
+		t.Errorf("expected no failed jobs after flush, got %d", len(jobs))
+	}
+}
+
+func TestRedisDriver_AckByReservation(t *testing.T) {
+	ctx := context.Background()
+	mr, pool := newTestRedisPool(t)
+	driver := NewRedisDriver(pool, "adele")
+	q := New(driver)
+
+	if _, err := q.Dispatch(ctx, &testJob{Key: "redelivered"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first worker's reservation expires and the job, unchanged, is
+	// handed to a second worker.
+	first, _ := driver.Pop(ctx, []string{"default"}, 0)
+	time.Sleep(2 * time.Millisecond)
+	second, _ := driver.Pop(ctx, []string{"default"}, time.Minute)
+	if first == nil || second == nil || second.ID != first.ID {
+		t.Fatalf("expected the job to be handed out again, got %+v and %+v", first, second)
+	}
+
+	if err := driver.Ack(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.Release(ctx, first, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.Fail(ctx, first, errors.New("late")); err != nil {
+		t.Fatal(err)
+	}
+	if members, _ := mr.ZMembers("adele:queues:default:reserved"); len(members) != 1 {
+		t.Errorf("expected the late worker to leave the second reservation alone, got %d", len(members))
+	}
+	if mr.Exists("adele:queues:default:delayed") || mr.Exists("adele:failed_jobs") {
+		t.Error("expected the late worker not to release or fail the job")
+	}
+
+	if err := driver.Ack(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"reserved", "reservations", "attempts"} {
+		if mr.Exists("adele:queues:default:" + key) {
+			t.Errorf("expected the %s key to be removed", key)
+		}
+	}
+}
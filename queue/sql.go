@@ -0,0 +1,184 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/cidekar/adele-framework/database"
+)
+
+// Create a SQL driver for the given pool. The database type is the value of
+// DATABASE_TYPE and is normalized to either "postgres" or "mysql".
+func NewSQLDriver(db *sql.DB, databaseType string) *SQLDriver {
+	return &SQLDriver{
+		DB:      db,
+		Dialect: database.Dialect(databaseType),
+	}
+}
+
+// Push inserts the job into the jobs table.
+func (d *SQLDriver) Push(ctx context.Context, env *Envelope) error {
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+
+	query := database.Rebind(d.Dialect, "INSERT INTO jobs (id, queue, payload, attempts, available_at, created_at) VALUES (?, ?, ?, ?, ?, ?)")
+	_, err = d.DB.ExecContext(ctx, query, env.ID, env.Queue, payload, env.Attempts, env.AvailableAt.UTC(), env.CreatedAt.UTC())
+	return err
+}
+
+// Pop reserves the oldest available job from the first queue that has one. A
+// job is available when it is unreserved and due, or its reservation is older
+// than retryAfter. Rows locked by another worker are skipped. The reservation
+// is recorded under a token of its own and the attempts column is counted up
+// with it.
+func (d *SQLDriver) Pop(ctx context.Context, queues []string, retryAfter time.Duration) (*Envelope, error) {
+	for _, name := range queues {
+		env, err := d.pop(ctx, name, retryAfter)
+		if err != nil || env != nil {
+			return env, err
+		}
+	}
+
+	return nil, nil
+}
+
+func (d *SQLDriver) pop(ctx context.Context, name string, retryAfter time.Duration) (*Envelope, error) {
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	query := database.Rebind(d.Dialect, `SELECT id, payload, attempts FROM jobs
+		WHERE queue = ? AND ((reserved_at IS NULL AND available_at <= ?) OR reserved_at <= ?)
+		ORDER BY available_at, created_at LIMIT 1 FOR UPDATE SKIP LOCKED`)
+
+	var (
+		id       string
+		payload  []byte
+		attempts int
+	)
+	err = tx.QueryRowContext(ctx, query, name, now, now.Add(-retryAfter)).Scan(&id, &payload, &attempts)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	token := newID()
+	if _, err := tx.ExecContext(ctx, database.Rebind(d.Dialect, "UPDATE jobs SET reserved_at = ?, reservation = ?, attempts = attempts + 1 WHERE id = ?"), now, token, id); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(payload, &env); err != nil {
+		return nil, err
+	}
+	env.Attempts = attempts + 1
+	env.reservation = token
+	return &env, nil
+}
+
+// Ack deletes the job, unless its reservation expired and the job was handed
+// out again.
+func (d *SQLDriver) Ack(ctx context.Context, env *Envelope) error {
+	_, err := d.DB.ExecContext(ctx, database.Rebind(d.Dialect, "DELETE FROM jobs WHERE id = ? AND reservation = ?"), env.ID, env.reservation)
+	return err
+}
+
+// Release clears the job's reservation and makes it available after delay,
+// unless the reservation expired and the job was handed out again. The
+// attempts column already counts the attempt.
+func (d *SQLDriver) Release(ctx context.Context, env *Envelope, delay time.Duration) error {
+	e := *env
+	e.AvailableAt = time.Now().UTC().Add(delay)
+	payload, err := json.Marshal(&e)
+	if err != nil {
+		return err
+	}
+
+	query := database.Rebind(d.Dialect, "UPDATE jobs SET payload = ?, reserved_at = NULL, reservation = NULL, available_at = ? WHERE id = ? AND reservation = ?")
+	_, err = d.DB.ExecContext(ctx, query, payload, e.AvailableAt, e.ID, e.reservation)
+	return err
+}
+
+// Fail moves the job from the jobs table to the failed_jobs table, unless its
+// reservation expired and the job was handed out again.
+func (d *SQLDriver) Fail(ctx context.Context, env *Envelope, jobErr error) error {
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, database.Rebind(d.Dialect, "DELETE FROM jobs WHERE id = ? AND reservation = ?"), env.ID, env.reservation)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+
+	query := database.Rebind(d.Dialect, "INSERT INTO failed_jobs (id, queue, payload, exception, failed_at) VALUES (?, ?, ?, ?, ?)")
+	if _, err := tx.ExecContext(ctx, query, env.ID, env.Queue, payload, errorString(jobErr), time.Now().UTC()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Failed lists the failed jobs in the order they failed.
+func (d *SQLDriver) Failed(ctx context.Context) ([]FailedJob, error) {
+	rows, err := d.DB.QueryContext(ctx, "SELECT payload, exception, failed_at FROM failed_jobs ORDER BY failed_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []FailedJob
+	for rows.Next() {
+		var (
+			f       FailedJob
+			payload []byte
+		)
+		if err := rows.Scan(&payload, &f.Error, &f.FailedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(payload, &f.Envelope); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, f)
+	}
+
+	return jobs, rows.Err()
+}
+
+// ForgetFailed deletes one failed job.
+func (d *SQLDriver) ForgetFailed(ctx context.Context, id string) (bool, error) {
+	res, err := d.DB.ExecContext(ctx, database.Rebind(d.Dialect, "DELETE FROM failed_jobs WHERE id = ?"), id)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// FlushFailed deletes every failed job.
+func (d *SQLDriver) FlushFailed(ctx context.Context) error {
+	_, err := d.DB.ExecContext(ctx, "DELETE FROM failed_jobs")
+	return err
+}
@@ -0,0 +1,185 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSQLDriver_Postgres(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	driver := NewSQLDriver(db, "pgx")
+	if driver.Dialect != "postgres" {
+		t.Fatalf("expected postgres dialect, got %s", driver.Dialect)
+	}
+
+	env := &Envelope{ID: "abc", Queue: "default", Name: "test-job", MaxAttempts: 3}
+	payload, _ := json.Marshal(env)
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO jobs (id, queue, payload, attempts, available_at, created_at) VALUES ($1, $2, $3, $4, $5, $6)")).
+		WithArgs("abc", "default", sqlmock.AnyArg(), 0, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, payload, attempts FROM jobs")).
+		WithArgs("default", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "payload", "attempts"}).AddRow("abc", payload, 2))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE jobs SET reserved_at = $1, reservation = $2, attempts = attempts + 1 WHERE id = $3")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "abc").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM jobs WHERE id = $1 AND reservation = $2")).
+		WithArgs("abc", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO failed_jobs (id, queue, payload, exception, failed_at) VALUES ($1, $2, $3, $4, $5)")).
+		WithArgs("abc", "default", sqlmock.AnyArg(), "broken", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := driver.Push(ctx, env); err != nil {
+		t.Fatal(err)
+	}
+
+	reserved, err := driver.Pop(ctx, []string{"default"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reserved == nil || reserved.ID != "abc" || reserved.Attempts != 3 {
+		t.Fatalf("expected to reserve job abc for its third attempt, got %+v", reserved)
+	}
+
+	if err := driver.Fail(ctx, reserved, errors.New("broken")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSQLDriver_MySQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	driver := NewSQLDriver(db, "mariadb")
+	if driver.Dialect != "mysql" {
+		t.Fatalf("expected mysql dialect, got %s", driver.Dialect)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, payload, attempts FROM jobs")).
+		WithArgs("default", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "payload", "attempts"}))
+	mock.ExpectRollback()
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE jobs SET payload = ?, reserved_at = NULL, reservation = NULL, available_at = ? WHERE id = ? AND reservation = ?")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "abc", "token").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM failed_jobs WHERE id = ?")).
+		WithArgs("abc").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	env, err := driver.Pop(ctx, []string{"default"}, time.Minute)
+	if err != nil || env != nil {
+		t.Fatalf("expected an empty queue, got %+v, %v", env, err)
+	}
+
+	if err := driver.Release(ctx, &Envelope{ID: "abc", Attempts: 1, reservation: "token"}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := driver.ForgetFailed(ctx, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Error("expected a missing failed job to be reported")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSQLDriver_AckByReservation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	driver := NewSQLDriver(db, "postgres")
+
+	env := &Envelope{ID: "abc", Queue: "default", Name: "test-job", MaxAttempts: 3}
+	payload, _ := json.Marshal(env)
+
+	// The first worker's reservation expires and the job is handed to a
+	// second worker under a new token.
+	var tokens []string
+	for range 2 {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id, payload, attempts FROM jobs")).
+			WithArgs("default", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "payload", "attempts"}).AddRow("abc", payload, len(tokens)))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE jobs SET reserved_at = $1, reservation = $2")).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "abc").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		reserved, err := driver.Pop(ctx, []string{"default"}, 0)
+		if err != nil || reserved == nil {
+			t.Fatalf("expected to reserve job abc, got %+v, %v", reserved, err)
+		}
+		tokens = append(tokens, reserved.reservation)
+	}
+	if tokens[0] == "" || tokens[0] == tokens[1] {
+		t.Fatalf("expected each reservation to get a token of its own, got %q", tokens)
+	}
+
+	// The late first worker matches no row and leaves the job alone.
+	first := &Envelope{ID: "abc", Queue: "default", reservation: tokens[0]}
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM jobs WHERE id = $1 AND reservation = $2")).
+		WithArgs("abc", tokens[0]).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE jobs SET payload = $1, reserved_at = NULL, reservation = NULL, available_at = $2 WHERE id = $3 AND reservation = $4")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "abc", tokens[0]).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM jobs WHERE id = $1 AND reservation = $2")).
+		WithArgs("abc", tokens[0]).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	if err := driver.Ack(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.Release(ctx, first, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.Fail(ctx, first, errors.New("late")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,165 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/sirupsen/logrus"
+)
+
+// Job is a unit of background work. A job value is encoded as JSON when it is
+// dispatched, so the fields needed to run it must be exported. Job types must be
+// registered with RegisterJob before a worker can decode them.
+type Job interface {
+	Name() string
+	Handle(ctx context.Context) error
+}
+
+// AttemptsJob lets a job override the number of times it is attempted.
+type AttemptsJob interface {
+	Job
+	MaxAttempts() int
+}
+
+// TimeoutJob lets a job override how long a single attempt may run. When the
+// timeout passes the context given to Handle is cancelled, and Handle must
+// return; the worker waits for it before the job is retried or failed.
+type TimeoutJob interface {
+	Job
+	Timeout() time.Duration
+}
+
+// QueuedJob lets a job choose the queue it is dispatched to.
+type QueuedJob interface {
+	Job
+	Queue() string
+}
+
+// FailedHandler lets a job react once it has failed for the last time.
+type FailedHandler interface {
+	Job
+	Failed(ctx context.Context, err error)
+}
+
+// Envelope is a dispatched job as stored by a driver.
+type Envelope struct {
+	ID          string          `json:"id"`
+	Queue       string          `json:"queue"`
+	Name        string          `json:"name"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	Timeout     time.Duration   `json:"timeout"`
+	AvailableAt time.Time       `json:"available_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+
+	// The token of the reservation a driver handed the envelope out under,
+	// which ends that reservation and no later one of the same job.
+	reservation string
+}
+
+// FailedJob is a job that exhausted its attempts.
+type FailedJob struct {
+	Envelope Envelope  `json:"envelope"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// Driver stores pending, reserved and failed jobs.
+type Driver interface {
+	// Push adds a job, delayed until its AvailableAt time.
+	Push(ctx context.Context, env *Envelope) error
+	// Pop reserves the next available job from the first non-empty queue, or
+	// returns nil when every queue is empty. A reservation that is neither
+	// acknowledged nor released within retryAfter is handed out again. Every
+	// reservation counts as an attempt, stored with the job as it is reserved
+	// so a worker that dies mid-job still uses one up.
+	Pop(ctx context.Context, queues []string, retryAfter time.Duration) (*Envelope, error)
+	// Ack removes a reserved job once it has been handled.
+	Ack(ctx context.Context, env *Envelope) error
+	// Release returns a reserved job to its queue to be attempted again after delay.
+	Release(ctx context.Context, env *Envelope, delay time.Duration) error
+	// Fail moves a reserved job into the failed jobs store.
+	Fail(ctx context.Context, env *Envelope, jobErr error) error
+	// Failed lists the failed jobs, oldest first.
+	Failed(ctx context.Context) ([]FailedJob, error)
+	// ForgetFailed removes one failed job, reporting whether it existed.
+	ForgetFailed(ctx context.Context, id string) (bool, error)
+	// FlushFailed removes every failed job.
+	FlushFailed(ctx context.Context) error
+}
+
+// Queue dispatches jobs to a driver and retries or flushes failed jobs.
+type Queue struct {
+	Driver       Driver
+	DefaultQueue string
+	MaxAttempts  int
+	Timeout      time.Duration
+}
+
+// Worker reserves jobs from one or more queues and runs them.
+type Worker struct {
+	Queue       *Queue
+	Queues      []string
+	Concurrency int
+	// Stop after this many jobs (0 for no limit) so a process manager can
+	// recycle the worker.
+	MaxJobs int
+	// Stop after running for this long (0 for no limit).
+	MaxTime time.Duration
+	// How long to wait before polling again when every queue is empty.
+	Sleep time.Duration
+	// How long a reservation is held before the job is handed out again. It
+	// should be longer than the longest job timeout, or a job still running
+	// is handed to another worker.
+	RetryAfter time.Duration
+	// The delay before the first retry; each further retry doubles it.
+	Backoff time.Duration
+	// The longest delay between retries.
+	MaxBackoff time.Duration
+	Log        *logrus.Logger
+
+	processed int
+	mu        sync.Mutex
+}
+
+// MemoryDriver keeps jobs in process memory. Jobs are lost when the process
+// exits, so it suits tests and local development.
+type MemoryDriver struct {
+	mu       sync.Mutex
+	pending  []*Envelope
+	reserved map[string]memoryReservation
+	failed   []FailedJob
+}
+
+type memoryReservation struct {
+	env     *Envelope
+	token   string
+	expires time.Time
+}
+
+// RedisDriver stores jobs in Redis lists and sorted sets.
+type RedisDriver struct {
+	Pool   *redis.Pool
+	Prefix string
+}
+
+// SQLDriver stores jobs in the jobs and failed_jobs tables.
+// Workers reserve jobs with SELECT ... FOR UPDATE SKIP LOCKED, which needs
+// PostgreSQL 9.5, MySQL 8.0 or MariaDB 10.6 or later.
+type SQLDriver struct {
+	DB      *sql.DB
+	Dialect string
+}
+
+// ErrUnknownJob is returned when a worker reserves a job whose name has not been
+// registered with RegisterJob.
+var ErrUnknownJob = errors.New("queue: job type is not registered")
+
+// ErrFailedJobNotFound is returned when retrying a failed job id that does not exist.
+var ErrFailedJobNotFound = errors.New("queue: failed job not found")
@@ -0,0 +1,213 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Create a worker for the named queues, or the queue's default queue when none
+// are given, with a single goroutine and the default polling and backoff settings.
+func (q *Queue) NewWorker(queues ...string) *Worker {
+	if len(queues) == 0 {
+		queues = []string{q.DefaultQueue}
+	}
+	return &Worker{
+		Queue:       q,
+		Queues:      queues,
+		Concurrency: 1,
+		Sleep:       3 * time.Second,
+		RetryAfter:  90 * time.Second,
+		Backoff:     5 * time.Second,
+		MaxBackoff:  time.Hour,
+	}
+}
+
+// Run reserves and handles jobs until ctx is cancelled or the MaxJobs or MaxTime
+// limit is reached. Cancelling ctx stops the worker from reserving new jobs; jobs
+// already running are allowed to finish, bounded only by their own timeout, so
+// a SIGTERM never abandons the job in flight.
+func (w *Worker) Run(ctx context.Context) error {
+	if w.Queue == nil || w.Queue.Driver == nil {
+		return errors.New("queue: worker has no driver")
+	}
+
+	concurrency := w.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	if w.MaxTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.MaxTime)
+		defer cancel()
+	}
+
+	// Stop every loop once MaxJobs is reached.
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx, stop)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// The number of jobs this worker has handled.
+func (w *Worker) Processed() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.processed
+}
+
+// Reserve and handle jobs one at a time until ctx is done.
+func (w *Worker) loop(ctx context.Context, stop context.CancelFunc) {
+	for ctx.Err() == nil {
+		if !w.reserveSlot() {
+			stop()
+			return
+		}
+
+		env, err := w.Queue.Driver.Pop(ctx, w.Queues, w.RetryAfter)
+		if err != nil && ctx.Err() == nil {
+			w.log().Errorf("queue: reserve job: %v", err)
+		}
+		if env == nil {
+			w.releaseSlot()
+			sleep(ctx, w.Sleep)
+			continue
+		}
+
+		// The job runs detached from ctx so a shutdown lets it finish.
+		w.process(context.WithoutCancel(ctx), env)
+	}
+}
+
+// Claim one of the MaxJobs slots, reporting false once they are used up.
+func (w *Worker) reserveSlot() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.MaxJobs > 0 && w.processed >= w.MaxJobs {
+		return false
+	}
+	w.processed++
+	return true
+}
+
+func (w *Worker) releaseSlot() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.processed--
+}
+
+// Run one reserved job and acknowledge, release or fail it.
+func (w *Worker) process(ctx context.Context, env *Envelope) {
+	log := w.log().WithFields(logrus.Fields{"job": env.Name, "id": env.ID, "queue": env.Queue, "attempt": env.Attempts})
+
+	job, err := decode(env)
+	if err != nil {
+		log.Errorf("queue: decode job: %v", err)
+		w.fail(ctx, env, nil, err)
+		return
+	}
+
+	err = w.handle(ctx, job, env.Timeout)
+	if err == nil {
+		if err := w.Queue.Driver.Ack(ctx, env); err != nil {
+			log.Errorf("queue: acknowledge job: %v", err)
+		}
+		return
+	}
+
+	if env.Attempts >= env.MaxAttempts {
+		log.Errorf("queue: job failed: %v", err)
+		w.fail(ctx, env, job, err)
+		return
+	}
+
+	delay := w.backoff(env.Attempts)
+	log.Warnf("queue: job attempt failed, retrying in %s: %v", delay, err)
+	if err := w.Queue.Driver.Release(ctx, env, delay); err != nil {
+		log.Errorf("queue: release job: %v", err)
+	}
+}
+
+// Run the job's handler with the attempt timeout, converting a panic into an
+// error. A timeout cancels ctx, and the handler must return once it is done;
+// until it has, the job keeps its reservation so a retry never runs alongside
+// the attempt that timed out.
+func (w *Worker) handle(ctx context.Context, job Job, timeout time.Duration) (err error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			err = fmt.Errorf("job panicked: %v", rvr)
+		}
+	}()
+
+	err = job.Handle(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("job timed out after %s: %w", timeout, err)
+	}
+	return err
+}
+
+// Move a job to the failed jobs store and notify it.
+func (w *Worker) fail(ctx context.Context, env *Envelope, job Job, jobErr error) {
+	if err := w.Queue.Driver.Fail(ctx, env, jobErr); err != nil {
+		w.log().Errorf("queue: store failed job %s: %v", env.ID, err)
+	}
+	if h, ok := job.(FailedHandler); ok {
+		h.Failed(ctx, jobErr)
+	}
+}
+
+// The delay before the next attempt, doubling with every attempt up to MaxBackoff.
+func (w *Worker) backoff(attempt int) time.Duration {
+	delay := w.Backoff
+	for i := 1; i < attempt && delay > 0; i++ {
+		delay *= 2
+		if w.MaxBackoff > 0 && delay >= w.MaxBackoff {
+			return w.MaxBackoff
+		}
+	}
+	if w.MaxBackoff > 0 && delay > w.MaxBackoff {
+		return w.MaxBackoff
+	}
+	return delay
+}
+
+func (w *Worker) log() *logrus.Logger {
+	if w.Log == nil {
+		return logrus.StandardLogger()
+	}
+	return w.Log
+}
+
+// Wait for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
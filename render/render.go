@@ -1,8 +1,8 @@
 // Package render renders HTML pages using the configured templating engine.
 //
 // It supports Go's html/template, Jet templates, and Inertia, injecting default
-// template data such as the CSRF token, authentication state, and flash/error
-// messages drawn from the session.
+// template data such as the CSRF token, authentication and impersonation state,
+// and flash/error messages drawn from the session.
 package render
 
 import (
@@ -27,6 +27,11 @@ func (a *Render) defaultData(td *TemplateData, r *http.Request) *TemplateData {
 		td.IsAuthenticated = true
 	}
 
+	// Set by auth.Impersonate, e.g. to show a banner with a way back.
+	if a.Session.Exists(r.Context(), "impersonatorID") {
+		td.IsImpersonating = true
+	}
+
 	td.Error = a.Session.PopString(r.Context(), "error")
 	td.Flash = a.Session.PopString(r.Context(), "flash")
 	return td
@@ -53,8 +58,9 @@ func (a *Render) InertiaPage(w http.ResponseWriter, r *http.Request, template st
 	flash := a.Session.Pop(r.Context(), "flash")
 
 	err := a.InertiaManager.Render(w, r, template, map[string]interface{}{
-		"flash": flash,
-		"csrf":  csrfToken,
+		"flash":         flash,
+		"csrf":          csrfToken,
+		"impersonating": a.Session.Exists(r.Context(), "impersonatorID"),
 	})
 	if err != nil {
 		log.Printf("%s", err)
@@ -90,11 +96,16 @@ func (a *Render) JetPage(w http.ResponseWriter, r *http.Request, templateName st
 	var vars jet.VarMap
 
 	// Convert the vars and data into the right format
-	if variables == nil {
-		vars = make(jet.VarMap)
-	} else {
+	if variables != nil {
 		vars = variables.(jet.VarMap) // cast it
 	}
+	if vars == nil {
+		vars = make(jet.VarMap)
+	}
+
+	// Template functions that depend on the current user, such as can and
+	// cannot, read the request from here.
+	vars.Set("request", r)
 
 	td := &TemplateData{}
 	if data != nil {
@@ -107,3 +107,25 @@ func TestRender_JetPage(t *testing.T) {
 		t.Fatalf("%s", body)
 	}
 }
+
+func TestRender_DefaultData_Impersonating(t *testing.T) {
+
+	r := mux.NewRouter()
+
+	r.Use(testRenderer.Session.LoadAndSave)
+
+	var impersonating []bool
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		impersonating = append(impersonating, testRenderer.defaultData(&TemplateData{}, r).IsImpersonating)
+		testRenderer.Session.Put(r.Context(), "impersonatorID", 1)
+		impersonating = append(impersonating, testRenderer.defaultData(&TemplateData{}, r).IsImpersonating)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	makeRequest(t, ts, "GET", "/", nil)
+	if len(impersonating) != 2 || impersonating[0] || !impersonating[1] {
+		t.Errorf("expected the flag to follow the impersonatorID session key, got %v", impersonating)
+	}
+}
@@ -20,6 +20,7 @@ type Render struct {
 
 type TemplateData struct {
 	IsAuthenticated bool
+	IsImpersonating bool
 	IntMap          map[string]int
 	StringMap       map[string]string
 	FloatMap        map[string]float32
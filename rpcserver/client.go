@@ -3,8 +3,6 @@ package rpcserver
 import (
 	"errors"
 	"net/rpc"
-
-	"github.com/cidekar/adele-framework"
 )
 
 type MaintenanceModeArgs struct {
@@ -20,12 +18,16 @@ type RPCClient struct {
 }
 
 // Creates a new RPC connection to a server and wraps the connection in
-// a custom RPCClient struct. The client is returned ready to use, or
-// an error if connection fails.
+// a custom RPCClient struct. The server address is read from the RPC
+// settings of the .env file in the working directory and the environment.
+// The client is returned ready to use, or an error if connection fails.
 func NewRPCClient() (*RPCClient, error) {
-	ServerAddr := adele.Helpers.Getenv("RPC_SERVER_ADDR", ServerAddrDefault)
-	ServerPort := adele.Helpers.Getenv("RPC_SERVER_PORT", ServerPortDefault)
-	client, err := rpc.Dial("tcp", ServerAddr+":"+ServerPort)
+	c, err := rpcConfig(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := rpc.Dial("tcp", c.Addr+":"+c.Port)
 	if err != nil {
 		return nil, err
 	}
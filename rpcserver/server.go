@@ -2,7 +2,7 @@
 // running Adele application out of band, such as toggling maintenance mode.
 //
 // The server listens on a configurable TCP address and can be disabled via the
-// RPC_SERVER_DISABLE environment variable.
+// RPC_SERVER_DISABLE setting.
 package rpcserver
 
 import (
@@ -11,6 +11,7 @@ import (
 	"net/rpc"
 
 	"github.com/cidekar/adele-framework"
+	"github.com/cidekar/adele-framework/config"
 )
 
 const (
@@ -35,7 +36,11 @@ func (r *RPCServer) SetMaintenanceMode(args *MaintenanceModeArgs, reply *Mainten
 
 func Start(app *adele.Adele) error {
 
-	if adele.Helpers.Getenv("RPC_SERVER_DISABLE") != "" {
+	c, err := rpcConfig(app)
+	if err != nil {
+		return err
+	}
+	if c.Disable {
 		return nil
 	}
 
@@ -43,15 +48,12 @@ func Start(app *adele.Adele) error {
 		App: app,
 	}
 
-	err := rpc.Register(rs)
+	err = rpc.Register(rs)
 	if err != nil {
 		return fmt.Errorf("failed to publish the reciever: %s", err)
 	}
 
-	ServerAddr := adele.Helpers.Getenv("RPC_SERVER_ADDR", ServerAddrDefault)
-	ServerPort := adele.Helpers.Getenv("RPC_SERVER_PORT", ServerPortDefault)
-
-	listener, err := net.Listen("tcp", ServerAddr+":"+ServerPort)
+	listener, err := net.Listen("tcp", c.Addr+":"+c.Port)
 	if err != nil {
 		return fmt.Errorf("failed to announce on the local network address: %s", err)
 	}
@@ -79,7 +81,11 @@ func Start(app *adele.Adele) error {
 }
 
 func Stop(app *adele.Adele) error {
-	if adele.Helpers.Getenv("RPC_SERVER_DISABLE") != "" {
+	c, err := rpcConfig(app)
+	if err != nil {
+		return err
+	}
+	if c.Disable {
 		return nil
 	}
 
@@ -91,7 +97,7 @@ func Stop(app *adele.Adele) error {
 		return nil
 	}
 
-	err := (*app.RPCListener).Close()
+	err = (*app.RPCListener).Close()
 	if err != nil {
 		return fmt.Errorf("failed to close RPC listener: %w", err)
 	}
@@ -101,3 +107,23 @@ func Stop(app *adele.Adele) error {
 	return nil
 
 }
+
+// Read the RPC settings from the application configuration, loading them from
+// the .env file and the environment when the application was not bootstrapped
+// with a configuration.
+func rpcConfig(app *adele.Adele) (config.RPC, error) {
+	if app != nil && app.Config != nil {
+		return app.Config.RPC, nil
+	}
+
+	rootPath := "."
+	if app != nil && app.RootPath != "" {
+		rootPath = app.RootPath
+	}
+
+	var c config.RPC
+	if err := config.Load(rootPath, &c); err != nil {
+		return c, fmt.Errorf("load rpc configuration: %w", err)
+	}
+	return c, nil
+}
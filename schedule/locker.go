@@ -0,0 +1,151 @@
+package schedule
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/cidekar/adele-framework/database"
+	"github.com/gomodule/redigo/redis"
+)
+
+// Create an empty in-memory locker.
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{locks: make(map[string]memoryLock)}
+}
+
+// Acquire takes the lock unless it is held and unexpired.
+func (l *MemoryLocker) Acquire(ctx context.Context, name string, ttl time.Duration) (string, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if lock, held := l.locks[name]; held && time.Now().Before(lock.expires) {
+		return "", false, nil
+	}
+
+	token := newToken()
+	l.locks[name] = memoryLock{token: token, expires: time.Now().Add(ttl)}
+	return token, true, nil
+}
+
+// Release frees the lock if the token still owns it.
+func (l *MemoryLocker) Release(ctx context.Context, name, token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if lock, held := l.locks[name]; held && lock.token == token {
+		delete(l.locks, name)
+	}
+	return nil
+}
+
+// Delete the key only when it still holds the owner's token.
+var releaseScript = redis.NewScript(1, `
+if redis.call('get', KEYS[1]) == ARGV[1] then
+	return redis.call('del', KEYS[1])
+end
+return 0
+`)
+
+// Create a Redis locker whose keys start with prefix.
+func NewRedisLocker(pool *redis.Pool, prefix string) *RedisLocker {
+	return &RedisLocker{
+		Pool:   pool,
+		Prefix: prefix,
+	}
+}
+
+// Acquire sets the lock key if it does not exist, expiring it after ttl.
+func (l *RedisLocker) Acquire(ctx context.Context, name string, ttl time.Duration) (string, bool, error) {
+	conn, err := l.Pool.GetContext(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	defer conn.Close()
+
+	token := newToken()
+	_, err = redis.String(conn.Do("SET", l.key(name), token, "NX", "PX", ttl.Milliseconds()))
+	if errors.Is(err, redis.ErrNil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return token, true, nil
+}
+
+// Release deletes the lock key if the token still owns it.
+func (l *RedisLocker) Release(ctx context.Context, name, token string) error {
+	conn, err := l.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = releaseScript.Do(conn, l.key(name), token)
+	return err
+}
+
+func (l *RedisLocker) key(name string) string {
+	return l.Prefix + ":" + name
+}
+
+// Create a SQL locker for the given pool. The database type is the value of
+// DATABASE_TYPE and is normalized to either "postgres" or "mysql".
+func NewSQLLocker(db *sql.DB, databaseType string) *SQLLocker {
+	return &SQLLocker{
+		DB:      db,
+		Dialect: database.Dialect(databaseType),
+	}
+}
+
+// Acquire inserts the lock row, or takes over a row whose lock has expired.
+func (l *SQLLocker) Acquire(ctx context.Context, name string, ttl time.Duration) (string, bool, error) {
+	token := newToken()
+	now := time.Now().UTC()
+
+	var (
+		res sql.Result
+		err error
+	)
+	switch l.Dialect {
+	case "mysql":
+		// MySQL reports 1 affected row for an insert, 2 for an update that
+		// changed the row and 0 when the unexpired lock was left alone.
+		res, err = l.DB.ExecContext(ctx, `INSERT INTO schedule_locks (name, owner, expires_at) VALUES (?, ?, ?)
+			ON DUPLICATE KEY UPDATE owner = IF(expires_at < ?, VALUES(owner), owner), expires_at = IF(expires_at < ?, VALUES(expires_at), expires_at)`,
+			name, token, now.Add(ttl), now, now)
+	default:
+		res, err = l.DB.ExecContext(ctx, `INSERT INTO schedule_locks (name, owner, expires_at) VALUES ($1, $2, $3)
+			ON CONFLICT (name) DO UPDATE SET owner = EXCLUDED.owner, expires_at = EXCLUDED.expires_at WHERE schedule_locks.expires_at < $4`,
+			name, token, now.Add(ttl), now)
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return "", false, err
+	}
+	if n == 0 {
+		return "", false, nil
+	}
+	return token, true, nil
+}
+
+// Release deletes the lock row if the token still owns it.
+func (l *SQLLocker) Release(ctx context.Context, name, token string) error {
+	_, err := l.DB.ExecContext(ctx, database.Rebind(l.Dialect, "DELETE FROM schedule_locks WHERE name = ? AND owner = ?"), name, token)
+	return err
+}
+
+// Generate a random lock owner token.
+func newToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,140 @@
+package schedule
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestMemoryLocker(t *testing.T) {
+	ctx := context.Background()
+	l := NewMemoryLocker()
+
+	token, ok, _ := l.Acquire(ctx, "job", time.Minute)
+	if !ok {
+		t.Fatal("expected to acquire a free lock")
+	}
+	if _, ok, _ := l.Acquire(ctx, "job", time.Minute); ok {
+		t.Error("expected a held lock to be refused")
+	}
+
+	_ = l.Release(ctx, "job", "someone-else")
+	if _, ok, _ := l.Acquire(ctx, "job", time.Minute); ok {
+		t.Error("expected a release by another owner to be ignored")
+	}
+
+	_ = l.Release(ctx, "job", token)
+	if _, ok, _ := l.Acquire(ctx, "job", -time.Second); !ok {
+		t.Error("expected a released lock to be free")
+	}
+	if _, ok, _ := l.Acquire(ctx, "job", time.Minute); !ok {
+		t.Error("expected an expired lock to be free")
+	}
+}
+
+func TestRedisLocker(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	t.Cleanup(func() { pool.Close() })
+
+	l := NewRedisLocker(pool, "adele")
+
+	token, ok, err := l.Acquire(ctx, "job", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("expected to acquire a free lock, got ok=%v err=%v", ok, err)
+	}
+	if !mr.Exists("adele:job") {
+		t.Error("expected the lock key to be written with the prefix")
+	}
+	if _, ok, _ := l.Acquire(ctx, "job", time.Minute); ok {
+		t.Error("expected a held lock to be refused")
+	}
+
+	if err := l.Release(ctx, "job", "someone-else"); err != nil {
+		t.Fatal(err)
+	}
+	if !mr.Exists("adele:job") {
+		t.Error("expected a release by another owner to be ignored")
+	}
+
+	if err := l.Release(ctx, "job", token); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("adele:job") {
+		t.Error("expected the lock key to be deleted")
+	}
+
+	_, _, _ = l.Acquire(ctx, "job", time.Second)
+	mr.FastForward(2 * time.Second)
+	if _, ok, _ := l.Acquire(ctx, "job", time.Minute); !ok {
+		t.Error("expected an expired lock to be free")
+	}
+}
+
+func TestSQLLocker_Postgres(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	l := NewSQLLocker(db, "postgres")
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schedule_locks (name, owner, expires_at) VALUES ($1, $2, $3)")).
+		WithArgs("job", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schedule_locks")).
+		WithArgs("job", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schedule_locks WHERE name = $1 AND owner = $2")).
+		WithArgs("job", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	token, ok, err := l.Acquire(ctx, "job", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("expected to acquire the lock, got ok=%v err=%v", ok, err)
+	}
+	if _, ok, _ := l.Acquire(ctx, "job", time.Minute); ok {
+		t.Error("expected a held lock to be refused")
+	}
+	if err := l.Release(ctx, "job", token); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSQLLocker_MySQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	l := NewSQLLocker(db, "mariadb")
+
+	mock.ExpectExec(regexp.QuoteMeta("ON DUPLICATE KEY UPDATE owner = IF(expires_at < ?, VALUES(owner), owner)")).
+		WithArgs("job", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	if _, ok, err := l.Acquire(context.Background(), "job", time.Minute); err != nil || !ok {
+		t.Errorf("expected an expired lock to be taken over, got ok=%v err=%v", ok, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
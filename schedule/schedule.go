@@ -0,0 +1,361 @@
+// Package schedule registers named, recurring tasks on a cron scheduler.
+//
+// Tasks may be protected against overlapping runs, restricted to a single server
+// when several replicas share a Locker, bounded by a timeout and wrapped with
+// before and after hooks. Every run's start time, duration and result is
+// recorded and can be shared with other processes through the cache.
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// Create a schedule that adds its tasks to the given cron scheduler. Locks are
+// held in memory until a shared Locker is configured.
+func New(c *cron.Cron) *Schedule {
+	return &Schedule{
+		Cron:   c,
+		Locker: NewMemoryLocker(),
+	}
+}
+
+// Prevent a task from starting while its previous run is still going. With a
+// shared Locker the check also covers runs on other servers; the lock expires
+// after ttl in case the process holding it dies (24 hours when ttl is zero).
+func WithoutOverlapping(ttl time.Duration) TaskOption {
+	return func(t *Task) {
+		t.withoutOverlapping = true
+		t.overlapTTL = ttl
+	}
+}
+
+// Run each occurrence of the task on only one of the servers sharing the
+// schedule's Locker. Occurrences are identified by the minute they start in, so
+// the first server to claim a minute runs the task and the others skip it.
+func OnOneServer() TaskOption {
+	return func(t *Task) {
+		t.onOneServer = true
+	}
+}
+
+// Cancel the task's context when a run takes longer than d.
+func Timeout(d time.Duration) TaskOption {
+	return func(t *Task) {
+		t.timeout = d
+	}
+}
+
+// Call fn before every run of the task that is not skipped.
+func Before(fn func(ctx context.Context)) TaskOption {
+	return func(t *Task) {
+		t.before = append(t.before, fn)
+	}
+}
+
+// Call fn with the result after every run of the task that is not skipped.
+func After(fn func(ctx context.Context, run Run)) TaskOption {
+	return func(t *Task) {
+		t.after = append(t.after, fn)
+	}
+}
+
+// Add a named task that runs fn on the cron spec, for example "@every 5m" or
+// "0 3 * * *".
+func (s *Schedule) Add(name, spec string, fn TaskFunc, opts ...TaskOption) (*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tasks {
+		if t.Name == name {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateTask, name)
+		}
+	}
+
+	t := &Task{
+		Name:     name,
+		Spec:     spec,
+		fn:       fn,
+		schedule: s,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	id, err := s.Cron.AddFunc(spec, func() {
+		t.Run(context.Background())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("schedule: task %s: %w", name, err)
+	}
+	t.entryID = id
+
+	s.tasks = append(s.tasks, t)
+	return t, nil
+}
+
+// List the tasks in the order they were added.
+func (s *Schedule) Tasks() []*Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := make([]*Task, len(s.tasks))
+	copy(tasks, s.tasks)
+	return tasks
+}
+
+// Find a task by name.
+func (s *Schedule) Task(name string) (*Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.tasks {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, name)
+}
+
+// The next time the task is due after now.
+func (t *Task) Next() time.Time {
+	entry := t.schedule.Cron.Entry(t.entryID)
+	if entry.Schedule == nil {
+		return time.Time{}
+	}
+	return entry.Schedule.Next(time.Now())
+}
+
+// The options set on the task, for display.
+func (t *Task) Options() []string {
+	var opts []string
+	if t.withoutOverlapping {
+		opts = append(opts, "without-overlapping")
+	}
+	if t.onOneServer {
+		opts = append(opts, "on-one-server")
+	}
+	if t.timeout > 0 {
+		opts = append(opts, "timeout="+t.timeout.String())
+	}
+	return opts
+}
+
+// The task's most recent run, read from the schedule's cache when it has not run
+// in this process. The bool is false when no run has been recorded.
+func (t *Task) LastRun() (Run, bool) {
+	t.mu.Lock()
+	last := t.last
+	t.mu.Unlock()
+
+	if last != nil {
+		return *last, true
+	}
+
+	c := t.schedule.Cache
+	if c == nil {
+		return Run{}, false
+	}
+
+	v, err := c.Get(t.cacheKey())
+	if err != nil || v == nil {
+		return Run{}, false
+	}
+	s, ok := v.(string)
+	if !ok {
+		return Run{}, false
+	}
+
+	var run Run
+	if err := json.Unmarshal([]byte(s), &run); err != nil {
+		return Run{}, false
+	}
+	return run, true
+}
+
+// Run the task now, applying its overlap, single server and timeout options, and
+// record the result.
+func (t *Task) Run(ctx context.Context) Run {
+	start := time.Now()
+
+	var release func()
+	if t.withoutOverlapping {
+		unlock, ok, err := t.preventOverlap(ctx)
+		if err != nil {
+			return t.record(ctx, Run{StartedAt: start, Status: StatusFailed, Error: err.Error()})
+		}
+		if !ok {
+			return t.skip("previous run is still in progress")
+		}
+		release = unlock
+		defer func() {
+			if release != nil {
+				release()
+			}
+		}()
+	}
+
+	if t.onOneServer {
+		key := "schedule:" + t.Name + ":" + strconv.FormatInt(start.Truncate(time.Minute).Unix(), 10)
+		_, ok, err := t.schedule.locker().Acquire(ctx, key, time.Hour)
+		if err != nil {
+			return t.record(ctx, Run{StartedAt: start, Status: StatusFailed, Error: err.Error()})
+		}
+		if !ok {
+			return t.skip("already run on another server")
+		}
+	}
+
+	for _, fn := range t.before {
+		fn(ctx)
+	}
+
+	// The overlap lock is released once the function returns rather than
+	// when Run does, so a run outliving its timeout still keeps the next
+	// tick from starting a second instance alongside it.
+	finished := release
+	release = nil
+	err := t.call(ctx, finished)
+
+	run := Run{StartedAt: start, Duration: time.Since(start), Status: StatusSuccess}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		run.Status = StatusTimeout
+		run.Error = fmt.Sprintf("timed out after %s", t.timeout)
+	case err != nil:
+		run.Status = StatusFailed
+		run.Error = err.Error()
+	}
+
+	run = t.record(ctx, run)
+
+	for _, fn := range t.after {
+		fn(ctx, run)
+	}
+
+	return run
+}
+
+// Mark the task as running, here and through the locker, returning a func that
+// clears the mark. The bool is false when a run is already in progress.
+func (t *Task) preventOverlap(ctx context.Context) (func(), bool, error) {
+	t.mu.Lock()
+	if t.running {
+		t.mu.Unlock()
+		return nil, false, nil
+	}
+	t.running = true
+	t.mu.Unlock()
+
+	done := func() {
+		t.mu.Lock()
+		t.running = false
+		t.mu.Unlock()
+	}
+
+	ttl := t.overlapTTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+
+	key := "schedule:" + t.Name + ":running"
+	locker := t.schedule.locker()
+	token, ok, err := locker.Acquire(ctx, key, ttl)
+	if err != nil || !ok {
+		done()
+		return nil, false, err
+	}
+
+	return func() {
+		if err := locker.Release(context.Background(), key, token); err != nil {
+			t.schedule.log().Errorf("schedule: release lock for %s: %v", t.Name, err)
+		}
+		done()
+	}, true, nil
+}
+
+// Call the task's function with its timeout, converting a panic into an error.
+// A timeout returns without waiting for the function, and finished, when
+// given, runs once the function has returned.
+func (t *Task) call(ctx context.Context, finished func()) error {
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		if finished != nil {
+			defer finished()
+		}
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				done <- fmt.Errorf("task panicked: %v", rvr)
+			}
+		}()
+		done <- t.fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Record a skipped run without replacing the last completed run.
+func (t *Task) skip(reason string) Run {
+	t.schedule.log().WithField("task", t.Name).Debugf("schedule: skipped: %s", reason)
+	return Run{StartedAt: time.Now(), Status: StatusSkipped, Error: reason}
+}
+
+// Keep the run as the task's last run, share it through the cache and log it.
+func (t *Task) record(ctx context.Context, run Run) Run {
+	t.mu.Lock()
+	t.last = &run
+	t.mu.Unlock()
+
+	if c := t.schedule.Cache; c != nil {
+		if b, err := json.Marshal(run); err == nil {
+			if err := c.Set(t.cacheKey(), string(b)); err != nil {
+				t.schedule.log().Errorf("schedule: record run of %s: %v", t.Name, err)
+			}
+		}
+	}
+
+	log := t.schedule.log().WithFields(logrus.Fields{"task": t.Name, "duration": run.Duration.String()})
+	if run.Status == StatusSuccess {
+		log.Info("schedule: task finished")
+	} else {
+		log.Errorf("schedule: task %s: %s", run.Status, run.Error)
+	}
+
+	return run
+}
+
+func (t *Task) cacheKey() string {
+	return "schedule:last-run:" + t.Name
+}
+
+func (s *Schedule) locker() Locker {
+	if s.Locker == nil {
+		return NewMemoryLocker()
+	}
+	return s.Locker
+}
+
+func (s *Schedule) log() *logrus.Logger {
+	if s.Log == nil {
+		return logrus.StandardLogger()
+	}
+	return s.Log
+}
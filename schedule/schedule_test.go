@@ -0,0 +1,200 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// A minimal cache.Cache keeping values in a map.
+type mapCache struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+func (c *mapCache) Has(key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.values[key]
+	return ok, nil
+}
+
+func (c *mapCache) Get(key string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key], nil
+}
+
+func (c *mapCache) Set(key string, value interface{}, expires ...int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+	return nil
+}
+
+func (c *mapCache) Forget(key string) error           { return nil }
+func (c *mapCache) EmptyByMatch(pattern string) error { return nil }
+func (c *mapCache) Empty() error                      { return nil }
+
+func TestAdd_DuplicateAndInvalidSpec(t *testing.T) {
+	s := New(cron.New())
+	noop := func(ctx context.Context) error { return nil }
+
+	if _, err := s.Add("prune", "@every 5m", noop); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add("prune", "@every 5m", noop); !errors.Is(err, ErrDuplicateTask) {
+		t.Errorf("expected ErrDuplicateTask, got %v", err)
+	}
+	if _, err := s.Add("broken", "not a spec", noop); err == nil {
+		t.Error("expected an invalid spec to be refused")
+	}
+
+	task, err := s.Task("prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := task.Next(); next.Before(time.Now()) || next.After(time.Now().Add(5*time.Minute+time.Second)) {
+		t.Errorf("unexpected next run %s", next)
+	}
+	if len(s.Tasks()) != 1 {
+		t.Errorf("expected one task, got %d", len(s.Tasks()))
+	}
+}
+
+func TestRun_RecordsResultAndHooks(t *testing.T) {
+	s := New(cron.New())
+	s.Cache = &mapCache{values: map[string]interface{}{}}
+
+	var calls []string
+	task, _ := s.Add("report", "@daily", func(ctx context.Context) error {
+		calls = append(calls, "task")
+		return errors.New("boom")
+	},
+		Before(func(ctx context.Context) { calls = append(calls, "before") }),
+		After(func(ctx context.Context, run Run) { calls = append(calls, "after:"+string(run.Status)) }),
+	)
+
+	run := task.Run(context.Background())
+	if run.Status != StatusFailed || run.Error != "boom" {
+		t.Errorf("unexpected run: %+v", run)
+	}
+	if got := len(calls); got != 3 || calls[0] != "before" || calls[2] != "after:failed" {
+		t.Errorf("unexpected hook order: %v", calls)
+	}
+
+	// A second schedule sharing the cache sees the last run.
+	other := New(cron.New())
+	other.Cache = s.Cache
+	otherTask, _ := other.Add("report", "@daily", func(ctx context.Context) error { return nil })
+	last, ok := otherTask.LastRun()
+	if !ok || last.Status != StatusFailed {
+		t.Errorf("expected the last run to be read from the cache, got %+v", last)
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	s := New(cron.New())
+	task, _ := s.Add("slow", "@daily", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, Timeout(10*time.Millisecond))
+
+	if run := task.Run(context.Background()); run.Status != StatusTimeout {
+		t.Errorf("expected a timeout, got %+v", run)
+	}
+}
+
+func TestRun_WithoutOverlapping(t *testing.T) {
+	s := New(cron.New())
+	release := make(chan struct{})
+	started := make(chan struct{})
+	task, _ := s.Add("long", "@daily", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}, WithoutOverlapping(0))
+
+	done := make(chan Run)
+	go func() { done <- task.Run(context.Background()) }()
+	<-started
+
+	if run := task.Run(context.Background()); run.Status != StatusSkipped {
+		t.Errorf("expected an overlapping run to be skipped, got %+v", run)
+	}
+
+	close(release)
+	if run := <-done; run.Status != StatusSuccess {
+		t.Errorf("expected the first run to succeed, got %+v", run)
+	}
+}
+
+func TestRun_WithoutOverlappingAfterTimeout(t *testing.T) {
+	locker := NewMemoryLocker()
+	release := make(chan struct{})
+	returned := make(chan struct{})
+	fn := func(ctx context.Context) error {
+		defer close(returned)
+		<-release
+		return nil
+	}
+
+	s := New(cron.New())
+	s.Locker = locker
+	task, _ := s.Add("stuck", "@daily", fn, WithoutOverlapping(0), Timeout(10*time.Millisecond))
+
+	if run := task.Run(context.Background()); run.Status != StatusTimeout {
+		t.Fatalf("expected a timeout, got %+v", run)
+	}
+
+	// The next tick, here and on another server, finds the first run still
+	// going.
+	if run := task.Run(context.Background()); run.Status != StatusSkipped {
+		t.Errorf("expected the next tick to be skipped, got %+v", run)
+	}
+	other := New(cron.New())
+	other.Locker = locker
+	otherTask, _ := other.Add("stuck", "@daily", func(ctx context.Context) error { return nil }, WithoutOverlapping(0))
+	if run := otherTask.Run(context.Background()); run.Status != StatusSkipped {
+		t.Errorf("expected the next tick on another server to be skipped, got %+v", run)
+	}
+
+	close(release)
+	<-returned
+
+	// The lock is released just after the function returns.
+	deadline := time.Now().Add(time.Second)
+	run := otherTask.Run(context.Background())
+	for run.Status == StatusSkipped && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		run = otherTask.Run(context.Background())
+	}
+	if run.Status != StatusSuccess {
+		t.Errorf("expected a run once the timed out one returned, got %+v", run)
+	}
+}
+
+func TestRun_OnOneServer(t *testing.T) {
+	locker := NewMemoryLocker()
+	runs := 0
+	fn := func(ctx context.Context) error {
+		runs++
+		return nil
+	}
+
+	// Two servers sharing a locker claim the same occurrence.
+	for i := 0; i < 2; i++ {
+		s := New(cron.New())
+		s.Locker = locker
+		task, _ := s.Add("nightly", "@daily", fn, OnOneServer())
+		task.Run(context.Background())
+	}
+
+	if runs != 1 {
+		t.Errorf("expected the task to run once, ran %d times", runs)
+	}
+}
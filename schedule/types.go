@@ -0,0 +1,113 @@
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+	"github.com/gomodule/redigo/redis"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// TaskFunc is the work a scheduled task performs. The context is cancelled when
+// the task's timeout is reached.
+type TaskFunc func(ctx context.Context) error
+
+// TaskOption configures a task when it is added to a schedule.
+type TaskOption func(*Task)
+
+// Schedule is a registry of named tasks run by a cron scheduler.
+type Schedule struct {
+	Cron *cron.Cron
+	// Locker coordinates OnOneServer and WithoutOverlapping tasks between processes.
+	Locker Locker
+	// Cache, when set, records the last run of every task so other processes,
+	// such as `adele schedule:list`, can report it.
+	Cache cache.Cache
+	Log   *logrus.Logger
+
+	mu    sync.RWMutex
+	tasks []*Task
+}
+
+// Task is a named job added to a schedule.
+type Task struct {
+	Name string
+	Spec string
+
+	fn                 TaskFunc
+	schedule           *Schedule
+	entryID            cron.EntryID
+	withoutOverlapping bool
+	overlapTTL         time.Duration
+	onOneServer        bool
+	timeout            time.Duration
+	before             []func(ctx context.Context)
+	after              []func(ctx context.Context, run Run)
+
+	mu      sync.Mutex
+	running bool
+	last    *Run
+}
+
+// Status is the result of a task run.
+type Status string
+
+const (
+	StatusSuccess Status = "success"
+	StatusFailed  Status = "failed"
+	StatusTimeout Status = "timeout"
+	StatusSkipped Status = "skipped"
+)
+
+// Run records one run of a task.
+type Run struct {
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Status    Status        `json:"status"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Locker hands out named locks that expire after a time to live, so a crashed
+// process cannot hold a lock forever.
+type Locker interface {
+	// Acquire takes the named lock, reporting false when another owner holds it.
+	// The returned token identifies this owner when releasing the lock.
+	Acquire(ctx context.Context, name string, ttl time.Duration) (token string, ok bool, err error)
+	// Release frees the named lock if it is still held by the token's owner.
+	Release(ctx context.Context, name, token string) error
+}
+
+// MemoryLocker holds locks in process memory. It only coordinates tasks within a
+// single process.
+type MemoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]memoryLock
+}
+
+type memoryLock struct {
+	token   string
+	expires time.Time
+}
+
+// RedisLocker holds locks as Redis keys written with SET NX.
+type RedisLocker struct {
+	Pool   *redis.Pool
+	Prefix string
+}
+
+// SQLLocker holds locks as rows in the schedule_locks table.
+type SQLLocker struct {
+	DB      *sql.DB
+	Dialect string
+}
+
+// ErrDuplicateTask is returned when a task name is added twice.
+var ErrDuplicateTask = errors.New("schedule: a task with this name already exists")
+
+// ErrTaskNotFound is returned when a named task does not exist.
+var ErrTaskNotFound = errors.New("schedule: task not found")
@@ -0,0 +1,165 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// NewRedisStore returns a RedisStore using the given pool. Keys are written as
+// "<prefix>:session:<token>", or "session:<token>" when the prefix is empty.
+func NewRedisStore(pool *redis.Pool, prefix string) *RedisStore {
+	return &RedisStore{
+		Pool:   pool,
+		Prefix: prefix,
+	}
+}
+
+// Find returns the data for a session token. The exists value is false when the
+// token is unknown or has expired, in which case Redis has already evicted it.
+func (s *RedisStore) Find(token string) ([]byte, bool, error) {
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	b, err := redis.Bytes(conn.Do("GET", s.key(token)))
+	if err == redis.ErrNil {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	return b, true, nil
+}
+
+// Commit adds the session token and data to the store with the given absolute
+// expiry time. Existing data for the token is replaced.
+func (s *RedisStore) Commit(token string, b []byte, expiry time.Time) error {
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	ttl := time.Until(expiry).Milliseconds()
+	if ttl <= 0 {
+		_, err := conn.Do("DEL", s.key(token), s.deviceKey(token))
+		return err
+	}
+
+	_, err := conn.Do("SET", s.key(token), b, "PX", ttl)
+	return err
+}
+
+// Delete removes the session token and its data from the store.
+func (s *RedisStore) Delete(token string) error {
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", s.key(token), s.deviceKey(token))
+	return err
+}
+
+// TouchDevice records the device of a session in a hash expiring with the
+// session, and adds the session to the set of its user. A session that has
+// not been committed yet is ignored.
+func (s *RedisStore) TouchDevice(device Device) error {
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	ttl, err := redis.Int64(conn.Do("PTTL", s.key(device.Token)))
+	if err != nil || ttl <= 0 {
+		return err
+	}
+
+	_, err = conn.Do("HSET", s.deviceKey(device.Token),
+		"user_id", device.UserID,
+		"ip_address", device.IPAddress,
+		"user_agent", device.UserAgent,
+		"last_activity", device.LastActivity.Unix())
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Do("PEXPIRE", s.deviceKey(device.Token), ttl); err != nil {
+		return err
+	}
+
+	userKey := s.userKey(device.UserID)
+	if _, err := conn.Do("SADD", userKey, device.Token); err != nil {
+		return err
+	}
+
+	// The set lives as long as the longest lived session in it.
+	current, err := redis.Int64(conn.Do("PTTL", userKey))
+	if err != nil {
+		return err
+	}
+	if current >= 0 && current < ttl {
+		_, err = conn.Do("PEXPIRE", userKey, ttl)
+	}
+	return err
+}
+
+// UserDevices lists the unexpired sessions of a user, most recently active
+// first. Expired sessions found in the set of the user are removed from it.
+func (s *RedisStore) UserDevices(userID int) ([]Device, error) {
+	conn := s.Pool.Get()
+	defer conn.Close()
+
+	userKey := s.userKey(userID)
+	tokens, err := redis.Strings(conn.Do("SMEMBERS", userKey))
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []Device
+	for _, token := range tokens {
+		values, err := redis.StringMap(conn.Do("HGETALL", s.deviceKey(token)))
+		if err != nil {
+			return nil, err
+		}
+
+		exists, err := redis.Bool(conn.Do("EXISTS", s.key(token)))
+		if err != nil {
+			return nil, err
+		}
+		if !exists || len(values) == 0 {
+			if _, err := conn.Do("SREM", userKey, token); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		lastActivity, _ := strconv.ParseInt(values["last_activity"], 10, 64)
+		devices = append(devices, Device{
+			Token:        token,
+			UserID:       userID,
+			IPAddress:    values["ip_address"],
+			UserAgent:    values["user_agent"],
+			LastActivity: time.Unix(lastActivity, 0),
+		})
+	}
+
+	sort.Slice(devices, func(i, j int) bool { return devices[i].LastActivity.After(devices[j].LastActivity) })
+	return devices, nil
+}
+
+// Build the namespaced key for a session token.
+func (s *RedisStore) key(token string) string {
+	if s.Prefix == "" {
+		return fmt.Sprintf("session:%s", token)
+	}
+	return fmt.Sprintf("%s:session:%s", s.Prefix, token)
+}
+
+// Build the key of the device hash of a session token.
+func (s *RedisStore) deviceKey(token string) string {
+	return s.key(token) + ":device"
+}
+
+// Build the key of the set of session tokens of a user.
+func (s *RedisStore) userKey(userID int) string {
+	if s.Prefix == "" {
+		return fmt.Sprintf("session:user:%d", userID)
+	}
+	return fmt.Sprintf("%s:session:user:%d", s.Prefix, userID)
+}
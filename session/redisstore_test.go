@@ -0,0 +1,162 @@
+package session
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+// Start a miniredis server standing in for a local Redis instance and return a
+// pool dialing it.
+func newTestRedisPool(t *testing.T) (*miniredis.Miniredis, *redis.Pool) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+
+	pool := &redis.Pool{
+		MaxIdle: 5,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	t.Cleanup(func() { pool.Close() })
+
+	return mr, pool
+}
+
+func TestRedisStore_CommitFindDelete(t *testing.T) {
+	mr, pool := newTestRedisPool(t)
+	store := NewRedisStore(pool, "adele")
+
+	err := store.Commit("token", []byte("data"), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !mr.Exists("adele:session:token") {
+		t.Error("expected session key to be written with the configured prefix")
+	}
+
+	b, found, err := store.Find("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Fatal("expected session to be found")
+	}
+	if !bytes.Equal(b, []byte("data")) {
+		t.Errorf("expected data to be %q, got %q", "data", b)
+	}
+
+	err = store.Delete("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, found, err = store.Find("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Error("expected session to be deleted")
+	}
+}
+
+func TestRedisStore_Expiry(t *testing.T) {
+	mr, pool := newTestRedisPool(t)
+	store := NewRedisStore(pool, "")
+
+	err := store.Commit("token", []byte("data"), time.Now().Add(10*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !mr.Exists("session:token") {
+		t.Error("expected unprefixed session key when no prefix is configured")
+	}
+
+	mr.FastForward(11 * time.Second)
+
+	_, found, err := store.Find("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Error("expected session to expire")
+	}
+}
+
+func TestSession_InitSessionRedis(t *testing.T) {
+	_, pool := newTestRedisPool(t)
+
+	a := &Session{
+		CookieLifetime: "100",
+		CookiePersist:  "true",
+		CookieName:     "Adele",
+		CookieDomain:   "localhost",
+		SessionType:    "redis",
+		RedisPool:      pool,
+		RedisPrefix:    "adele",
+	}
+
+	ses := a.InitSession()
+
+	store, ok := ses.Store.(*RedisStore)
+	if !ok {
+		t.Fatalf("expected a *RedisStore, got %T", ses.Store)
+	}
+
+	if store.Prefix != "adele" {
+		t.Errorf("expected prefix %q, got %q", "adele", store.Prefix)
+	}
+}
+
+func TestRedisStore_Devices(t *testing.T) {
+	mr, pool := newTestRedisPool(t)
+	store := NewRedisStore(pool, "adele")
+
+	now := time.Now()
+	for i, token := range []string{"laptop", "phone"} {
+		if err := store.Commit(token, []byte("data"), now.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		err := store.TouchDevice(Device{Token: token, UserID: 7, IPAddress: "10.0.0.1", UserAgent: token, LastActivity: now.Add(time.Duration(i) * time.Minute)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A session that was never committed is not tracked.
+	if err := store.TouchDevice(Device{Token: "new", UserID: 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	devices, err := store.UserDevices(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 || devices[0].Token != "phone" || devices[1].UserAgent != "laptop" || devices[0].IPAddress != "10.0.0.1" {
+		t.Fatalf("expected the phone and laptop sessions, most recent first, got %+v", devices)
+	}
+
+	if err := store.Delete("phone"); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("adele:session:phone:device") {
+		t.Error("expected the device of a deleted session to be removed")
+	}
+
+	devices, err = store.UserDevices(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 || devices[0].Token != "laptop" {
+		t.Errorf("expected only the laptop session, got %+v", devices)
+	}
+	if members, _ := mr.Members("adele:session:user:7"); len(members) != 1 {
+		t.Errorf("expected the deleted session to be dropped from the user set, got %v", members)
+	}
+}
@@ -45,8 +45,18 @@ func (s *Session) InitSession() *scs.SessionManager {
 	session.Cookie.Domain = s.CookieDomain
 	session.Cookie.SameSite = http.SameSiteLaxMode
 
-	// Use cookie for sessions
-	// TODO: allow for other session store types
+	// Attach the session store; anything other than a configured backend
+	// falls back to the scs in-memory store.
+	switch strings.ToLower(s.SessionType) {
+	case "redis":
+		if s.RedisPool != nil {
+			session.Store = NewRedisStore(s.RedisPool, s.RedisPrefix)
+		}
+	case "mysql", "postgres", "mariadb", "postgresql":
+		if s.DBPool != nil {
+			session.Store = NewSQLStore(s.DBPool, s.SessionType)
+		}
+	}
 
 	return session
 }
@@ -0,0 +1,103 @@
+package session
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/cidekar/adele-framework/database"
+)
+
+// NewSQLStore returns a SQLStore for the given pool. The database type is the
+// value of DATABASE_TYPE and is normalized to either "postgres" or "mysql".
+func NewSQLStore(db *sql.DB, databaseType string) *SQLStore {
+	return &SQLStore{
+		DB:      db,
+		Dialect: database.Dialect(databaseType),
+	}
+}
+
+// Find returns the data for a session token that has not yet expired. The exists
+// value is false when the token is unknown or its expiry is in the past.
+func (s *SQLStore) Find(token string) ([]byte, bool, error) {
+	var b []byte
+
+	query := database.Rebind(s.Dialect, "SELECT data FROM sessions WHERE token = ? AND expiry > ?")
+	row := s.DB.QueryRow(query, token, time.Now().UTC())
+	err := row.Scan(&b)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	return b, true, nil
+}
+
+// Commit adds the session token and data to the store with the given expiry
+// time, replacing the row when the token already exists.
+func (s *SQLStore) Commit(token string, b []byte, expiry time.Time) error {
+	var query string
+
+	switch s.Dialect {
+	case "mysql":
+		query = "INSERT INTO sessions (token, data, expiry) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE data = VALUES(data), expiry = VALUES(expiry)"
+	default:
+		query = "INSERT INTO sessions (token, data, expiry) VALUES ($1, $2, $3) ON CONFLICT (token) DO UPDATE SET data = EXCLUDED.data, expiry = EXCLUDED.expiry"
+	}
+
+	_, err := s.DB.Exec(query, token, b, expiry.UTC())
+	return err
+}
+
+// Delete removes the session token and its data from the store.
+func (s *SQLStore) Delete(token string) error {
+	_, err := s.DB.Exec(database.Rebind(s.Dialect, "DELETE FROM sessions WHERE token = ?"), token)
+	return err
+}
+
+// DeleteExpired removes every session whose expiry has passed and returns the
+// number of rows removed. Expired rows are never returned by Find, so the sweep
+// only keeps the table from growing; it is run by the application scheduler.
+func (s *SQLStore) DeleteExpired() (int64, error) {
+	res, err := s.DB.Exec(database.Rebind(s.Dialect, "DELETE FROM sessions WHERE expiry < ?"), time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// TouchDevice records the user and device of a session. A session that has not
+// been committed yet has no row to update.
+func (s *SQLStore) TouchDevice(device Device) error {
+	_, err := s.DB.Exec(database.Rebind(s.Dialect, "UPDATE sessions SET user_id = ?, ip_address = ?, user_agent = ?, last_activity = ? WHERE token = ?"),
+		device.UserID, device.IPAddress, device.UserAgent, device.LastActivity.UTC(), device.Token)
+	return err
+}
+
+// UserDevices lists the unexpired sessions of a user, most recently active
+// first.
+func (s *SQLStore) UserDevices(userID int) ([]Device, error) {
+	rows, err := s.DB.Query(database.Rebind(s.Dialect, "SELECT token, ip_address, user_agent, last_activity FROM sessions WHERE user_id = ? AND expiry > ? ORDER BY last_activity DESC"),
+		userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []Device
+	for rows.Next() {
+		var (
+			d            = Device{UserID: userID}
+			ip, agent    sql.NullString
+			lastActivity sql.NullTime
+		)
+		if err := rows.Scan(&d.Token, &ip, &agent, &lastActivity); err != nil {
+			return nil, err
+		}
+		d.IPAddress, d.UserAgent, d.LastActivity = ip.String, agent.String, lastActivity.Time
+		devices = append(devices, d)
+	}
+
+	return devices, rows.Err()
+}
@@ -0,0 +1,179 @@
+package session
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSQLStore_Postgres(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := NewSQLStore(db, "postgresql")
+	if store.Dialect != "postgres" {
+		t.Fatalf("expected postgres dialect, got %s", store.Dialect)
+	}
+
+	expiry := time.Now().Add(time.Minute)
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO sessions (token, data, expiry) VALUES ($1, $2, $3) ON CONFLICT (token)")).
+		WithArgs("token", []byte("data"), expiry.UTC()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT data FROM sessions WHERE token = $1 AND expiry > $2")).
+		WithArgs("token", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"data"}).AddRow([]byte("data")))
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM sessions WHERE token = $1")).
+		WithArgs("token").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := store.Commit("token", []byte("data"), expiry); err != nil {
+		t.Fatal(err)
+	}
+
+	b, found, err := store.Find("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || string(b) != "data" {
+		t.Errorf("expected to find session data, got found=%v data=%q", found, b)
+	}
+
+	if err := store.Delete("token"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSQLStore_MySQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := NewSQLStore(db, "mariadb")
+	if store.Dialect != "mysql" {
+		t.Fatalf("expected mysql dialect, got %s", store.Dialect)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO sessions (token, data, expiry) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE")).
+		WithArgs("token", []byte("data"), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT data FROM sessions WHERE token = ? AND expiry > ?")).
+		WithArgs("missing", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"data"}))
+
+	if err := store.Commit("token", []byte("data"), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, found, err := store.Find("missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Error("expected unknown token not to be found")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSQLStore_DeleteExpired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := NewSQLStore(db, "postgres")
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM sessions WHERE expiry < $1")).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	n, err := store.DeleteExpired()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 expired sessions removed, got %d", n)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSession_InitSessionSQL(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	a := &Session{
+		CookieLifetime: "100",
+		CookiePersist:  "true",
+		CookieName:     "Adele",
+		CookieDomain:   "localhost",
+		SessionType:    "mysql",
+		DBPool:         db,
+	}
+
+	ses := a.InitSession()
+
+	if _, ok := ses.Store.(*SQLStore); !ok {
+		t.Fatalf("expected a *SQLStore, got %T", ses.Store)
+	}
+}
+
+func TestSQLStore_Devices(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store := NewSQLStore(db, "postgres")
+	now := time.Now()
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE sessions SET user_id = $1, ip_address = $2, user_agent = $3, last_activity = $4 WHERE token = $5")).
+		WithArgs(7, "10.0.0.1", "Firefox", now.UTC(), "token").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT token, ip_address, user_agent, last_activity FROM sessions WHERE user_id = $1 AND expiry > $2 ORDER BY last_activity DESC")).
+		WithArgs(7, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"token", "ip_address", "user_agent", "last_activity"}).
+			AddRow("token", "10.0.0.1", "Firefox", now).
+			AddRow("other", nil, nil, nil))
+
+	if err := store.TouchDevice(Device{Token: "token", UserID: 7, IPAddress: "10.0.0.1", UserAgent: "Firefox", LastActivity: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	devices, err := store.UserDevices(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 || devices[0].UserAgent != "Firefox" || devices[1].Token != "other" || devices[1].UserID != 7 {
+		t.Errorf("unexpected devices %+v", devices)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -2,7 +2,9 @@ package session
 
 import (
 	"database/sql"
+	"time"
 
+	"github.com/alexedwards/scs/v2"
 	"github.com/gomodule/redigo/redis"
 )
 
@@ -14,5 +16,47 @@ type Session struct {
 	CookieName     string
 	DBPool         *sql.DB
 	RedisPool      *redis.Pool
+	RedisPrefix    string
 	SessionType    string
 }
+
+// RedisStore is a scs.Store backed by a redigo connection pool. Pool is the
+// shared pool built by redisdriver.CreateRedisPool and Prefix namespaces the
+// session keys so several applications can share a single Redis instance.
+type RedisStore struct {
+	Pool   *redis.Pool
+	Prefix string
+}
+
+// SQLStore is a scs.Store backed by the application's database pool. Sessions
+// are kept in a "sessions" table with token, data and expiry columns. Tracking
+// devices also needs the user_id, ip_address, user_agent and last_activity
+// columns the session-devices kit adds; Dialect selects the placeholder and upsert syntax for postgres or mysql.
+type SQLStore struct {
+	DB      *sql.DB
+	Dialect string
+}
+
+// A Device describes where the session with Token is used from by the logged
+// in user UserID.
+type Device struct {
+	Token        string
+	UserID       int
+	IPAddress    string
+	UserAgent    string
+	LastActivity time.Time
+}
+
+// A DeviceStore is a scs.Store that also records the user and device of each
+// session, so users can list their sessions and end them. The SQL and Redis
+// stores are DeviceStores.
+type DeviceStore interface {
+	scs.Store
+
+	// Record the device of a committed session; a session that has not been
+	// committed yet is ignored.
+	TouchDevice(device Device) error
+
+	// List the unexpired sessions of a user, most recently active first.
+	UserDevices(userID int) ([]Device, error)
+}
@@ -0,0 +1,106 @@
+package adele
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/cidekar/adele-framework/cache/badgerdriver"
+)
+
+// Register a hook to run when the application shuts down. Hooks run before any
+// framework subsystem is stopped, in the reverse order they were registered, so
+// they may still use the database, cache and mailer. Each hook receives the
+// shutdown context and should return once its work is done or the context ends.
+func (a *Adele) RegisterShutdownHook(hook ShutdownHook) {
+	a.shutdownHooks = append(a.shutdownHooks, hook)
+}
+
+// Gracefully stop the application's subsystems. The HTTP server is expected to
+// have finished draining in-flight requests before Shutdown is called. Shutdown
+// then stops, in order: registered shutdown hooks, the scheduler and the mail
+// listener (waiting for running jobs and for the queued mail to be sent), the RPC
+// listener, the Badger cache, the Redis pool and finally the database pool. The
+// cache and the pools are left open when ctx ends before the scheduler and the
+// mail listener are done. Every step is attempted even when an earlier one fails
+// and the errors are joined in the returned value. Calling Shutdown more than once
+// has no further effect.
+func (a *Adele) Shutdown(ctx context.Context) error {
+	var errs []error
+
+	a.shutdownOnce.Do(func() {
+		for i := len(a.shutdownHooks) - 1; i >= 0; i-- {
+			if err := a.shutdownHooks[i](ctx); err != nil {
+				errs = append(errs, fmt.Errorf("shutdown hook: %w", err))
+			}
+		}
+
+		// The scheduler and the mail listener are stopped together, and the
+		// pools their jobs and sends use are only closed once both are done.
+		// When ctx ends first the pools are left open rather than closed
+		// under them.
+		var scheduler context.Context
+		if a.Scheduler != nil {
+			scheduler = a.Scheduler.Stop()
+		}
+
+		running := false
+		if err := a.Mail.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("mail listener: %w", err))
+			running = true
+		}
+
+		if scheduler != nil {
+			select {
+			case <-scheduler.Done():
+			case <-ctx.Done():
+				errs = append(errs, fmt.Errorf("scheduler: %w", ctx.Err()))
+				running = true
+			}
+		}
+
+		if a.RPCListener != nil && *a.RPCListener != nil {
+			if err := (*a.RPCListener).Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+				errs = append(errs, fmt.Errorf("rpc listener: %w", err))
+			}
+		}
+
+		if running {
+			errs = append(errs, errors.New("left the cache, redis and database pools open while scheduled jobs or mail sends are still running"))
+		} else {
+			errs = append(errs, a.closePools()...)
+		}
+
+		if a.Log != nil {
+			a.Log.Info("application shutdown complete")
+		}
+	})
+
+	return errors.Join(errs...)
+}
+
+// Close the Badger cache, the Redis pool and the database pool.
+func (a *Adele) closePools() []error {
+	var errs []error
+
+	if bc, ok := a.Cache.(*badgerdriver.BadgerCache); ok && bc.Conn != nil {
+		if err := bc.Conn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("badger cache: %w", err))
+		}
+	}
+
+	if a.RedisPool != nil {
+		if err := a.RedisPool.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("redis pool: %w", err))
+		}
+	}
+
+	if a.DB != nil && a.DB.Pool != nil {
+		if err := a.DB.Pool.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("database pool: %w", err))
+		}
+	}
+
+	return errs
+}
@@ -1,20 +1,28 @@
 package adele
 
 import (
+	"context"
 	"log"
 	"net"
+	"sync"
 
 	"github.com/CloudyKit/jet/v6"
 	"github.com/alexedwards/scs/v2"
 	"github.com/cidekar/adele-framework/auth"
 	"github.com/cidekar/adele-framework/cache"
+	"github.com/cidekar/adele-framework/config"
 	"github.com/cidekar/adele-framework/database"
+	"github.com/cidekar/adele-framework/health"
 	"github.com/cidekar/adele-framework/helpers"
 	"github.com/cidekar/adele-framework/mailer"
 	"github.com/cidekar/adele-framework/middleware"
 	"github.com/cidekar/adele-framework/mux"
+	"github.com/cidekar/adele-framework/oauth"
 	"github.com/cidekar/adele-framework/provider"
+	"github.com/cidekar/adele-framework/queue"
 	"github.com/cidekar/adele-framework/render"
+	"github.com/cidekar/adele-framework/schedule"
+	"github.com/gomodule/redigo/redis"
 	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 )
@@ -22,35 +30,52 @@ import (
 type Adele struct {
 	AppName          string
 	Auth             *auth.Auth
-	config           config
 	Cache            cache.Cache
+	Config           *config.Config
 	DB               *database.Database
 	Debug            bool
 	EncryptionKey    string
 	ErrorLog         *log.Logger
 	FileSystem       map[string]interface{}
+	Health           *health.Checker
 	Helpers          *helpers.Helpers
 	JetViews         *jet.Set
 	Log              *logrus.Logger
 	Mail             mailer.Mail
 	middleware       middleware.Middleware
 	MaintenanceMode  bool
+	OAuth            *oauth.Server
 	Provider         *provider.Provider
+	Queue            *queue.Queue
+	RedisPool        *redis.Pool
 	Render           *render.Render
 	Routes           *mux.Mux
 	RootPath         string
 	RPCListener      *net.Listener
+	Schedule         *schedule.Schedule
 	Scheduler        *cron.Cron
 	Server           Server
 	Session          *scs.SessionManager
+	shutdownHooks    []ShutdownHook
+	shutdownOnce     sync.Once
 	Version          string
 	ViewsTemplateDir string
 }
 
-type config struct {
-	port        string
-	renderer    string
-	sessionType string
+// ShutdownHook is a function run by Adele.Shutdown before the framework
+// subsystems are stopped.
+type ShutdownHook func(ctx context.Context) error
+
+// The subsystems Adele.New boots, set through Option values.
+type bootOptions struct {
+	cache      bool
+	database   bool
+	filesystem bool
+	mail       bool
+	providers  bool
+	queue      bool
+	render     bool
+	config     *config.Config
 }
 
 type Server struct {